@@ -0,0 +1,13 @@
+package v1
+
+// RegistryResponse lists the names managed at runtime, by source.
+type RegistryResponse struct {
+	Catalog RegistryEntries `json:"catalog"`
+	Admin   RegistryEntries `json:"admin"`
+}
+
+type RegistryEntries struct {
+	Tools     []string `json:"tools"`
+	Prompts   []string `json:"prompts"`
+	Resources []string `json:"resources"`
+}
@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ListAuditRequest filters the audit log. Records come newest first; pass
+// NextBefore of a page as Before to get the next one.
+type ListAuditRequest struct {
+	Session string    `form:"session"`
+	Caller  string    `form:"caller"`
+	Tool    string    `form:"tool"`
+	Status  string    `form:"status"`
+	Since   time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until   time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Before  uint      `form:"before"`
+	Limit   int       `form:"limit"`
+}
+
+type ListAuditResponse struct {
+	Records    []*AuditRecord `json:"records"`
+	NextBefore uint           `json:"next_before,omitempty"`
+}
+
+// AuditRecord is one tool call in the audit log.
+type AuditRecord struct {
+	ID         uint            `json:"id"`
+	Time       time.Time       `json:"time"`
+	Session    string          `json:"session,omitempty"`
+	Caller     string          `json:"caller,omitempty"`
+	Tool       string          `json:"tool"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	Status     string          `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+}
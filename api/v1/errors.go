@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrorKind decides how the MCP server reports a handler error. Tool errors
+// of every kind become tool results with IsError set, so the model can react
+// to them; prompt and resource errors become JSON-RPC errors. Internal errors
+// are logged and reach the client only as "internal error".
+type ErrorKind string
+
+const (
+	KindValidation  ErrorKind = "validation"  // the arguments are wrong
+	KindNotFound    ErrorKind = "not_found"   // the requested entity does not exist
+	KindForbidden   ErrorKind = "forbidden"   // the caller or a policy does not allow it
+	KindUpstream    ErrorKind = "upstream"    // an API, upstream server or the client's LLM failed
+	KindUnavailable ErrorKind = "unavailable" // a timeout or limit was hit; a later retry may succeed
+	KindInternal    ErrorKind = "internal"    // a bug or an unexpected failure
+)
+
+// Sentinels of each kind, for errors.Is(err, v1.ErrNotFound).
+var (
+	ErrValidation  = &Error{Kind: KindValidation, Message: "invalid arguments"}
+	ErrNotFound    = &Error{Kind: KindNotFound, Message: "not found"}
+	ErrForbidden   = &Error{Kind: KindForbidden, Message: "forbidden"}
+	ErrUpstream    = &Error{Kind: KindUpstream, Message: "upstream error"}
+	ErrUnavailable = &Error{Kind: KindUnavailable, Message: "temporarily unavailable"}
+	ErrInternal    = &Error{Kind: KindInternal, Message: "internal error"}
+)
+
+var sentinels = map[ErrorKind]*Error{
+	KindValidation:  ErrValidation,
+	KindNotFound:    ErrNotFound,
+	KindForbidden:   ErrForbidden,
+	KindUpstream:    ErrUpstream,
+	KindUnavailable: ErrUnavailable,
+	KindInternal:    ErrInternal,
+}
+
+// Error is a handler error of a known kind. Message is what the client sees.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Err     error
+	// RetryAfter hints when an unavailable call may succeed. Tool errors
+	// carry it as "retryAfter" seconds in the result's _meta.
+	RetryAfter time.Duration
+}
+
+// Errorf returns an error of kind. As with fmt.Errorf, a %w verb keeps the
+// wrapped error available to errors.Is and errors.As.
+func Errorf(kind ErrorKind, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	return &Error{Kind: kind, Message: err.Error(), Err: errors.Unwrap(err)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the sentinel of the error's kind.
+func (e *Error) Is(target error) bool {
+	return target == error(sentinels[e.Kind])
+}
+
+// KindOf returns the kind of err. Errors without one are internal.
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	cause := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		err      error
+		kind     ErrorKind
+		sentinel error
+		message  string
+	}{
+		{name: "sentinel", err: ErrNotFound, kind: KindNotFound, sentinel: ErrNotFound, message: "not found"},
+		{name: "Errorf", err: Errorf(KindValidation, "%q is required", "a"), kind: KindValidation, sentinel: ErrValidation, message: `"a" is required`},
+		{name: "Errorf wrapping", err: Errorf(KindUpstream, "api failed: %w", cause), kind: KindUpstream, sentinel: ErrUpstream, message: "api failed: connection refused"},
+		{name: "wrapped by fmt.Errorf", err: fmt.Errorf("load: %w", Errorf(KindForbidden, "no access")), kind: KindForbidden, sentinel: ErrForbidden, message: "load: no access"},
+		{name: "struct literal", err: &Error{Kind: KindUnavailable, Message: "busy"}, kind: KindUnavailable, sentinel: ErrUnavailable, message: "busy"},
+		{name: "plain error", err: cause, kind: KindInternal, message: "connection refused"},
+		{name: "context error", err: context.DeadlineExceeded, kind: KindInternal, message: "context deadline exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.kind {
+				t.Errorf("KindOf = %s, want %s", got, tt.kind)
+			}
+			if tt.sentinel != nil && !errors.Is(tt.err, tt.sentinel) {
+				t.Errorf("errors.Is(%v, %v) = false", tt.err, tt.sentinel)
+			}
+			for _, other := range sentinels {
+				if error(other) != tt.sentinel && errors.Is(tt.err, other) {
+					t.Errorf("errors.Is(%v, %v) = true", tt.err, other)
+				}
+			}
+			if tt.err.Error() != tt.message {
+				t.Errorf("Error() = %q, want %q", tt.err.Error(), tt.message)
+			}
+		})
+	}
+
+	if err := Errorf(KindUpstream, "api failed: %w", cause); !errors.Is(err, cause) {
+		t.Error("Errorf lost the wrapped error")
+	}
+}
@@ -0,0 +1,39 @@
+package v1
+
+import "time"
+
+// ResourceRequest creates or replaces a database resource. Text and Blob
+// are exclusive; Blob is base64 in JSON. MIMEType defaults to text/plain
+// for text and application/octet-stream for blobs.
+type ResourceRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	MIMEType    string `json:"mime_type"`
+	Text        string `json:"text"`
+	Blob        []byte `json:"blob"`
+}
+
+// ListResourcesRequest pages through the database resources, oldest first;
+// pass NextAfter of a page as After to get the next one.
+type ListResourcesRequest struct {
+	After uint `form:"after"`
+	Limit int  `form:"limit"`
+}
+
+type ListResourcesResponse struct {
+	Resources []*Resource `json:"resources"`
+	NextAfter uint        `json:"next_after,omitempty"`
+}
+
+// Resource is a database resource. Lists leave out Text and Blob.
+type Resource struct {
+	ID          uint      `json:"id"`
+	URI         string    `json:"uri"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	MIMEType    string    `json:"mime_type"`
+	Text        string    `json:"text,omitempty"`
+	Blob        []byte    `json:"blob,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -1,14 +1,73 @@
 package v1
 
+import "time"
+
 type EchoToolRequest struct {
-	Message string `json:"message"`
+	Message string `json:"message" jsonschema:"description=Message to echo,required" validate:"required"`
+}
+type EchoToolResponse struct {
+	Message string `json:"message" jsonschema:"description=The echoed message,required"`
 }
 type AddToolRequest struct {
-	A float64 `json:"a"`
-	B float64 `json:"b"`
+	// Pointers, so a missing number fails validation instead of counting as 0.
+	A *float64 `json:"a" jsonschema:"description=First number,required" validate:"required"`
+	B *float64 `json:"b" jsonschema:"description=Second number,required" validate:"required"`
+}
+type AddToolResponse struct {
+	Sum float64 `json:"sum" jsonschema:"description=a + b,required"`
+}
+type LongRunningOperationRequest struct {
+	// Pointers, so a missing value takes the default instead of 0.
+	Duration *float64 `json:"duration" jsonschema:"description=Duration of the operation in seconds,default=10,minimum=0" validate:"omitnil,gte=0"`
+	Steps    *int     `json:"steps" jsonschema:"description=Number of steps in the operation,default=5,minimum=1" validate:"omitnil,gte=1"`
 }
 type HttpToolRequest struct {
-	Method string `json:"method"`
-	Url    string `json:"url"`
-	Body   string `json:"body"`
+	Method           string            `json:"method" jsonschema:"description=HTTP method to use,required,enum=GET,enum=POST,enum=PUT,enum=PATCH,enum=DELETE,enum=HEAD" validate:"required,oneof=GET POST PUT PATCH DELETE HEAD"`
+	Url              string            `json:"url" jsonschema:"description=URL to send the request to,required,pattern=^https?://.*" validate:"required,http_url"`
+	Headers          map[string]string `json:"headers" jsonschema:"description=Request headers,writeOnly=true" validate:"dive,keys,required,endkeys"`
+	Query            map[string]string `json:"query" jsonschema:"description=Query parameters added to the URL"`
+	Body             string            `json:"body" jsonschema:"description=Request body (for POST/PUT/PATCH)"`
+	Timeout          float64           `json:"timeout" jsonschema:"description=Request timeout in seconds (default 30; max 120),minimum=0,maximum=120" validate:"gte=0,lte=120"`
+	MaxResponseBytes int64             `json:"maxResponseBytes" jsonschema:"description=Maximum response body size in bytes; larger bodies are truncated (default 1MiB; max 10MiB),minimum=0,maximum=10485760" validate:"gte=0,lte=10485760"`
+}
+type HttpToolResponse struct {
+	Status      int               `json:"status" jsonschema:"description=HTTP status code,required"`
+	StatusText  string            `json:"statusText" jsonschema:"required"`
+	Headers     map[string]string `json:"headers" jsonschema:"description=Response headers; repeated headers are joined with commas,required"`
+	ContentType string            `json:"contentType" jsonschema:"required"`
+	Size        int               `json:"size" jsonschema:"description=Number of body bytes returned,required"`
+	Truncated   bool              `json:"truncated" jsonschema:"description=Whether the body was cut at maxResponseBytes,required"`
+}
+type SampleLLMToolRequest struct {
+	Prompt               string   `json:"prompt" jsonschema:"description=The prompt to send to the LLM,required" validate:"required"`
+	MaxTokens            int      `json:"maxTokens" jsonschema:"description=Maximum number of tokens to generate,default=100" validate:"gte=0"`
+	SystemPrompt         string   `json:"systemPrompt" jsonschema:"description=Optional system prompt"`
+	Temperature          *float64 `json:"temperature" jsonschema:"description=Sampling temperature; the client's default when omitted,minimum=0,maximum=2" validate:"omitnil,gte=0,lte=2"`
+	ModelHints           []string `json:"modelHints" jsonschema:"description=Preferred model names in order"`
+	CostPriority         float64  `json:"costPriority" jsonschema:"description=0-1: how much to prioritize cost,minimum=0,maximum=1" validate:"gte=0,lte=1"`
+	SpeedPriority        float64  `json:"speedPriority" jsonschema:"description=0-1: how much to prioritize speed,minimum=0,maximum=1" validate:"gte=0,lte=1"`
+	IntelligencePriority float64  `json:"intelligencePriority" jsonschema:"description=0-1: how much to prioritize capability,minimum=0,maximum=1" validate:"gte=0,lte=1"`
+	Timeout              float64  `json:"timeout" jsonschema:"description=Timeout in seconds; capped by mcp.sampling.timeout,minimum=0" validate:"gte=0"`
+}
+type SamplingMessage struct {
+	Role string `json:"role"` // "user" or "assistant"
+	Text string `json:"text"`
+}
+type SamplingRequest struct {
+	Messages             []SamplingMessage `json:"messages"`
+	SystemPrompt         string            `json:"systemPrompt"`
+	MaxTokens            int               `json:"maxTokens"`
+	Temperature          *float64          `json:"temperature"` // nil leaves it to the client
+	StopSequences        []string          `json:"stopSequences"`
+	ModelHints           []string          `json:"modelHints"`
+	CostPriority         float64           `json:"costPriority"`
+	SpeedPriority        float64           `json:"speedPriority"`
+	IntelligencePriority float64           `json:"intelligencePriority"`
+	Timeout              time.Duration     `json:"timeout"` // 0 uses mcp.sampling.timeout
+}
+type SamplingResponse struct {
+	Model      string `json:"model" jsonschema:"description=Model that generated the message,required"`
+	StopReason string `json:"stopReason" jsonschema:"description=Why sampling stopped if known"`
+	Role       string `json:"role" jsonschema:"required"`
+	Text       string `json:"text" jsonschema:"required"`
 }
@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Response is the envelope of every admin API response.
+type Response struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+func HandleSuccess(ctx *gin.Context, data interface{}) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	ctx.JSON(http.StatusOK, Response{Code: 0, Message: "ok", Data: data})
+}
+
+func HandleError(ctx *gin.Context, httpCode int, err error, data interface{}) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	ctx.AbortWithStatusJSON(httpCode, Response{Code: httpCode, Message: err.Error(), Data: data})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/go-nunu/nunu-layout-mcp/cmd/migration/wire"
+	"github.com/go-nunu/nunu-layout-mcp/internal/migration"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/config"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/migrate"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: migration [-conf config/local.yml] <command>
+
+Commands:
+  up [N]                  apply N pending migrations, default all
+  down [N]                roll back the N latest applied migrations, default 1
+  status                  list migrations and whether they are applied
+  create [-sql] <name>    add an empty Go migration, or SQL with -sql
+`
+
+func main() {
+	var envConf = flag.String("conf", "config/local.yml", "config path, eg: -conf ./config/local.yml")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, args := flag.Arg(0), flag.Args()[1:]
+
+	// create only writes files, so it needs neither config nor database.
+	if command == "create" {
+		create(args)
+		return
+	}
+
+	conf := config.NewConfig(*envConf)
+	logger := log.NewLog(conf)
+	migrator, cleanup, err := wire.NewWire(conf, logger)
+	if err != nil {
+		fatal(err)
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		done, err := migrator.Up(ctx, count(args, 0))
+		report("applied", done)
+		if err != nil {
+			fatal(err)
+		}
+	case "down":
+		done, err := migrator.Down(ctx, count(args, 1))
+		report("rolled back", done)
+		if err != nil {
+			fatal(err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fatal(err)
+		}
+		printStatus(statuses)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func create(args []string) {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	sql := fs.Bool("sql", false, "write .up.sql and .down.sql files instead of a Go file")
+	dir := fs.String("dir", "internal/migration", "directory of the migration package")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	paths, err := migration.Create(*dir, fs.Arg(0), *sql)
+	for _, path := range paths {
+		fmt.Println("created", path)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// count parses the optional N argument of up and down.
+func count(args []string, def int) int {
+	if len(args) == 0 {
+		return def
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		fatal(fmt.Errorf("N must be a positive number, got %q", args[0]))
+	}
+	return n
+}
+
+func report(verb string, migrations []*migrate.Migration) {
+	if len(migrations) == 0 {
+		fmt.Println("nothing to do")
+	}
+	for _, m := range migrations {
+		fmt.Printf("%s %d_%s\n", verb, m.Version, m.Name)
+	}
+}
+
+func printStatus(statuses []migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.Applied() {
+			applied = s.AppliedAt.Local().Format(time.DateTime)
+		}
+		if s.Up == nil {
+			applied += " (unknown to this build)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	_ = w.Flush()
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,24 @@
+//go:build wireinject
+// +build wireinject
+
+package wire
+
+import (
+	"github.com/go-nunu/nunu-layout-mcp/internal/migration"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/migrate"
+	"github.com/google/wire"
+	"github.com/spf13/viper"
+)
+
+var repositorySet = wire.NewSet(
+	repository.NewDB,
+)
+
+func NewWire(*viper.Viper, *log.Logger) (*migrate.Migrator, func(), error) {
+	panic(wire.Build(
+		repositorySet,
+		migration.NewMigrator,
+	))
+}
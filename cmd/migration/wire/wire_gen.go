@@ -0,0 +1,32 @@
+// Code generated by Wire. DO NOT EDIT.
+
+//go:generate go run -mod=mod github.com/google/wire/cmd/wire
+//go:build !wireinject
+// +build !wireinject
+
+package wire
+
+import (
+	"github.com/go-nunu/nunu-layout-mcp/internal/migration"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/migrate"
+	"github.com/google/wire"
+	"github.com/spf13/viper"
+)
+
+// Injectors from wire.go:
+
+func NewWire(viperViper *viper.Viper, logger *log.Logger) (*migrate.Migrator, func(), error) {
+	db := repository.NewDB(viperViper, logger)
+	migrator, err := migration.NewMigrator(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrator, func() {
+	}, nil
+}
+
+// wire.go:
+
+var repositorySet = wire.NewSet(repository.NewDB)
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/openapi"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// headerFlags collects repeated -header "Name: value" flags.
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	return fmt.Sprint(map[string]string(h))
+}
+
+func (h headerFlags) Set(value string) error {
+	name, val, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("header %q must look like \"Name: value\"", value)
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(val)
+	return nil
+}
+
+func main() {
+	var (
+		spec       = flag.String("spec", "", "OpenAPI 3 document, YAML or JSON")
+		out        = flag.String("out", "tools", "catalog directory the tool definitions are written to")
+		baseUrl    = flag.String("base-url", "", "API base URL, defaults to the first server in the spec")
+		prefix     = flag.String("prefix", "", "prefix for every tool name")
+		operations = flag.String("operations", "", "comma-separated operationIds or tool names to generate, default all")
+		timeout    = flag.Float64("timeout", 0, "request timeout in seconds, 0 uses the http_request default")
+		headers    = headerFlags{}
+	)
+	flag.Var(headers, "header", `header sent with every call, e.g. "X-Api-Version: 2"; repeatable. Values are written to the files, so keep secrets in mcp.openapi instead`)
+	flag.Parse()
+	if *spec == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	doc, err := openapi.Load(*spec)
+	if err != nil {
+		fatal(err)
+	}
+	source := handler.OpenAPISource{
+		Spec:    *spec,
+		BaseUrl: *baseUrl,
+		Headers: headers,
+		Prefix:  *prefix,
+		Timeout: *timeout,
+	}
+	if *operations != "" {
+		source.Operations = strings.Split(*operations, ",")
+	}
+	defs, err := handler.OpenAPIDefinitions(doc, source)
+	if err != nil {
+		fatal(err)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		fatal(err)
+	}
+	for _, def := range defs {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# Generated by openapi2mcp from %s.\n", filepath.Base(*spec))
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(def); err != nil {
+			fatal(err)
+		}
+		file := filepath.Join(*out, def.Name+".yaml")
+		if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+			fatal(err)
+		}
+		fmt.Println(file)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "openapi2mcp:", err)
+	os.Exit(1)
+}
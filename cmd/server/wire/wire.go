@@ -5,47 +5,91 @@ package wire
 
 import (
 	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/internal/middleware"
+	"github.com/go-nunu/nunu-layout-mcp/internal/migration"
 	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
 	"github.com/go-nunu/nunu-layout-mcp/internal/server"
 	"github.com/go-nunu/nunu-layout-mcp/internal/service"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/app"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/egress"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/metrics"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/redact"
+	pkgserver "github.com/go-nunu/nunu-layout-mcp/pkg/server"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/server/http"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/sid"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/telemetry"
 	"github.com/google/wire"
 	"github.com/spf13/viper"
 )
 
 var repositorySet = wire.NewSet(
-	//repository.NewDB,
-	//repository.NewTransaction,
+	migration.NewCheckedDB,
+	repository.NewTransaction,
 	//repository.NewRedis,
 	repository.NewRepository,
 	repository.NewExampleRepository,
+	repository.NewUpstreams,
+	repository.NewRateLimitRepository,
+	repository.NewAuditRepository,
 )
 
 var serviceSet = wire.NewSet(
 	service.NewService,
 	service.NewExampleService,
+	service.NewSamplingService,
+	service.NewAuditService,
 )
 
 var handlerSet = wire.NewSet(
 	handler.NewHandler,
+	handler.NewCancellation,
 	handler.NewExampleHandler,
+	handler.NewCatalog,
+	handler.NewAdminHandler,
+	handler.NewGateway,
+	handler.NewAuditHandler,
 )
 
 var serverSet = wire.NewSet(
+	middleware.NewPolicy,
+	middleware.NewLimits,
+	middleware.NewRateLimit,
 	server.NewMCPServer,
+	server.NewRegistry,
+	server.NewCatalogWatcher,
+	server.NewHTTPServer,
+	server.NewGatewayServer,
+	server.NewAuditWorker,
 )
 
 // build App
 func newApp(
 	mcpServer *mcp.Server,
+	httpServer *http.Server,
+	catalogWatcher *server.CatalogWatcher,
+	gatewayServer *server.GatewayServer,
+	auditWorker *server.AuditWorker,
 ) *app.App {
+	servers := []pkgserver.Server{mcpServer}
+	// These are nil when disabled in the config.
+	if httpServer != nil {
+		servers = append(servers, httpServer)
+	}
+	if catalogWatcher != nil {
+		servers = append(servers, catalogWatcher)
+	}
+	if gatewayServer != nil {
+		servers = append(servers, gatewayServer)
+	}
+	// Last, so it flushes the calls that end while the others stop.
+	if auditWorker != nil {
+		servers = append(servers, auditWorker)
+	}
 	return app.NewApp(
-		app.WithServer(
-			mcpServer,
-		),
+		app.WithServer(servers...),
 		app.WithName("demo-server"),
 	)
 }
@@ -57,6 +101,11 @@ func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
 		serverSet,
 		handlerSet,
 		sid.NewSid,
+		jwt.NewJwt,
+		egress.NewPolicy,
+		metrics.NewMetrics,
+		telemetry.NewTracing,
+		redact.NewRedactor,
 		newApp,
 	))
 }
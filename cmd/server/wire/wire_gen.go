@@ -8,13 +8,22 @@ package wire
 
 import (
 	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/internal/middleware"
+	"github.com/go-nunu/nunu-layout-mcp/internal/migration"
 	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
 	"github.com/go-nunu/nunu-layout-mcp/internal/server"
 	"github.com/go-nunu/nunu-layout-mcp/internal/service"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/app"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/egress"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/metrics"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/redact"
+	server2 "github.com/go-nunu/nunu-layout-mcp/pkg/server"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/server/http"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/sid"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/telemetry"
 	"github.com/google/wire"
 	"github.com/spf13/viper"
 )
@@ -22,35 +31,83 @@ import (
 // Injectors from wire.go:
 
 func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), error) {
-	handlerHandler := handler.NewHandler(logger)
+	jwtJWT, cleanup := jwt.NewJwt(viperViper, logger)
+	policy := middleware.NewPolicy(viperViper, logger)
+	limits := middleware.NewLimits(viperViper)
+	rateLimitRepository := repository.NewRateLimitRepository(viperViper)
+	rateLimit := middleware.NewRateLimit(viperViper, logger, rateLimitRepository)
+	cancellation := handler.NewCancellation()
+	metricsMetrics := metrics.NewMetrics(viperViper)
+	tracing, cleanup2 := telemetry.NewTracing(viperViper, logger)
+	redactor := redact.NewRedactor(viperViper)
+	db, err := migration.NewCheckedDB(viperViper, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	repositoryRepository := repository.NewRepository(logger, db)
+	transaction := repository.NewTransaction(repositoryRepository)
 	sidSid := sid.NewSid()
-	serviceService := service.NewService(logger, sidSid)
-	repositoryRepository := repository.NewRepository(logger)
+	serviceService := service.NewService(transaction, logger, sidSid)
+	auditRepository := repository.NewAuditRepository(repositoryRepository)
+	auditService := service.NewAuditService(serviceService, viperViper, auditRepository)
+	handlerHandler := handler.NewHandler(logger)
 	exampleRepository := repository.NewExampleRepository(repositoryRepository)
-	exampleService := service.NewExampleService(serviceService, exampleRepository)
-	exampleHandler := handler.NewExampleHandler(handlerHandler, exampleService)
-	mcpServer := server.NewMCPServer(viperViper, logger, exampleHandler)
-	appApp := newApp(mcpServer)
+	egressPolicy := egress.NewPolicy(viperViper)
+	exampleService := service.NewExampleService(serviceService, exampleRepository, egressPolicy)
+	samplingService := service.NewSamplingService(serviceService, viperViper)
+	exampleHandler := handler.NewExampleHandler(handlerHandler, exampleService, samplingService)
+	auditHandler := handler.NewAuditHandler(handlerHandler, auditService)
+	mcpServer := server.NewMCPServer(viperViper, logger, jwtJWT, policy, limits, rateLimit, cancellation, metricsMetrics, tracing, redactor, auditService, exampleHandler, auditHandler)
+	catalog := handler.NewCatalog(viperViper, handlerHandler, exampleService, exampleHandler)
+	registry := server.NewRegistry(viperViper, logger, mcpServer, catalog)
+	adminHandler := handler.NewAdminHandler(handlerHandler, registry, catalog, exampleService, mcpServer)
+	httpServer := server.NewHTTPServer(viperViper, logger, jwtJWT, adminHandler, auditHandler, metricsMetrics)
+	catalogWatcher := server.NewCatalogWatcher(viperViper, logger, catalog, registry)
+	gateway := handler.NewGateway(handlerHandler, registry)
+	v := repository.NewUpstreams(viperViper, logger)
+	gatewayServer := server.NewGatewayServer(viperViper, logger, gateway, v)
+	auditWorker := server.NewAuditWorker(viperViper, logger, auditService)
+	appApp := newApp(mcpServer, httpServer, catalogWatcher, gatewayServer, auditWorker)
 	return appApp, func() {
+		cleanup2()
+		cleanup()
 	}, nil
 }
 
 // wire.go:
 
-var repositorySet = wire.NewSet(repository.NewRepository, repository.NewExampleRepository)
+var repositorySet = wire.NewSet(migration.NewCheckedDB, repository.NewTransaction, repository.NewRepository, repository.NewExampleRepository, repository.NewUpstreams, repository.NewRateLimitRepository, repository.NewAuditRepository)
 
-var serviceSet = wire.NewSet(service.NewService, service.NewExampleService)
+var serviceSet = wire.NewSet(service.NewService, service.NewExampleService, service.NewSamplingService, service.NewAuditService)
 
-var handlerSet = wire.NewSet(handler.NewHandler, handler.NewExampleHandler)
+var handlerSet = wire.NewSet(handler.NewHandler, handler.NewCancellation, handler.NewExampleHandler, handler.NewCatalog, handler.NewAdminHandler, handler.NewGateway, handler.NewAuditHandler)
 
-var serverSet = wire.NewSet(server.NewMCPServer)
+var serverSet = wire.NewSet(middleware.NewPolicy, middleware.NewLimits, middleware.NewRateLimit, server.NewMCPServer, server.NewRegistry, server.NewCatalogWatcher, server.NewHTTPServer, server.NewGatewayServer, server.NewAuditWorker)
 
 // build App
 func newApp(
 	mcpServer *mcp.Server,
+	httpServer *http.Server,
+	catalogWatcher *server.CatalogWatcher,
+	gatewayServer *server.GatewayServer,
+	auditWorker *server.AuditWorker,
 ) *app.App {
-	return app.NewApp(app.WithServer(
-		mcpServer,
-	), app.WithName("demo-server"),
-	)
+	servers := []server2.Server{mcpServer}
+
+	if httpServer != nil {
+		servers = append(servers, httpServer)
+	}
+	if catalogWatcher != nil {
+		servers = append(servers, catalogWatcher)
+	}
+	if gatewayServer != nil {
+		servers = append(servers, gatewayServer)
+	}
+
+	if auditWorker != nil {
+		servers = append(servers, auditWorker)
+	}
+	return app.NewApp(app.WithServer(servers...), app.WithName("demo-server"))
 }
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"github.com/go-nunu/nunu-layout-mcp/internal/service"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// AdminSource owns the entries added through the admin API in the registry.
+const AdminSource = "admin"
+
+// AdminHandler changes tools, prompts and resources at runtime. Request
+// bodies are catalog definitions, in YAML or JSON, except for the database
+// resources, which take a v1.ResourceRequest.
+type AdminHandler struct {
+	*Handler
+	registry   *servermcp.Registry
+	catalog    *Catalog
+	exampleSvc service.ExampleService
+	mcpServer  *servermcp.Server
+}
+
+func NewAdminHandler(
+	handler *Handler,
+	registry *servermcp.Registry,
+	catalog *Catalog,
+	exampleSvc service.ExampleService,
+	mcpServer *servermcp.Server,
+) *AdminHandler {
+	return &AdminHandler{
+		Handler:    handler,
+		registry:   registry,
+		catalog:    catalog,
+		exampleSvc: exampleSvc,
+		mcpServer:  mcpServer,
+	}
+}
+
+// GetRegistry lists the names managed by the catalog and the admin API.
+func (h *AdminHandler) GetRegistry(ctx *gin.Context) {
+	v1.HandleSuccess(ctx, v1.RegistryResponse{
+		Catalog: h.entries(CatalogSource),
+		Admin:   h.entries(AdminSource),
+	})
+}
+
+func (h *AdminHandler) entries(source string) v1.RegistryEntries {
+	tools, prompts, resources := h.registry.Owned(source)
+	for _, names := range [][]string{tools, prompts, resources} {
+		slices.Sort(names)
+	}
+	return v1.RegistryEntries{Tools: tools, Prompts: prompts, Resources: resources}
+}
+
+// ReloadCatalog reloads mcp.tools.dir, like the file watcher does.
+func (h *AdminHandler) ReloadCatalog(ctx *gin.Context) {
+	if _, err := h.catalog.Reload(h.registry); err != nil {
+		h.logger.WithContext(ctx).Error("catalog reload failed", zap.Error(err))
+		v1.HandleError(ctx, http.StatusUnprocessableEntity, err, nil)
+		return
+	}
+	h.logger.WithContext(ctx).Info("catalog reloaded")
+	h.GetRegistry(ctx)
+}
+
+func (h *AdminHandler) PutTool(ctx *gin.Context) {
+	def, ok := decodeBody[model.ToolDefinition](ctx, model.KindTool)
+	if !ok {
+		return
+	}
+	tool, err := h.catalog.Tool(def)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := h.registry.UpsertTool(AdminSource, tool); err != nil {
+		handleRegistryError(ctx, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("tool updated", zap.String("name", def.Name))
+	v1.HandleSuccess(ctx, nil)
+}
+
+func (h *AdminHandler) DeleteTool(ctx *gin.Context) {
+	if err := h.registry.DeleteTool(AdminSource, ctx.Param("name")); err != nil {
+		handleRegistryError(ctx, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("tool deleted", zap.String("name", ctx.Param("name")))
+	v1.HandleSuccess(ctx, nil)
+}
+
+func (h *AdminHandler) PutPrompt(ctx *gin.Context) {
+	def, ok := decodeBody[model.PromptDefinition](ctx, model.KindPrompt)
+	if !ok {
+		return
+	}
+	prompt, err := h.catalog.Prompt(def)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := h.registry.UpsertPrompt(AdminSource, prompt); err != nil {
+		handleRegistryError(ctx, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("prompt updated", zap.String("name", def.Name))
+	v1.HandleSuccess(ctx, nil)
+}
+
+func (h *AdminHandler) DeletePrompt(ctx *gin.Context) {
+	if err := h.registry.DeletePrompt(AdminSource, ctx.Param("name")); err != nil {
+		handleRegistryError(ctx, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("prompt deleted", zap.String("name", ctx.Param("name")))
+	v1.HandleSuccess(ctx, nil)
+}
+
+func (h *AdminHandler) PutResource(ctx *gin.Context) {
+	def, ok := decodeBody[model.ResourceDefinition](ctx, model.KindResource)
+	if !ok {
+		return
+	}
+	resource, err := h.catalog.Resource(def)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		return
+	}
+	if err := h.registry.UpsertResource(AdminSource, resource); err != nil {
+		handleRegistryError(ctx, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("resource updated", zap.String("uri", def.URI))
+	v1.HandleSuccess(ctx, nil)
+}
+
+// DeleteResource takes the URI from the uri query parameter, since URIs
+// contain slashes.
+func (h *AdminHandler) DeleteResource(ctx *gin.Context) {
+	if err := h.registry.DeleteResource(AdminSource, ctx.Query("uri")); err != nil {
+		handleRegistryError(ctx, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("resource deleted", zap.String("uri", ctx.Query("uri")))
+	v1.HandleSuccess(ctx, nil)
+}
+
+func (h *AdminHandler) ListDBResources(ctx *gin.Context) {
+	var req v1.ListResourcesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		return
+	}
+	resp, err := h.exampleSvc.ListResources(ctx, &req)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+	v1.HandleSuccess(ctx, resp)
+}
+
+func (h *AdminHandler) GetDBResource(ctx *gin.Context) {
+	id, ok := paramID(ctx)
+	if !ok {
+		return
+	}
+	resource, err := h.exampleSvc.GetResource(ctx, id)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+	v1.HandleSuccess(ctx, resource)
+}
+
+func (h *AdminHandler) CreateDBResource(ctx *gin.Context) {
+	var req v1.ResourceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		return
+	}
+	resource, err := h.exampleSvc.CreateResource(ctx, &req)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("database resource created", zap.String("uri", resource.URI))
+	h.mcpServer.NotifyResourceListChanged()
+	v1.HandleSuccess(ctx, resource)
+}
+
+func (h *AdminHandler) UpdateDBResource(ctx *gin.Context) {
+	id, ok := paramID(ctx)
+	if !ok {
+		return
+	}
+	var req v1.ResourceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		return
+	}
+	resource, err := h.exampleSvc.UpdateResource(ctx, id, &req)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+	h.logger.WithContext(ctx).Info("database resource updated", zap.String("uri", resource.URI))
+	h.mcpServer.NotifyResourceListChanged()
+	v1.HandleSuccess(ctx, resource)
+}
+
+func (h *AdminHandler) DeleteDBResource(ctx *gin.Context) {
+	id, ok := paramID(ctx)
+	if !ok {
+		return
+	}
+	if err := h.exampleSvc.DeleteResource(ctx, id); err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+	uri := model.ResourceURI(id)
+	h.logger.WithContext(ctx).Info("database resource deleted", zap.String("uri", uri))
+	h.mcpServer.NotifyResourceListChanged()
+	v1.HandleSuccess(ctx, nil)
+}
+
+// paramID parses the id path parameter, answering 400 when it is invalid.
+func paramID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 0)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.Errorf(v1.KindValidation, "invalid id %q", ctx.Param("id")), nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// decodeBody decodes a definition of the given kind from the request body.
+func decodeBody[T any](ctx *gin.Context, kind string) (*T, bool) {
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		return nil, false
+	}
+	def, err := DecodeDefinition(data)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		return nil, false
+	}
+	typed, ok := def.(*T)
+	if !ok {
+		v1.HandleError(ctx, http.StatusBadRequest, fmt.Errorf("expected a %s definition", kind), nil)
+		return nil, false
+	}
+	return typed, true
+}
+
+func handleRegistryError(ctx *gin.Context, err error) {
+	if errors.Is(err, servermcp.ErrNotManaged) {
+		v1.HandleError(ctx, http.StatusNotFound, err, nil)
+		return
+	}
+	v1.HandleError(ctx, http.StatusConflict, err, nil)
+}
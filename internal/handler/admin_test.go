@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// notifiedSession is an initialized session that keeps its notifications.
+type notifiedSession chan mcp.JSONRPCNotification
+
+func (s notifiedSession) SessionID() string                                   { return "admin-test" }
+func (s notifiedSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s }
+func (s notifiedSession) Initialize()                                         {}
+func (s notifiedSession) Initialized() bool                                   { return true }
+
+func TestAdminDBResources(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := servermcp.NewServer(&log.Logger{Logger: zap.NewNop()}, servermcp.WithMCPSrv(server.NewMCPServer("test", "1.0.0",
+		server.WithResourceCapabilities(false, true),
+	)))
+	session := make(notifiedSession, 10)
+	if err := srv.RegisterSession(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+	h := NewAdminHandler(NewHandler(&log.Logger{Logger: zap.NewNop()}), nil, nil, newTestResourceService(t), srv)
+	router := gin.New()
+	router.GET("/db/resources", h.ListDBResources)
+	router.POST("/db/resources", h.CreateDBResource)
+	router.GET("/db/resources/:id", h.GetDBResource)
+	router.PUT("/db/resources/:id", h.UpdateDBResource)
+	router.DELETE("/db/resources/:id", h.DeleteDBResource)
+
+	// Each step runs on the state left by the previous one.
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantData   string // a substring of the JSON data
+		wantNotify bool
+	}{
+		{name: "create", method: http.MethodPost, path: "/db/resources", body: `{"name":"notes","text":"hello"}`, wantStatus: http.StatusOK, wantData: `"uri":"db://resource/1"`, wantNotify: true},
+		{name: "create blob", method: http.MethodPost, path: "/db/resources", body: `{"name":"logo","blob":"iVBORw=="}`, wantStatus: http.StatusOK, wantData: `"mime_type":"application/octet-stream"`, wantNotify: true},
+		{name: "create without a name", method: http.MethodPost, path: "/db/resources", body: `{"text":"hello"}`, wantStatus: http.StatusBadRequest},
+		{name: "create text and blob", method: http.MethodPost, path: "/db/resources", body: `{"name":"both","text":"a","blob":"YQ=="}`, wantStatus: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, path: "/db/resources/1", wantStatus: http.StatusOK, wantData: `"text":"hello"`},
+		{name: "get an invalid id", method: http.MethodGet, path: "/db/resources/one", wantStatus: http.StatusBadRequest},
+		{name: "get a missing id", method: http.MethodGet, path: "/db/resources/99", wantStatus: http.StatusNotFound},
+		{name: "update", method: http.MethodPut, path: "/db/resources/1", body: `{"name":"notes","text":"bye"}`, wantStatus: http.StatusOK, wantData: `"text":"bye"`, wantNotify: true},
+		{name: "update a missing id", method: http.MethodPut, path: "/db/resources/99", body: `{"name":"notes"}`, wantStatus: http.StatusNotFound},
+		{name: "list a page", method: http.MethodGet, path: "/db/resources?limit=1", wantStatus: http.StatusOK, wantData: `"next_after":1`},
+		{name: "list the next page", method: http.MethodGet, path: "/db/resources?limit=1&after=1", wantStatus: http.StatusOK, wantData: `"name":"logo"`},
+		{name: "list with an invalid limit", method: http.MethodGet, path: "/db/resources?limit=1000", wantStatus: http.StatusBadRequest},
+		{name: "list with an invalid cursor", method: http.MethodGet, path: "/db/resources?after=x", wantStatus: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, path: "/db/resources/1", wantStatus: http.StatusOK, wantNotify: true},
+		{name: "delete again", method: http.MethodDelete, path: "/db/resources/1", wantStatus: http.StatusNotFound},
+		{name: "list after delete", method: http.MethodGet, path: "/db/resources", wantStatus: http.StatusOK, wantData: `"resources":[{"id":2,`},
+	}
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: status %d %s, want %d", step.name, w.Code, w.Body, step.wantStatus)
+		}
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if !strings.Contains(string(resp.Data), step.wantData) {
+			t.Fatalf("%s: data %s, want %s", step.name, resp.Data, step.wantData)
+		}
+		select {
+		case n := <-session:
+			if !step.wantNotify || n.Method != mcp.MethodNotificationResourcesListChanged {
+				t.Fatalf("%s: sent %s, want notify %v", step.name, n.Method, step.wantNotify)
+			}
+		default:
+			if step.wantNotify {
+				t.Fatalf("%s: sent no %s", step.name, mcp.MethodNotificationResourcesListChanged)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/service"
+	"github.com/mark3labs/mcp-go/mcp"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resource templates of the audit log. Query parameters and path follow the
+// admin API: audit://records?tool=http_request&status=error&limit=10.
+const (
+	AuditRecordsTemplate = "audit://records{?session,caller,tool,status,since,until,before,limit}"
+	AuditRecordTemplate  = "audit://records/{id}"
+)
+
+// AuditHandler serves the audit log through the admin API and as MCP
+// resources.
+type AuditHandler struct {
+	*Handler
+	auditSvc service.AuditService
+}
+
+// NewAuditHandler returns nil when the audit log is disabled.
+func NewAuditHandler(
+	handler *Handler,
+	auditSvc service.AuditService,
+) *AuditHandler {
+	if auditSvc == nil {
+		return nil
+	}
+	return &AuditHandler{
+		Handler:  handler,
+		auditSvc: auditSvc,
+	}
+}
+
+func (h *AuditHandler) ListAudit(ctx *gin.Context) {
+	var req v1.ListAuditRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+		return
+	}
+	resp, err := h.auditSvc.List(ctx, &req)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+	v1.HandleSuccess(ctx, resp)
+}
+
+func (h *AuditHandler) GetAudit(ctx *gin.Context) {
+	id, ok := paramID(ctx)
+	if !ok {
+		return
+	}
+	record, err := h.auditSvc.Get(ctx, id)
+	if err != nil {
+		h.handleError(ctx, err)
+		return
+	}
+	v1.HandleSuccess(ctx, record)
+}
+
+// ReadRecords serves AuditRecordsTemplate. The query is parsed here rather
+// than taken from the template match, which only works when the parameters
+// come in template order.
+func (h *AuditHandler) ReadRecords(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	u, err := url.Parse(request.Params.URI)
+	if err != nil {
+		return nil, v1.Errorf(v1.KindValidation, "invalid URI: %w", err)
+	}
+	req, err := listAuditRequest(u.Query())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.auditSvc.List(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return jsonContents(request.Params.URI, resp)
+}
+
+// ReadRecord serves AuditRecordTemplate.
+func (h *AuditHandler) ReadRecord(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(request.Params.URI, "audit://records/"), 10, 0)
+	if err != nil {
+		return nil, v1.Errorf(v1.KindValidation, "invalid audit record URI %q", request.Params.URI)
+	}
+	record, err := h.auditSvc.Get(ctx, uint(id))
+	if err != nil {
+		return nil, err
+	}
+	return jsonContents(request.Params.URI, record)
+}
+
+func listAuditRequest(query url.Values) (*v1.ListAuditRequest, error) {
+	req := &v1.ListAuditRequest{
+		Session: query.Get("session"),
+		Caller:  query.Get("caller"),
+		Tool:    query.Get("tool"),
+		Status:  query.Get("status"),
+	}
+	var err error
+	for name, t := range map[string]*time.Time{"since": &req.Since, "until": &req.Until} {
+		if s := query.Get(name); s != "" {
+			if *t, err = time.Parse(time.RFC3339, s); err != nil {
+				return nil, v1.Errorf(v1.KindValidation, "%s must be an RFC 3339 time: %w", name, err)
+			}
+		}
+	}
+	if s := query.Get("before"); s != "" {
+		before, err := strconv.ParseUint(s, 10, 0)
+		if err != nil {
+			return nil, v1.Errorf(v1.KindValidation, "before must be a record id: %w", err)
+		}
+		req.Before = uint(before)
+	}
+	if s := query.Get("limit"); s != "" {
+		if req.Limit, err = strconv.Atoi(s); err != nil {
+			return nil, v1.Errorf(v1.KindValidation, "limit must be a number: %w", err)
+		}
+	}
+	return req, nil
+}
+
+func jsonContents(uri string, v any) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "application/json",
+			Text:     string(data),
+		},
+	}, nil
+}
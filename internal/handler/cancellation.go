@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"sync"
+)
+
+// metaRequestIdKey carries the JSON-RPC id from the BeforeCallTool hook to
+// the tool middleware, which otherwise never sees it.
+const metaRequestIdKey = "nunu/requestId"
+
+// Cancellation gives every tool call a context that is cancelled when the
+// client sends notifications/cancelled for it or the server shuts down.
+type Cancellation struct {
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+}
+
+func NewCancellation() *Cancellation {
+	return &Cancellation{
+		inflight: map[string]context.CancelFunc{},
+	}
+}
+
+// BeforeCallTool is a server hook that records the request id on the call.
+func (c *Cancellation) BeforeCallTool(ctx context.Context, id any, message *mcp.CallToolRequest) {
+	if message.Params.Meta == nil {
+		message.Params.Meta = &mcp.Meta{}
+	}
+	if message.Params.Meta.AdditionalFields == nil {
+		message.Params.Meta.AdditionalFields = map[string]any{}
+	}
+	message.Params.Meta.AdditionalFields[metaRequestIdKey] = id
+}
+
+// Middleware registers the tool call so it can be cancelled.
+func (c *Cancellation) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if request.Params.Meta == nil {
+			return next(ctx, request)
+		}
+		id, ok := request.Params.Meta.AdditionalFields[metaRequestIdKey]
+		if !ok {
+			return next(ctx, request)
+		}
+		delete(request.Params.Meta.AdditionalFields, metaRequestIdKey)
+
+		ctx, cancel := context.WithCancel(ctx)
+		key := inflightKey(ctx, id)
+		c.mu.Lock()
+		c.inflight[key] = cancel
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.inflight, key)
+			c.mu.Unlock()
+			cancel()
+		}()
+
+		return next(ctx, request)
+	}
+}
+
+// Notification handles notifications/cancelled.
+func (c *Cancellation) Notification(ctx context.Context, notification mcp.JSONRPCNotification) {
+	id, ok := notification.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	cancel, ok := c.inflight[inflightKey(ctx, id)]
+	c.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// CancelAll cancels every in-flight tool call, e.g. on server shutdown.
+func (c *Cancellation) CancelAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cancel := range c.inflight {
+		cancel()
+	}
+}
+
+// inflightKey scopes request ids to the session, since every client numbers
+// its own requests.
+func inflightKey(ctx context.Context, id any) string {
+	sessionId := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionId = session.SessionID()
+	}
+	return fmt.Sprintf("%s/%v", sessionId, id)
+}
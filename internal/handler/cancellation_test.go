@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"github.com/mark3labs/mcp-go/mcp"
+	"testing"
+	"time"
+)
+
+func TestCancellation(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel func(c *Cancellation)
+		want   error
+	}{
+		{
+			name: "notifications/cancelled",
+			cancel: func(c *Cancellation) {
+				notification := mcp.JSONRPCNotification{}
+				notification.Params.AdditionalFields = map[string]any{"requestId": 7}
+				c.Notification(context.Background(), notification)
+			},
+			want: context.Canceled,
+		},
+		{
+			name: "other request",
+			cancel: func(c *Cancellation) {
+				notification := mcp.JSONRPCNotification{}
+				notification.Params.AdditionalFields = map[string]any{"requestId": 8}
+				c.Notification(context.Background(), notification)
+			},
+		},
+		{name: "shutdown", cancel: (*Cancellation).CancelAll, want: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCancellation()
+			request := mcp.CallToolRequest{}
+			c.BeforeCallTool(context.Background(), 7, &request)
+
+			started := make(chan struct{})
+			done := make(chan error, 1)
+			go func() {
+				_, err := c.Middleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+					close(started)
+					return nil, Sleep(ctx, 200*time.Millisecond)
+				})(context.Background(), request)
+				done <- err
+			}()
+			<-started
+			tt.cancel(c)
+			if err := <-done; err != tt.want {
+				t.Fatalf("tool returned %v, want %v", err, tt.want)
+			}
+			if len(c.inflight) != 0 {
+				t.Fatalf("%d calls still in flight", len(c.inflight))
+			}
+		})
+	}
+}
@@ -0,0 +1,502 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"github.com/go-nunu/nunu-layout-mcp/internal/service"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// CatalogSource owns the catalog entries in the registry.
+const CatalogSource = "catalog"
+
+// Catalog turns the YAML definitions in mcp.tools.dir into tools, prompts
+// and resources, so simple ones can be shipped without touching Go code.
+type Catalog struct {
+	*Handler
+	dir        string
+	exampleSvc service.ExampleService
+	handlers   map[string]server.ToolHandlerFunc
+}
+
+// CatalogEntries is everything defined in the catalog directory.
+type CatalogEntries struct {
+	Tools     []server.ServerTool
+	Prompts   []server.ServerPrompt
+	Resources []server.ServerResource
+}
+
+func NewCatalog(
+	conf *viper.Viper,
+	handler *Handler,
+	exampleSvc service.ExampleService,
+	exampleHandler ExampleHandler,
+) *Catalog {
+	conf.SetDefault("mcp.tools.dir", "tools")
+	c := &Catalog{
+		Handler:    handler,
+		dir:        conf.GetString("mcp.tools.dir"),
+		exampleSvc: exampleSvc,
+		handlers:   map[string]server.ToolHandlerFunc{},
+	}
+	c.Register(string(model.ECHO), NewToolHandler(exampleHandler.EchoTool))
+	c.Register(string(model.ADD), NewToolHandler(exampleHandler.AddTool))
+	c.Register(string(model.HTTP_REQUEST), NewToolHandler(exampleHandler.HttpTool))
+	c.Register(string(model.SAMPLE_LLM), NewToolHandler(exampleHandler.SampleLLMTool))
+	c.Register(string(model.LONG_RUNNING_OPERATION), NewToolHandler(exampleHandler.LongRunningOperationTool))
+	c.Register(string(model.GET_TINY_IMAGE), exampleHandler.GetTinyImageTool)
+	return c
+}
+
+// Register makes a Go handler available to definitions as `handler: <name>`.
+func (c *Catalog) Register(name string, handler server.ToolHandlerFunc) {
+	c.handlers[name] = handler
+}
+
+// Dir is the catalog directory, mcp.tools.dir.
+func (c *Catalog) Dir() string {
+	return c.dir
+}
+
+// Reload loads the catalog directory and replaces the previous catalog
+// entries in registry. A broken catalog leaves the registry untouched.
+func (c *Catalog) Reload(registry *servermcp.Registry) (*CatalogEntries, error) {
+	entries, err := c.Load(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := registry.Set(CatalogSource, entries.Tools, entries.Prompts, entries.Resources); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Load reads every *.yaml and *.yml file in dir. A missing dir is not an error.
+func (c *Catalog) Load(dir string) (*CatalogEntries, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && IsCatalogFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return &CatalogEntries{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+
+	entries := &CatalogEntries{}
+	seen := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		def, err := DecodeDefinition(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		var key string
+		switch def := def.(type) {
+		case *model.ToolDefinition:
+			key = "tool " + strconv.Quote(def.Name)
+			tool, err := c.Tool(def)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			entries.Tools = append(entries.Tools, tool)
+		case *model.PromptDefinition:
+			key = "prompt " + strconv.Quote(def.Name)
+			prompt, err := c.Prompt(def)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			entries.Prompts = append(entries.Prompts, prompt)
+		case *model.ResourceDefinition:
+			key = "resource " + strconv.Quote(def.URI)
+			resource, err := c.Resource(def)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			entries.Resources = append(entries.Resources, resource)
+		}
+		if prev, ok := seen[key]; ok {
+			return nil, fmt.Errorf("%s: %s is already defined in %s", file, key, prev)
+		}
+		seen[key] = file
+	}
+	return entries, nil
+}
+
+// IsCatalogFile reports whether path is read by Load.
+func IsCatalogFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// DecodeDefinition decodes a single definition, written in YAML or JSON,
+// into a *model.ToolDefinition, *model.PromptDefinition or
+// *model.ResourceDefinition depending on its kind. Unknown fields are errors.
+func DecodeDefinition(data []byte) (any, error) {
+	var header struct {
+		Kind string `yaml:"kind"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	var def any
+	switch header.Kind {
+	case "", model.KindTool:
+		def = &model.ToolDefinition{}
+	case model.KindPrompt:
+		def = &model.PromptDefinition{}
+	case model.KindResource:
+		def = &model.ResourceDefinition{}
+	default:
+		return nil, fmt.Errorf("unknown kind %q", header.Kind)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(def); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// Tool builds the tool and handler for a single definition.
+func (c *Catalog) Tool(def *model.ToolDefinition) (server.ServerTool, error) {
+	if def.Name == "" {
+		return server.ServerTool{}, errors.New("name is required")
+	}
+	backends := 0
+	for _, set := range []bool{def.Http != nil, def.Static != nil, def.Handler != ""} {
+		if set {
+			backends++
+		}
+	}
+	if backends != 1 {
+		return server.ServerTool{}, errors.New("exactly one of http, static and handler must be set")
+	}
+
+	schema := def.InputSchema
+	if schema == nil {
+		schema = map[string]any{"type": "object"}
+	}
+	rawSchema, err := json.Marshal(schema)
+	if err != nil {
+		return server.ServerTool{}, fmt.Errorf("inputSchema: %w", err)
+	}
+	tool := mcp.NewToolWithRawSchema(def.Name, def.Description, rawSchema)
+	if a := def.Annotations; a != nil {
+		tool.Annotations = mcp.ToolAnnotation{
+			Title:           a.Title,
+			ReadOnlyHint:    a.ReadOnlyHint,
+			DestructiveHint: a.DestructiveHint,
+			IdempotentHint:  a.IdempotentHint,
+			OpenWorldHint:   a.OpenWorldHint,
+		}
+	}
+
+	if def.Handler != "" {
+		handler, ok := c.handlers[def.Handler]
+		if !ok {
+			return server.ServerTool{}, fmt.Errorf("unknown handler %q", def.Handler)
+		}
+		return server.ServerTool{Tool: tool, Handler: withArguments(schema, false, handler)}, nil
+	}
+
+	var handler server.ToolHandlerFunc
+	if def.Static != nil {
+		handler, err = c.staticHandler(def.Static)
+	} else {
+		handler, err = c.httpHandler(def.Http)
+	}
+	if err != nil {
+		return server.ServerTool{}, err
+	}
+	return server.ServerTool{Tool: tool, Handler: withArguments(schema, true, handler)}, nil
+}
+
+// Prompt builds the prompt and handler for a single definition.
+func (c *Catalog) Prompt(def *model.PromptDefinition) (server.ServerPrompt, error) {
+	if def.Name == "" {
+		return server.ServerPrompt{}, errors.New("name is required")
+	}
+	if len(def.Messages) == 0 {
+		return server.ServerPrompt{}, errors.New("messages is required")
+	}
+	prompt := mcp.NewPrompt(def.Name, mcp.WithPromptDescription(def.Description))
+	for _, arg := range def.Arguments {
+		if arg.Name == "" {
+			return server.ServerPrompt{}, errors.New("arguments: name is required")
+		}
+		opts := []mcp.ArgumentOption{mcp.ArgumentDescription(arg.Description)}
+		if arg.Required {
+			opts = append(opts, mcp.RequiredArgument())
+		}
+		mcp.WithArgument(arg.Name, opts...)(&prompt)
+	}
+
+	roles := make([]mcp.Role, len(def.Messages))
+	texts := make([]*template.Template, len(def.Messages))
+	for i, msg := range def.Messages {
+		switch role := mcp.Role(msg.Role); role {
+		case mcp.RoleUser, mcp.RoleAssistant:
+			roles[i] = role
+		case "":
+			roles[i] = mcp.RoleUser
+		default:
+			return server.ServerPrompt{}, fmt.Errorf("messages[%d].role %q must be user or assistant", i, msg.Role)
+		}
+		text, err := parseTemplate(fmt.Sprintf("messages[%d].text", i), msg.Text)
+		if err != nil {
+			return server.ServerPrompt{}, err
+		}
+		texts[i] = text
+	}
+
+	handler := func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := map[string]any{}
+		for _, arg := range def.Arguments {
+			value, ok := request.Params.Arguments[arg.Name]
+			if !ok && arg.Required {
+				return nil, v1.Errorf(v1.KindValidation, "argument %q is required", arg.Name)
+			}
+			args[arg.Name] = value
+		}
+		messages := make([]mcp.PromptMessage, len(texts))
+		for i, text := range texts {
+			out, err := render(text, args)
+			if err != nil {
+				return nil, err
+			}
+			messages[i] = mcp.NewPromptMessage(roles[i], mcp.NewTextContent(out))
+		}
+		return mcp.NewGetPromptResult(def.Description, messages), nil
+	}
+	return server.ServerPrompt{Prompt: prompt, Handler: handler}, nil
+}
+
+// Resource builds the resource and handler for a single definition.
+func (c *Catalog) Resource(def *model.ResourceDefinition) (server.ServerResource, error) {
+	if def.URI == "" {
+		return server.ServerResource{}, errors.New("uri is required")
+	}
+	if _, err := url.Parse(def.URI); err != nil {
+		return server.ServerResource{}, fmt.Errorf("uri: %w", err)
+	}
+	name := def.Name
+	if name == "" {
+		name = def.URI
+	}
+	mimeType := def.MimeType
+	if mimeType == "" {
+		mimeType = "text/plain"
+	}
+	resource := mcp.NewResource(def.URI, name,
+		mcp.WithResourceDescription(def.Description),
+		mcp.WithMIMEType(mimeType),
+	)
+	handler := func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      def.URI,
+				MIMEType: mimeType,
+				Text:     def.Text,
+			},
+		}, nil
+	}
+	return server.ServerResource{Resource: resource, Handler: handler}, nil
+}
+
+func (c *Catalog) staticHandler(backend *model.StaticBackend) (server.ToolHandlerFunc, error) {
+	text, err := parseTemplate("static.text", backend.Text)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		out, err := render(text, request.GetArguments())
+		if err != nil {
+			return nil, err
+		}
+		if backend.IsError {
+			return mcp.NewToolResultError(out), nil
+		}
+		return mcp.NewToolResultText(out), nil
+	}, nil
+}
+
+func (c *Catalog) httpHandler(backend *model.HttpBackend) (server.ToolHandlerFunc, error) {
+	method := strings.ToUpper(backend.Method)
+	if method == "" {
+		method = "GET"
+	}
+	if !slices.Contains([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}, method) {
+		return nil, fmt.Errorf("http.method %q is not supported", backend.Method)
+	}
+	if backend.Url == "" {
+		return nil, errors.New("http.url is required")
+	}
+	urlTmpl, err := parseTemplate("http.url", backend.Url)
+	if err != nil {
+		return nil, err
+	}
+	body, err := parseTemplate("http.body", backend.Body)
+	if err != nil {
+		return nil, err
+	}
+	query, err := parseTemplates("http.query", backend.Query)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := parseTemplates("http.headers", backend.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		params := &v1.HttpToolRequest{
+			Method:           method,
+			Timeout:          backend.Timeout,
+			MaxResponseBytes: backend.MaxResponseBytes,
+		}
+		var err error
+		if params.Url, err = render(urlTmpl, args); err != nil {
+			return nil, err
+		}
+		if params.Body, err = render(body, args); err != nil {
+			return nil, err
+		}
+		if params.Query, err = renderAll(query, args); err != nil {
+			return nil, err
+		}
+		if params.Headers, err = renderAll(headers, args); err != nil {
+			return nil, err
+		}
+		return c.exampleSvc.HttpTool(ctx, params)
+	}, nil
+}
+
+// withArguments rejects calls that miss a required argument. For template
+// backends it also fills in absent properties with their schema default, or
+// "" so templates never print "<no value>".
+func withArguments(schema map[string]any, fill bool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	properties, _ := schema["properties"].(map[string]any)
+	var required []string
+	if list, ok := schema["required"].([]any); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required = append(required, s)
+			}
+		}
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := map[string]any{}
+		for k, v := range request.GetArguments() {
+			args[k] = v
+		}
+		var missing []string
+		for _, name := range required {
+			if _, ok := args[name]; !ok {
+				missing = append(missing, "- "+name+": is required")
+			}
+		}
+		if len(missing) > 0 {
+			return nil, v1.Errorf(v1.KindValidation, "invalid arguments:\n%s", strings.Join(missing, "\n"))
+		}
+		if !fill {
+			return next(ctx, request)
+		}
+		for name, property := range properties {
+			if _, ok := args[name]; ok {
+				continue
+			}
+			args[name] = ""
+			if p, ok := property.(map[string]any); ok && p["default"] != nil {
+				args[name] = p["default"]
+			}
+		}
+		request.Params.Arguments = args
+		return next(ctx, request)
+	}
+}
+
+// templateFuncs adds to the text/template builtins, which already include
+// urlquery for query-string escaping.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"pathescape": func(v any) string {
+		return url.PathEscape(fmt.Sprint(v))
+	},
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func parseTemplates(name string, texts map[string]string) (map[string]*template.Template, error) {
+	tmpls := make(map[string]*template.Template, len(texts))
+	for key, text := range texts {
+		tmpl, err := parseTemplate(name+"."+key, text)
+		if err != nil {
+			return nil, err
+		}
+		tmpls[key] = tmpl
+	}
+	return tmpls, nil
+}
+
+func render(tmpl *template.Template, args map[string]any) (string, error) {
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, args); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderAll renders every template and drops the ones that come out empty,
+// so optional query parameters and headers are simply left out.
+func renderAll(tmpls map[string]*template.Template, args map[string]any) (map[string]string, error) {
+	out := make(map[string]string, len(tmpls))
+	for key, tmpl := range tmpls {
+		value, err := render(tmpl, args)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			out[key] = value
+		}
+	}
+	return out, nil
+}
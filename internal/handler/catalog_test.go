@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"context"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newTestCatalog(dir string) *Catalog {
+	conf := viper.New()
+	conf.Set("mcp.tools.dir", dir)
+	h := NewHandler(&log.Logger{Logger: zap.NewNop()})
+	return NewCatalog(conf, h, nil, NewExampleHandler(h, nil, nil))
+}
+
+// writeFiles writes files, keyed by their path relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const (
+	catalogTool     = "name: greet\nstatic:\n  text: hi\n"
+	catalogPrompt   = "kind: prompt\nname: review\nmessages:\n  - text: review this\n"
+	catalogResource = "kind: resource\nuri: docs://readme\ntext: read me\n"
+)
+
+func TestCatalogLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		tools     []string
+		prompts   []string
+		resources []string
+		wantErr   string
+	}{
+		{name: "empty"},
+		{
+			name: "every kind",
+			files: map[string]string{
+				"greet.yaml":       catalogTool,
+				"prompts/p.yml":    catalogPrompt,
+				"docs/readme.yaml": catalogResource,
+				"handler.yaml":     "name: sum\nhandler: add\n",
+				"notes.txt":        "not a definition",
+			},
+			tools:     []string{"greet", "sum"},
+			prompts:   []string{"review"},
+			resources: []string{"docs://readme"},
+		},
+		{
+			name:    "same tool twice",
+			files:   map[string]string{"a.yaml": catalogTool, "b.yaml": catalogTool},
+			wantErr: `b.yaml: tool "greet" is already defined in`,
+		},
+		{
+			name:    "unknown kind",
+			files:   map[string]string{"a.yaml": "kind: widget\nname: w\n"},
+			wantErr: `a.yaml: unknown kind "widget"`,
+		},
+		{
+			name:    "unknown field",
+			files:   map[string]string{"a.yaml": "name: greet\nstatik:\n  text: hi\n"},
+			wantErr: "field statik not found",
+		},
+		{
+			name:    "no name",
+			files:   map[string]string{"a.yaml": "static:\n  text: hi\n"},
+			wantErr: "a.yaml: name is required",
+		},
+		{
+			name:    "two backends",
+			files:   map[string]string{"a.yaml": "name: greet\nhandler: add\nstatic:\n  text: hi\n"},
+			wantErr: "exactly one of http, static and handler must be set",
+		},
+		{
+			name:    "unknown handler",
+			files:   map[string]string{"a.yaml": "name: greet\nhandler: missing\n"},
+			wantErr: `unknown handler "missing"`,
+		},
+		{
+			name:    "broken template",
+			files:   map[string]string{"a.yaml": "name: greet\nstatic:\n  text: '{{ .name'\n"},
+			wantErr: "static.text",
+		},
+		{
+			name:    "unsupported http method",
+			files:   map[string]string{"a.yaml": "name: get\nhttp:\n  method: TRACE\n  url: https://example.com\n"},
+			wantErr: `http.method "TRACE" is not supported`,
+		},
+		{
+			name:    "system prompt message",
+			files:   map[string]string{"a.yaml": "kind: prompt\nname: p\nmessages:\n  - role: system\n    text: hi\n"},
+			wantErr: `messages[0].role "system" must be user or assistant`,
+		},
+		{
+			name:    "resource without uri",
+			files:   map[string]string{"a.yaml": "kind: resource\ntext: hi\n"},
+			wantErr: "uri is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			entries, err := newTestCatalog(dir).Load(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(entries.Tools, func(tool server.ServerTool) string { return tool.Tool.Name }); !slices.Equal(got, orEmpty(tt.tools)) {
+				t.Errorf("tools = %v, want %v", got, tt.tools)
+			}
+			if got := names(entries.Prompts, func(prompt server.ServerPrompt) string { return prompt.Prompt.Name }); !slices.Equal(got, orEmpty(tt.prompts)) {
+				t.Errorf("prompts = %v, want %v", got, tt.prompts)
+			}
+			if got := names(entries.Resources, func(resource server.ServerResource) string { return resource.Resource.URI }); !slices.Equal(got, orEmpty(tt.resources)) {
+				t.Errorf("resources = %v, want %v", got, tt.resources)
+			}
+		})
+	}
+}
+
+func TestCatalogLoadMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	entries, err := newTestCatalog(dir).Load(dir)
+	if err != nil || len(entries.Tools)+len(entries.Prompts)+len(entries.Resources) != 0 {
+		t.Fatalf("Load = %+v, %v, want an empty catalog", entries, err)
+	}
+}
+
+// The definitions shipped in tools/ are the documented examples.
+func TestCatalogLoadExamples(t *testing.T) {
+	dir := filepath.Join("..", "..", "tools")
+	entries, err := newTestCatalog(dir).Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries.Tools) == 0 || len(entries.Prompts) == 0 || len(entries.Resources) == 0 {
+		t.Fatalf("got %d tools, %d prompts and %d resources, want some of each", len(entries.Tools), len(entries.Prompts), len(entries.Resources))
+	}
+}
+
+func TestCatalogTools(t *testing.T) {
+	c := newTestCatalog("")
+	greet, err := c.Tool(decode[*model.ToolDefinition](t, `
+name: greet
+inputSchema:
+  type: object
+  properties:
+    name: {type: string}
+    greeting: {type: string, default: Hello}
+    suffix: {type: string}
+  required: [name]
+static:
+  text: "{{ .greeting }}, {{ .name }}{{ .suffix }}!"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := c.Tool(decode[*model.ToolDefinition](t, "name: sum\nhandler: add\ninputSchema:\n  type: object\n  required: [a, b]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing, err := c.Tool(decode[*model.ToolDefinition](t, "name: fail\nstatic:\n  text: 'no {{ .what }}'\n  isError: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tool    server.ServerTool
+		args    map[string]any
+		want    string
+		isError bool
+		wantErr string
+	}{
+		{name: "defaults filled in", tool: greet, args: map[string]any{"name": "Ada"}, want: "Hello, Ada!"},
+		{name: "arguments", tool: greet, args: map[string]any{"name": "Ada", "greeting": "Hi", "suffix": "?"}, want: "Hi, Ada?!"},
+		{name: "missing required", tool: greet, args: map[string]any{}, wantErr: "invalid arguments:\n- name: is required"},
+		{name: "go handler", tool: sum, args: map[string]any{"a": 1, "b": 2}, want: `{"sum":3}`},
+		{name: "go handler missing required", tool: sum, args: map[string]any{"a": 1}, wantErr: "invalid arguments:\n- b: is required"},
+		{name: "static error", tool: failing, args: map[string]any{"what": "luck"}, want: "no luck", isError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = tt.args
+			result, err := tt.tool.Handler(context.Background(), request)
+			if tt.wantErr != "" {
+				if v1.KindOf(err) != v1.KindValidation || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want validation error %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text := result.Content[0].(mcp.TextContent).Text; text != tt.want || result.IsError != tt.isError {
+				t.Fatalf("result = %q (isError %v), want %q (isError %v)", text, result.IsError, tt.want, tt.isError)
+			}
+		})
+	}
+}
+
+func TestCatalogPromptAndResource(t *testing.T) {
+	c := newTestCatalog("")
+	prompt, err := c.Prompt(decode[*model.PromptDefinition](t, `
+kind: prompt
+name: review
+description: Review code
+arguments:
+  - name: code
+    required: true
+  - name: focus
+messages:
+  - text: "Review{{ if .focus }} for {{ .focus }}{{ end }}: {{ .code }}"
+  - role: assistant
+    text: Sure.
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := mcp.GetPromptRequest{}
+	request.Params.Arguments = map[string]string{"code": "x := 1", "focus": "style"}
+	result, err := prompt.Handler(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Messages) != 2 || result.Messages[0].Role != mcp.RoleUser || result.Messages[1].Role != mcp.RoleAssistant ||
+		result.Messages[0].Content.(mcp.TextContent).Text != "Review for style: x := 1" {
+		t.Fatalf("messages = %+v", result.Messages)
+	}
+	request.Params.Arguments = map[string]string{}
+	if _, err := prompt.Handler(context.Background(), request); v1.KindOf(err) != v1.KindValidation {
+		t.Fatalf("missing argument: err = %v, want a validation error", err)
+	}
+
+	resource, err := c.Resource(decode[*model.ResourceDefinition](t, "kind: resource\nuri: docs://readme\ntext: read me\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resource.Resource.Name != "docs://readme" || resource.Resource.MIMEType != "text/plain" {
+		t.Fatalf("resource = %+v, want the uri as name and text/plain", resource.Resource)
+	}
+	contents, err := resource.Handler(context.Background(), mcp.ReadResourceRequest{})
+	if err != nil || contents[0].(mcp.TextResourceContents).Text != "read me" {
+		t.Fatalf("contents = %+v, %v", contents, err)
+	}
+}
+
+func TestCatalogReload(t *testing.T) {
+	dir := t.TempDir()
+	srv := servermcp.NewServer(&log.Logger{Logger: zap.NewNop()}, servermcp.WithMCPSrv(server.NewMCPServer("test", "1.0.0",
+		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, true),
+	)))
+	registry := servermcp.NewRegistry(srv)
+	c := newTestCatalog(dir)
+
+	writeFiles(t, dir, map[string]string{"greet.yaml": catalogTool, "sum.yaml": "name: sum\nhandler: add\n"})
+	if _, err := c.Reload(registry); err != nil {
+		t.Fatal(err)
+	}
+	if srv.GetTool("greet") == nil || srv.GetTool("sum") == nil {
+		t.Fatal("tools not registered")
+	}
+
+	// A broken file leaves the previous catalog in place.
+	writeFiles(t, dir, map[string]string{"broken.yaml": "name: broken\n"})
+	if _, err := c.Reload(registry); err == nil {
+		t.Fatal("Reload of a broken catalog succeeded")
+	}
+	if srv.GetTool("greet") == nil || srv.GetTool("sum") == nil {
+		t.Fatal("tools removed by a failed reload")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "broken.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "sum.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{"docs.yaml": catalogResource})
+	if _, err := c.Reload(registry); err != nil {
+		t.Fatal(err)
+	}
+	if srv.GetTool("greet") == nil || srv.GetTool("sum") != nil || !srv.HasResource("docs://readme") {
+		t.Fatal("reload did not replace the catalog")
+	}
+}
+
+// decode decodes a single definition of type T.
+func decode[T any](t *testing.T, data string) T {
+	t.Helper()
+	def, err := DecodeDefinition([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return def.(T)
+}
+
+func names[T any](items []T, name func(T) string) []string {
+	out := []string{}
+	for _, item := range items {
+		out = append(out, name(item))
+	}
+	return out
+}
+
+func orEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -9,23 +9,29 @@ import (
 	"github.com/go-nunu/nunu-layout-mcp/internal/service"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// DBResourceTemplate reads the resources stored in the database.
+const DBResourceTemplate = model.ResourceURIPrefix + "{id}"
+
 type ExampleHandler interface {
-	AddTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
-	EchoTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error)
-	HttpTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error)
-	SampleLLMTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error)
-	LongRunningOperationTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
+	AddTool(ctx context.Context, req *v1.AddToolRequest) (*v1.AddToolResponse, error)
+	EchoTool(ctx context.Context, req *v1.EchoToolRequest) (*v1.EchoToolResponse, error)
+	HttpTool(ctx context.Context, req *v1.HttpToolRequest) (*mcp.CallToolResult, error)
+	SampleLLMTool(ctx context.Context, req *v1.SampleLLMToolRequest) (*mcp.CallToolResult, error)
+	LongRunningOperationTool(ctx context.Context, params *v1.LongRunningOperationRequest) (*mcp.CallToolResult, error)
 	GetTinyImageTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
 
 	SimplePrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error)
 	ComplexPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error)
 
-	GeneratedResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error)
+	DBResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error)
+	ListDBResources(ctx context.Context, id any, message *mcp.ListResourcesRequest, result *mcp.ListResourcesResult)
 	ReadResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error)
 	ResourceTemplate(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error)
 
@@ -36,83 +42,52 @@ type ExampleHandler interface {
 func NewExampleHandler(
 	tool *Handler,
 	exampleSvc service.ExampleService,
+	samplingSvc service.SamplingService,
 ) ExampleHandler {
 	return &exampleHandler{
-		exampleSvc: exampleSvc,
-		Handler:    tool,
+		exampleSvc:  exampleSvc,
+		samplingSvc: samplingSvc,
+		Handler:     tool,
 	}
 }
 
 type exampleHandler struct {
-	exampleSvc service.ExampleService
+	exampleSvc  service.ExampleService
+	samplingSvc service.SamplingService
 	*Handler
 }
 
 func (h exampleHandler) EchoTool(
 	ctx context.Context,
-	req mcp.CallToolRequest,
-) (*mcp.CallToolResult, error) {
-	params := v1.EchoToolRequest{}
-	err := req.BindArguments(&params)
-	if err != nil {
-		return nil, err
-	}
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: fmt.Sprintf("Echo: %s", params.Message),
-			},
-		},
-	}, nil
+	params *v1.EchoToolRequest,
+) (*v1.EchoToolResponse, error) {
+	return &v1.EchoToolResponse{Message: params.Message}, nil
 }
-func (h exampleHandler) AddTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	params := v1.AddToolRequest{}
-	err := req.BindArguments(&params)
-	if err != nil {
-		return nil, err
-	}
-	sum := params.A + params.B
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: fmt.Sprintf("The sum of %f and %f is %f.", params.A, params.B, sum),
-			},
-		},
-	}, nil
+func (h exampleHandler) AddTool(ctx context.Context, params *v1.AddToolRequest) (*v1.AddToolResponse, error) {
+	return &v1.AddToolResponse{Sum: *params.A + *params.B}, nil
 }
 
-func (h exampleHandler) HttpTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	params := v1.HttpToolRequest{}
-	err := req.BindArguments(&params)
+func (h exampleHandler) HttpTool(ctx context.Context, params *v1.HttpToolRequest) (*mcp.CallToolResult, error) {
+	return h.exampleSvc.HttpTool(ctx, params)
+}
+
+func (h exampleHandler) SampleLLMTool(ctx context.Context, params *v1.SampleLLMToolRequest) (*mcp.CallToolResult, error) {
+	resp, err := h.samplingSvc.CreateMessage(ctx, &v1.SamplingRequest{
+		Messages:             []v1.SamplingMessage{{Role: string(mcp.RoleUser), Text: params.Prompt}},
+		SystemPrompt:         params.SystemPrompt,
+		MaxTokens:            params.MaxTokens,
+		Temperature:          params.Temperature,
+		ModelHints:           params.ModelHints,
+		CostPriority:         params.CostPriority,
+		SpeedPriority:        params.SpeedPriority,
+		IntelligencePriority: params.IntelligencePriority,
+		Timeout:              time.Duration(params.Timeout * float64(time.Second)),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return h.exampleSvc.HttpTool(ctx, &params)
-}
-
-func (h exampleHandler) SampleLLMTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args := req.GetArguments()
-	prompt, _ := args["prompt"].(string)
-	maxTokens, _ := args["maxTokens"].(float64)
-
-	// This is a mock implementation. In a real scenario, you would use the server's RequestSampling method.
-	result := fmt.Sprintf(
-		"Sample LLM result for prompt: '%s' (max tokens: %d)",
-		prompt,
-		int(maxTokens),
-	)
-
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: fmt.Sprintf("LLM sampling result: %s", result),
-			},
-		},
-	}, nil
+	return mcp.NewToolResultStructured(resp, fmt.Sprintf("LLM sampling result (model: %s): %s", resp.Model, resp.Text)), nil
 }
 
 func (h exampleHandler) SimplePrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
@@ -147,38 +122,74 @@ func (h exampleHandler) ResourceTemplate(ctx context.Context, request mcp.ReadRe
 		},
 	}, nil
 }
-func (h exampleHandler) GeneratedResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	uri := request.Params.URI
 
-	var resourceNumber string
-	if _, err := fmt.Sscanf(uri, "test://static/resource/%s", &resourceNumber); err != nil {
-		return nil, fmt.Errorf("invalid resource URI format: %w", err)
+// DBResource serves DBResourceTemplate.
+func (h exampleHandler) DBResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(request.Params.URI, model.ResourceURIPrefix), 10, 0)
+	if err != nil {
+		return nil, v1.Errorf(v1.KindNotFound, "invalid resource URI %q", request.Params.URI)
 	}
-
-	num, err := strconv.Atoi(resourceNumber)
+	resource, err := h.exampleSvc.GetResource(ctx, uint(id))
 	if err != nil {
-		return nil, fmt.Errorf("invalid resource number: %w", err)
+		return nil, err
 	}
-
-	index := num - 1
-
-	if index%2 == 0 {
-		return []mcp.ResourceContents{
-			mcp.TextResourceContents{
-				URI:      uri,
-				MIMEType: "text/plain",
-				Text:     fmt.Sprintf("Text content for resource %d", num),
-			},
-		}, nil
-	} else {
+	if len(resource.Blob) > 0 {
 		return []mcp.ResourceContents{
 			mcp.BlobResourceContents{
-				URI:      uri,
-				MIMEType: "application/octet-stream",
-				Blob:     base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("Binary content for resource %d", num))),
+				URI:      resource.URI,
+				MIMEType: resource.MIMEType,
+				Blob:     base64.StdEncoding.EncodeToString(resource.Blob),
 			},
 		}, nil
 	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      resource.URI,
+			MIMEType: resource.MIMEType,
+			Text:     resource.Text,
+		},
+	}, nil
+}
+
+// dbCursorPrefix starts the decoded cursors of database pages. It sorts
+// after every resource name, and mcp-go lists the in-memory resources named
+// after the cursor, so it lists none for these pages.
+const dbCursorPrefix = "\U0010FFFFdb:"
+
+// ListDBResources is an AfterListResources hook that pages through the
+// database resources once mcp-go has listed the in-memory ones.
+func (h exampleHandler) ListDBResources(ctx context.Context, id any, message *mcp.ListResourcesRequest, result *mcp.ListResourcesResult) {
+	if result.NextCursor != "" {
+		return
+	}
+	var after uint
+	if cursor, err := base64.StdEncoding.DecodeString(string(message.Params.Cursor)); err == nil {
+		if s, ok := strings.CutPrefix(string(cursor), dbCursorPrefix); ok {
+			n, err := strconv.ParseUint(s, 10, 0)
+			if err != nil {
+				h.logger.WithContext(ctx).Warn("invalid resources cursor", zap.String("cursor", string(message.Params.Cursor)))
+				return
+			}
+			after = uint(n)
+		}
+	}
+	resp, err := h.exampleSvc.ListResources(ctx, &v1.ListResourcesRequest{After: after})
+	if err != nil {
+		h.logger.WithContext(ctx).Error("list database resources failed", zap.Error(err))
+		return
+	}
+	for _, resource := range resp.Resources {
+		result.Resources = append(result.Resources, mcp.Resource{
+			URI:         resource.URI,
+			Name:        resource.Name,
+			Description: resource.Description,
+			MIMEType:    resource.MIMEType,
+		})
+	}
+	if resp.NextAfter > 0 {
+		result.NextCursor = mcp.Cursor(base64.StdEncoding.EncodeToString(
+			[]byte(dbCursorPrefix + strconv.FormatUint(uint64(resp.NextAfter), 10))))
+	}
 }
 func (h exampleHandler) Notification(ctx context.Context, notification mcp.JSONRPCNotification) {
 	log.Printf("Received notification: %s", notification.Method)
@@ -186,30 +197,25 @@ func (h exampleHandler) Notification(ctx context.Context, notification mcp.JSONR
 func (h exampleHandler) GetTinyImageTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return h.exampleSvc.GetTinyImageTool(ctx)
 }
-func (h exampleHandler) LongRunningOperationTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	arguments := request.GetArguments()
-	progressToken := request.Params.Meta.ProgressToken
-	duration, _ := arguments["duration"].(float64)
-	steps, _ := arguments["steps"].(float64)
-	stepDuration := duration / steps
-	server := server.ServerFromContext(ctx)
+func (h exampleHandler) LongRunningOperationTool(ctx context.Context, params *v1.LongRunningOperationRequest) (*mcp.CallToolResult, error) {
+	duration, steps := 10.0, 5
+	if params.Duration != nil {
+		duration = *params.Duration
+	}
+	if params.Steps != nil {
+		steps = *params.Steps
+	}
+	request, _ := ToolRequestFromContext(ctx)
+	stepDuration := time.Duration(duration / float64(steps) * float64(time.Second))
+	progress := NewProgressReporter(ctx, request, float64(steps))
 
-	for i := 1; i < int(steps)+1; i++ {
-		time.Sleep(time.Duration(stepDuration * float64(time.Second)))
-		if progressToken != nil {
-			err := server.SendNotificationToClient(
-				ctx,
-				"notifications/progress",
-				map[string]any{
-					"progress":      i,
-					"total":         int(steps),
-					"progressToken": progressToken,
-					"message":       fmt.Sprintf("Server progress %v%%", int(float64(i)*100/steps)),
-				},
-			)
-			if err != nil {
-				return nil, fmt.Errorf("failed to send notification: %w", err)
-			}
+	for i := 1; i <= steps; i++ {
+		if err := Sleep(ctx, stepDuration); err != nil {
+			h.logger.WithContext(ctx).Info("long running operation cancelled", zap.Int("step", i), zap.Error(err))
+			return nil, fmt.Errorf("operation cancelled at step %d of %d: %w", i, steps, err)
+		}
+		if err := progress.Report(ctx, float64(i), ""); err != nil {
+			return nil, err
 		}
 	}
 
@@ -220,7 +226,7 @@ func (h exampleHandler) LongRunningOperationTool(ctx context.Context, request mc
 				Text: fmt.Sprintf(
 					"Long running operation completed. Duration: %f seconds, Steps: %d.",
 					duration,
-					int(steps),
+					steps,
 				),
 			},
 		},
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/migration"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/internal/service"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/egress"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newTestResourceService returns an ExampleService on a migrated SQLite
+// database.
+func newTestResourceService(t *testing.T) service.ExampleService {
+	t.Helper()
+	logger := &log.Logger{Logger: zap.NewNop()}
+	conf := viper.New()
+	conf.Set("data.resources.enable", true)
+	conf.Set("data.db.user.driver", "sqlite")
+	conf.Set("data.db.user.dsn", filepath.Join(t.TempDir(), "test.db"))
+	conf.Set("data.migrations.on_start", "up")
+	db, err := migration.NewCheckedDB(conf, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := repository.NewRepository(logger, db)
+	return service.NewExampleService(service.NewService(repository.NewTransaction(repo), logger, nil), repository.NewExampleRepository(repo), egress.NewPolicy(viper.New()))
+}
+
+func TestDBResource(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestResourceService(t)
+	h := NewExampleHandler(NewHandler(&log.Logger{Logger: zap.NewNop()}), svc, nil)
+	text, err := svc.CreateResource(ctx, &v1.ResourceRequest{Name: "notes", MIMEType: "text/markdown", Text: "# notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := svc.CreateResource(ctx, &v1.ResourceRequest{Name: "logo", MIMEType: "image/png", Blob: []byte{0x89, 'P', 'N', 'G'}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		uri     string
+		want    mcp.ResourceContents
+		wantErr v1.ErrorKind
+	}{
+		{name: "text", uri: text.URI, want: mcp.TextResourceContents{URI: text.URI, MIMEType: "text/markdown", Text: "# notes"}},
+		{name: "blob", uri: blob.URI, want: mcp.BlobResourceContents{URI: blob.URI, MIMEType: "image/png", Blob: base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G'})}},
+		{name: "missing", uri: "db://resource/99", wantErr: v1.KindNotFound},
+		{name: "not an id", uri: "db://resource/logo", wantErr: v1.KindNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.ReadResourceRequest{}
+			request.Params.URI = tt.uri
+			contents, err := h.DBResource(ctx, request)
+			if tt.wantErr != "" {
+				if v1.KindOf(err) != tt.wantErr {
+					t.Fatalf("DBResource = %v, want a %s error", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DBResource: %v", err)
+			}
+			if len(contents) != 1 || contents[0] != tt.want {
+				t.Fatalf("contents = %+v, want %+v", contents, tt.want)
+			}
+		})
+	}
+}
+
+func TestListDBResources(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestResourceService(t)
+	h := NewExampleHandler(NewHandler(&log.Logger{Logger: zap.NewNop()}), svc, nil)
+	// One more than the default page of the service.
+	var want []string
+	for i := 0; i < 51; i++ {
+		resource, err := svc.CreateResource(ctx, &v1.ResourceRequest{Name: fmt.Sprint("r", i), Text: "text"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, resource.URI)
+	}
+
+	tests := []struct {
+		name   string
+		opts   []server.ServerOption
+		static []string
+		pages  int
+	}{
+		{name: "in-memory resources on one page", static: []string{"test://a", "test://b"}, pages: 2},
+		{name: "in-memory resources paginated", opts: []server.ServerOption{server.WithPaginationLimit(1)}, static: []string{"test://a", "test://b"}, pages: 4},
+		{name: "no in-memory resources", pages: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks := &server.Hooks{}
+			hooks.AddAfterListResources(h.ListDBResources)
+			srv := server.NewMCPServer("test", "1.0.0", append(tt.opts, server.WithResourceCapabilities(false, true), server.WithHooks(hooks))...)
+			for _, uri := range tt.static {
+				srv.AddResource(mcp.NewResource(uri, uri), h.ReadResource)
+			}
+
+			var (
+				uris   []string
+				cursor mcp.Cursor
+				pages  int
+			)
+			for {
+				var result mcp.ListResourcesResult
+				rpc(t, srv, "resources/list", map[string]any{"cursor": cursor}, &result)
+				pages++
+				uris = append(uris, names(result.Resources, func(r mcp.Resource) string { return r.URI })...)
+				if result.NextCursor == "" || pages > 10 {
+					break
+				}
+				cursor = result.NextCursor
+			}
+			if wantURIs := append(slices.Clone(tt.static), want...); !slices.Equal(uris, wantURIs) {
+				t.Fatalf("listed %v, want %v", uris, wantURIs)
+			}
+			if pages != tt.pages {
+				t.Fatalf("listed %d pages, want %d", pages, tt.pages)
+			}
+		})
+	}
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		result := &mcp.ListResourcesResult{}
+		message := &mcp.ListResourcesRequest{}
+		message.Params.Cursor = mcp.Cursor(base64.StdEncoding.EncodeToString([]byte(dbCursorPrefix + "x")))
+		h.ListDBResources(ctx, 1, message, result)
+		if len(result.Resources) != 0 || result.NextCursor != "" {
+			t.Fatalf("result = %+v, want nothing listed", result)
+		}
+	})
+}
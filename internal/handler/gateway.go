@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/metrics"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// Gateway re-exports the tools, prompts and resources of upstream MCP
+// servers. Tool and prompt names get the upstream's prefix; resources keep
+// their URI. Every upstream owns its entries in the registry as
+// "gateway:<name>", so they can be swapped or dropped as a whole.
+type Gateway struct {
+	*Handler
+	registry *servermcp.Registry
+}
+
+func NewGateway(handler *Handler, registry *servermcp.Registry) *Gateway {
+	return &Gateway{
+		Handler:  handler,
+		registry: registry,
+	}
+}
+
+func gatewaySource(u *repository.Upstream) string {
+	return "gateway:" + u.Name
+}
+
+// Sync lists what upstream offers and replaces its entries in the registry.
+// Entries that clash with existing names are logged and skipped; only
+// failures to reach the upstream are returned.
+func (g *Gateway) Sync(ctx context.Context, u *repository.Upstream) error {
+	source := gatewaySource(u)
+	caps := u.Capabilities()
+
+	var tools []server.ServerTool
+	if caps.Tools != nil {
+		list, err := u.ListTools(ctx)
+		if err != nil {
+			return fmt.Errorf("tools/list: %w", err)
+		}
+		for _, tool := range list {
+			tools = append(tools, g.tool(u, tool))
+		}
+	}
+	var prompts []server.ServerPrompt
+	if caps.Prompts != nil {
+		list, err := u.ListPrompts(ctx)
+		if err != nil {
+			return fmt.Errorf("prompts/list: %w", err)
+		}
+		for _, prompt := range list {
+			prompts = append(prompts, g.prompt(u, prompt))
+		}
+	}
+	var resources []server.ServerResource
+	if caps.Resources != nil {
+		list, err := u.ListResources(ctx)
+		if err != nil {
+			return fmt.Errorf("resources/list: %w", err)
+		}
+		for _, resource := range list {
+			resources = append(resources, g.resource(u, resource))
+		}
+	}
+
+	err := errors.Join(
+		g.registry.SetTools(source, tools...),
+		g.registry.SetPrompts(source, prompts...),
+		g.registry.SetResources(source, resources...),
+	)
+	if err != nil {
+		g.logger.Error("some upstream entries were not registered", zap.String("upstream", u.Name), zap.Error(err))
+	}
+	g.logger.Info("synced upstream",
+		zap.String("upstream", u.Name),
+		zap.Int("tools", len(tools)),
+		zap.Int("prompts", len(prompts)),
+		zap.Int("resources", len(resources)),
+	)
+	return nil
+}
+
+// Clear removes every entry of upstream, e.g. while it is disconnected.
+func (g *Gateway) Clear(u *repository.Upstream) {
+	source := gatewaySource(u)
+	err := errors.Join(
+		g.registry.SetTools(source),
+		g.registry.SetPrompts(source),
+		g.registry.SetResources(source),
+	)
+	if err != nil {
+		g.logger.Error("failed to clear upstream entries", zap.String("upstream", u.Name), zap.Error(err))
+	}
+}
+
+func (g *Gateway) tool(u *repository.Upstream, tool mcp.Tool) server.ServerTool {
+	name := tool.Name
+	tool.Name = u.Prefix + name
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := u.CallTool(ctx, name, request.Params.Arguments, forwardProgress(ctx, request))
+		if err != nil {
+			return nil, v1.Errorf(v1.KindUpstream, "upstream %s failed: %w", u.Name, err)
+		}
+		return result, nil
+	}
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+// forwardProgress relays upstream progress to the downstream client under
+// the client's own token. It returns nil when the client sent no token.
+func forwardProgress(ctx context.Context, request mcp.CallToolRequest) repository.ProgressFunc {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil || request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	token := request.Params.Meta.ProgressToken
+	return func(progress mcp.ProgressNotificationParams) {
+		params := map[string]any{
+			"progress":      progress.Progress,
+			"progressToken": token,
+		}
+		if progress.Total > 0 {
+			params["total"] = progress.Total
+		}
+		if progress.Message != "" {
+			params["message"] = progress.Message
+		}
+		if srv.SendNotificationToClient(ctx, "notifications/progress", params) == nil {
+			metrics.ProgressSent(ctx)
+		}
+	}
+}
+
+func (g *Gateway) prompt(u *repository.Upstream, prompt mcp.Prompt) server.ServerPrompt {
+	name := prompt.Name
+	prompt.Name = u.Prefix + name
+	handler := func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		result, err := u.GetPrompt(ctx, name, request.Params.Arguments)
+		if err != nil {
+			return nil, v1.Errorf(v1.KindUpstream, "upstream %s failed: %w", u.Name, err)
+		}
+		return result, nil
+	}
+	return server.ServerPrompt{Prompt: prompt, Handler: handler}
+}
+
+func (g *Gateway) resource(u *repository.Upstream, resource mcp.Resource) server.ServerResource {
+	resource.Name = u.Prefix + resource.Name
+	handler := func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		result, err := u.ReadResource(ctx, request.Params.URI)
+		if err != nil {
+			return nil, v1.Errorf(v1.KindUpstream, "upstream %s failed: %w", u.Name, err)
+		}
+		return result.Contents, nil
+	}
+	return server.ServerResource{Resource: resource, Handler: handler}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"slices"
+	"testing"
+)
+
+func upstreamTool(name string) server.ServerTool {
+	return server.ServerTool{
+		Tool: mcp.NewTool(name, mcp.WithString("text")),
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(name + ": " + request.GetString("text", "")), nil
+		},
+	}
+}
+
+// newTestUpstream serves upstream over StreamableHTTP and connects to it.
+func newTestUpstream(t *testing.T, upstream *server.MCPServer) *repository.Upstream {
+	t.Helper()
+	ts := server.NewTestStreamableHTTPServer(upstream)
+	t.Cleanup(ts.Close)
+	conf := viper.New()
+	conf.Set("mcp.gateway.upstreams", []map[string]any{{"name": "up", "transport": "http", "url": ts.URL + "/mcp"}})
+	u := repository.NewUpstreams(conf, &log.Logger{Logger: zap.NewNop()})[0]
+	if err := u.Connect(context.Background(), mcp.Implementation{Name: "test", Version: "1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { u.Close() })
+	return u
+}
+
+func newTestGateway() (*servermcp.Server, *servermcp.Registry, *Gateway) {
+	srv := servermcp.NewServer(&log.Logger{Logger: zap.NewNop()}, servermcp.WithMCPSrv(server.NewMCPServer("test", "1.0.0",
+		server.WithToolCapabilities(true),
+		server.WithPromptCapabilities(true),
+		server.WithResourceCapabilities(false, true),
+	)))
+	registry := servermcp.NewRegistry(srv)
+	return srv, registry, NewGateway(NewHandler(&log.Logger{Logger: zap.NewNop()}), registry)
+}
+
+func TestGatewaySync(t *testing.T) {
+	// A page size of 1 makes Sync follow the cursors.
+	upstream := server.NewMCPServer("upstream", "1.0.0",
+		server.WithToolCapabilities(true),
+		server.WithPromptCapabilities(true),
+		server.WithResourceCapabilities(false, true),
+		server.WithPaginationLimit(1),
+	)
+	upstream.AddTools(upstreamTool("echo"), upstreamTool("shout"))
+	upstream.AddPrompt(mcp.NewPrompt("greet"), func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return mcp.NewGetPromptResult("greet", []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("hello "+request.Params.Arguments["name"])),
+		}), nil
+	})
+	upstream.AddResource(mcp.NewResource("docs://readme", "readme"), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: request.Params.URI, Text: "read me"}}, nil
+	})
+	u := newTestUpstream(t, upstream)
+	srv, registry, gateway := newTestGateway()
+	ctx := context.Background()
+
+	if err := gateway.Sync(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	tools, prompts, resources := registry.Owned("gateway:up")
+	slices.Sort(tools)
+	if !slices.Equal(tools, []string{"up_echo", "up_shout"}) || !slices.Equal(prompts, []string{"up_greet"}) || !slices.Equal(resources, []string{"docs://readme"}) {
+		t.Fatalf("synced tools %v, prompts %v and resources %v", tools, prompts, resources)
+	}
+
+	call := mcp.CallToolRequest{}
+	call.Params.Arguments = map[string]any{"text": "hi"}
+	result, err := srv.GetTool("up_echo").Handler(ctx, call)
+	if err != nil || result.Content[0].(mcp.TextContent).Text != "echo: hi" {
+		t.Fatalf("up_echo = %+v, %v, want the upstream echo tool", result, err)
+	}
+	if schema := srv.GetTool("up_echo").Tool.RawInputSchema; len(schema) == 0 {
+		t.Fatal("input schema not kept")
+	}
+
+	// Upstream changes replace the previous entries on the next sync.
+	upstream.DeleteTools("shout")
+	upstream.AddTool(mcp.NewTool("whisper"), upstreamTool("whisper").Handler)
+	if err := gateway.Sync(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if srv.GetTool("up_shout") != nil || srv.GetTool("up_whisper") == nil || srv.GetTool("up_echo") == nil {
+		t.Fatal("resync did not replace the upstream tools")
+	}
+
+	gateway.Clear(u)
+	if tools, prompts, resources := registry.Owned("gateway:up"); len(tools)+len(prompts)+len(resources) != 0 {
+		t.Fatalf("Clear left tools %v, prompts %v and resources %v", tools, prompts, resources)
+	}
+	if srv.GetTool("up_echo") != nil || srv.HasPrompt("up_greet") || srv.HasResource("docs://readme") {
+		t.Fatal("Clear left entries on the server")
+	}
+}
+
+func TestGatewaySyncClash(t *testing.T) {
+	upstream := server.NewMCPServer("upstream", "1.0.0", server.WithToolCapabilities(true), server.WithPromptCapabilities(true))
+	upstream.AddTools(upstreamTool("echo"))
+	upstream.AddPrompt(mcp.NewPrompt("greet"), func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return mcp.NewGetPromptResult("greet", nil), nil
+	})
+	u := newTestUpstream(t, upstream)
+	srv, registry, gateway := newTestGateway()
+	srv.AddTool(mcp.NewTool("up_echo"), upstreamTool("local").Handler)
+
+	// The clash is logged, not returned, and the other kinds still sync.
+	if err := gateway.Sync(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	tools, prompts, _ := registry.Owned("gateway:up")
+	if len(tools) != 0 || !slices.Equal(prompts, []string{"up_greet"}) {
+		t.Fatalf("synced tools %v and prompts %v, want only the prompt", tools, prompts)
+	}
+	result, err := srv.GetTool("up_echo").Handler(context.Background(), mcp.CallToolRequest{})
+	if err != nil || result.Content[0].(mcp.TextContent).Text != "local: " {
+		t.Fatalf("up_echo = %+v, %v, want the local tool", result, err)
+	}
+}
+
+func TestGatewayUpstreamDown(t *testing.T) {
+	upstream := server.NewMCPServer("upstream", "1.0.0", server.WithToolCapabilities(true))
+	upstream.AddTools(upstreamTool("echo"))
+	u := newTestUpstream(t, upstream)
+	srv, _, gateway := newTestGateway()
+	if err := gateway.Sync(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	handler := srv.GetTool("up_echo").Handler
+
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Sync(context.Background(), u); err == nil {
+		t.Fatal("Sync of a closed upstream succeeded")
+	}
+	if _, err := handler(context.Background(), mcp.CallToolRequest{}); v1.KindOf(err) != v1.KindUpstream {
+		t.Fatalf("call to a closed upstream: err = %v, want an upstream error", err)
+	}
+}
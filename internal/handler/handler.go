@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"go.uber.org/zap"
+	"net/http"
 )
 
 type Handler struct {
@@ -17,10 +21,39 @@ func NewHandler(
 		logger: logger,
 	}
 }
+
+// handleError answers with the status of the error kind of a service error,
+// and hides unexpected errors behind v1.ErrInternal.
+func (h *Handler) handleError(ctx *gin.Context, err error) {
+	switch v1.KindOf(err) {
+	case v1.KindValidation:
+		v1.HandleError(ctx, http.StatusBadRequest, err, nil)
+	case v1.KindNotFound:
+		v1.HandleError(ctx, http.StatusNotFound, err, nil)
+	default:
+		h.logger.WithContext(ctx).Error("request failed", zap.String("path", ctx.FullPath()), zap.Error(err))
+		v1.HandleError(ctx, http.StatusInternalServerError, v1.ErrInternal, nil)
+	}
+}
+
 func GetUserIdFromCtx(ctx *gin.Context) string {
 	v, exists := ctx.Get("claims")
 	if !exists {
 		return ""
 	}
-	return v.(*jwt.MyCustomClaims).UserId
+	claims, ok := v.(*jwt.MyCustomClaims)
+	if !ok {
+		return ""
+	}
+	return claims.UserId
+}
+
+// GetUserIdFromContext returns the UserId of the authenticated MCP caller,
+// or "" when the request did not pass through middleware.StrictAuth.
+func GetUserIdFromContext(ctx context.Context) string {
+	claims, ok := jwt.ClaimsFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return claims.UserId
 }
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(&log.Logger{Logger: zap.NewNop()})
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{name: "validation", err: v1.Errorf(v1.KindValidation, "uri is required"), status: http.StatusBadRequest, message: "uri is required"},
+		{name: "not found", err: v1.Errorf(v1.KindNotFound, "resource 3 not found"), status: http.StatusNotFound, message: "resource 3 not found"},
+		{name: "internal", err: errors.New("database is locked"), status: http.StatusInternalServerError, message: "internal error"},
+		{name: "upstream", err: v1.Errorf(v1.KindUpstream, "api down"), status: http.StatusInternalServerError, message: "internal error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			h.handleError(ctx, tt.err)
+
+			var resp v1.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.status || resp.Code != tt.status || resp.Message != tt.message {
+				t.Fatalf("got %d %+v, want %d %q", w.Code, resp, tt.status, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/openapi"
+	"github.com/mark3labs/mcp-go/server"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OpenAPISource is one entry of mcp.openapi: a spec whose operations are
+// served as tools.
+type OpenAPISource struct {
+	Name string `mapstructure:"name"`
+	Spec string `mapstructure:"spec"` // path to an OpenAPI 3 document, YAML or JSON
+	// BaseUrl defaults to the first server in the spec.
+	BaseUrl string `mapstructure:"base_url"`
+	// Headers are sent with every call, e.g. for auth. ${VAR} expands to
+	// the environment variable, so secrets can stay out of the config file.
+	Headers map[string]string `mapstructure:"headers"`
+	// Prefix is prepended to every tool name.
+	Prefix string `mapstructure:"prefix"`
+	// Operations limits the tools to these operationIds or tool names.
+	Operations       []string `mapstructure:"operations"`
+	Timeout          float64  `mapstructure:"timeout"` // seconds
+	MaxResponseBytes int64    `mapstructure:"max_response_bytes"`
+}
+
+// OpenAPI builds one tool per operation of the source's spec. The tools
+// call the API through the http_request service, like catalog http tools.
+func (c *Catalog) OpenAPI(source OpenAPISource) ([]server.ServerTool, error) {
+	doc, err := openapi.Load(source.Spec)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := OpenAPIDefinitions(doc, source)
+	if err != nil {
+		return nil, err
+	}
+	tools := make([]server.ServerTool, 0, len(defs))
+	for _, def := range defs {
+		tool, err := c.Tool(def)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// OpenAPIDefinitions turns every operation of doc into a catalog tool
+// definition with an http backend. Path, query and header parameters become
+// arguments of the same name and the request body becomes `body`.
+func OpenAPIDefinitions(doc *openapi.Document, source OpenAPISource) ([]*model.ToolDefinition, error) {
+	baseUrl := source.BaseUrl
+	if baseUrl == "" {
+		baseUrl = doc.BaseURL()
+	}
+	if u, err := url.Parse(baseUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("base url %q must be an absolute http(s) URL", baseUrl)
+	}
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+
+	endpoints, err := doc.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	var defs []*model.ToolDefinition
+	seen := map[string]string{}
+	for _, endpoint := range endpoints {
+		name := source.Prefix + toolName(endpoint)
+		if len(source.Operations) > 0 &&
+			!slices.Contains(source.Operations, endpoint.OperationID) &&
+			!slices.Contains(source.Operations, name) {
+			continue
+		}
+		operation := endpoint.Method + " " + endpoint.Path
+		if prev, ok := seen[name]; ok {
+			return nil, fmt.Errorf("%s: tool %q is already defined by %s", operation, name, prev)
+		}
+		seen[name] = operation
+		def, err := openAPIDefinition(name, baseUrl, endpoint, source)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+func openAPIDefinition(name, baseUrl string, endpoint openapi.Endpoint, source OpenAPISource) (*model.ToolDefinition, error) {
+	properties := map[string]any{}
+	var required []any
+	backend := &model.HttpBackend{
+		Method:           endpoint.Method,
+		Query:            map[string]string{},
+		Headers:          map[string]string{},
+		Timeout:          source.Timeout,
+		MaxResponseBytes: source.MaxResponseBytes,
+	}
+
+	path := endpoint.Path
+	for _, param := range endpoint.Parameters {
+		if param.In == "cookie" {
+			if param.Required {
+				return nil, fmt.Errorf("cookie parameter %q is not supported", param.Name)
+			}
+			continue
+		}
+		schema := maps.Clone(param.Schema)
+		if schema == nil {
+			schema = map[string]any{"type": "string"}
+		}
+		if _, ok := schema["description"]; !ok && param.Description != "" {
+			schema["description"] = param.Description
+		}
+		if param.In == "header" {
+			// Header parameters often carry credentials; keep them out of the logs.
+			schema["writeOnly"] = true
+		}
+		if _, ok := properties[param.Name]; ok {
+			return nil, fmt.Errorf("parameter %q is defined twice", param.Name)
+		}
+		properties[param.Name] = schema
+		if param.Required {
+			required = append(required, param.Name)
+		}
+
+		arg := "index . " + strconv.Quote(param.Name)
+		switch param.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", "{{ pathescape ("+arg+") }}")
+		case "query":
+			backend.Query[param.Name] = valueTemplate(arg, schema)
+		case "header":
+			backend.Headers[param.Name] = valueTemplate(arg, schema)
+		default:
+			return nil, fmt.Errorf("parameter %q: unknown location %q", param.Name, param.In)
+		}
+	}
+	backend.Url = baseUrl + path
+
+	if body := endpoint.Body; body != nil {
+		if _, ok := properties["body"]; ok {
+			return nil, errors.New(`a parameter named "body" clashes with the request body`)
+		}
+		arg := `index . "body"`
+		schema := maps.Clone(body.Schema)
+		if isJSON(body.ContentType) {
+			backend.Body = "{{ with " + arg + " }}{{ json . }}{{ end }}"
+		} else {
+			// Other media types are passed through as text.
+			schema = map[string]any{"type": "string"}
+			backend.Body = "{{ " + arg + " }}"
+		}
+		if schema == nil {
+			schema = map[string]any{}
+		}
+		if _, ok := schema["description"]; !ok && body.Description != "" {
+			schema["description"] = body.Description
+		}
+		properties["body"] = schema
+		if body.Required {
+			required = append(required, "body")
+		}
+		backend.Headers["Content-Type"] = "{{ if " + arg + " }}" + body.ContentType + "{{ end }}"
+	}
+
+	for key, value := range source.Headers {
+		backend.Headers[key] = literal(os.ExpandEnv(value))
+	}
+
+	inputSchema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		inputSchema["required"] = required
+	}
+	return &model.ToolDefinition{
+		Name:        name,
+		Description: describe(endpoint),
+		Annotations: annotations(endpoint),
+		InputSchema: inputSchema,
+		Http:        backend,
+	}, nil
+}
+
+var toolNameReplacer = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// toolName is the operationId or, without one, e.g. "get_users_id" for
+// GET /users/{id}, restricted to the characters MCP clients accept.
+func toolName(endpoint openapi.Endpoint) string {
+	name := endpoint.OperationID
+	if name == "" {
+		name = strings.ToLower(endpoint.Method) + endpoint.Path
+	}
+	name = strings.Trim(toolNameReplacer.ReplaceAllString(name, "_"), "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+func describe(endpoint openapi.Endpoint) string {
+	var parts []string
+	for _, part := range []string{endpoint.Summary, endpoint.Description} {
+		if part = strings.TrimSpace(part); part != "" && !slices.Contains(parts, part) {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return endpoint.Method + " " + endpoint.Path
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func annotations(endpoint openapi.Endpoint) *model.ToolAnnotations {
+	readOnly := endpoint.Method == http.MethodGet || endpoint.Method == http.MethodHead
+	destructive := endpoint.Method == http.MethodDelete
+	idempotent := readOnly || endpoint.Method == http.MethodPut || destructive
+	openWorld := true
+	return &model.ToolAnnotations{
+		Title:           endpoint.Summary,
+		ReadOnlyHint:    &readOnly,
+		DestructiveHint: &destructive,
+		IdempotentHint:  &idempotent,
+		OpenWorldHint:   &openWorld,
+	}
+}
+
+// valueTemplate renders a query or header value. Arrays are sent as a
+// comma-separated list and objects as JSON.
+func valueTemplate(arg string, schema map[string]any) string {
+	switch schema["type"] {
+	case "array":
+		return "{{ with " + arg + " }}{{ range $i, $v := . }}{{ if $i }},{{ end }}{{ $v }}{{ end }}{{ end }}"
+	case "object":
+		return "{{ with " + arg + " }}{{ json . }}{{ end }}"
+	default:
+		return "{{ " + arg + " }}"
+	}
+}
+
+// literal protects a fixed value from being read as a template.
+func literal(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return "{{ " + strconv.Quote(s) + " }}"
+}
+
+func isJSON(contentType string) bool {
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-nunu/nunu-layout-mcp/internal/service"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/egress"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/openapi"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const itemsSpec = `
+openapi: 3.0.3
+info: {title: Items, version: "1.0"}
+servers: [{url: "https://items.example.com/api/"}]
+paths:
+  /items/{id}:
+    get:
+      operationId: getItem
+      summary: Get an item
+      parameters:
+        - {name: id, in: path, schema: {type: string}}
+        - {name: tags, in: query, schema: {type: array, items: {type: string}}}
+        - {name: filter, in: query, schema: {type: object}}
+        - {name: X-Trace, in: header, description: trace id}
+        - {name: session, in: cookie}
+    delete:
+      summary: Delete an item
+      parameters:
+        - {name: id, in: path}
+  /items:
+    post:
+      operationId: createItem
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              properties: {name: {type: string}}
+              required: [name]
+    put:
+      operationId: importItems
+      requestBody:
+        content:
+          text/csv: {schema: {type: string}}
+`
+
+func TestOpenAPIDefinitions(t *testing.T) {
+	doc, err := openapi.Parse([]byte(itemsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := OpenAPIDefinitions(doc, OpenAPISource{Prefix: "items_"})
+	if err != nil {
+		t.Fatalf("OpenAPIDefinitions: %v", err)
+	}
+
+	byName := map[string]int{}
+	for i, def := range defs {
+		byName[def.Name] = i
+	}
+	wantNames := []string{"items_createItem", "items_importItems", "items_getItem", "items_delete_items_id"}
+	if len(defs) != len(wantNames) {
+		t.Fatalf("got %d tools, want %v", len(defs), wantNames)
+	}
+	for _, name := range wantNames {
+		if _, ok := byName[name]; !ok {
+			t.Fatalf("tool %q missing, got %v", name, byName)
+		}
+	}
+
+	get := defs[byName["items_getItem"]]
+	if get.Http.Url != `https://items.example.com/api/items/{{ pathescape (index . "id") }}` {
+		t.Errorf("getItem url = %q", get.Http.Url)
+	}
+	properties := get.InputSchema["properties"].(map[string]any)
+	if _, ok := properties["session"]; ok {
+		t.Error("optional cookie parameter became an argument")
+	}
+	if header := properties["X-Trace"].(map[string]any); header["writeOnly"] != true || header["description"] != "trace id" {
+		t.Errorf("X-Trace schema = %v", header)
+	}
+	if !reflect.DeepEqual(get.InputSchema["required"], []any{"id"}) {
+		t.Errorf("getItem required = %v", get.InputSchema["required"])
+	}
+	if a := get.Annotations; !*a.ReadOnlyHint || *a.DestructiveHint || a.Title != "Get an item" {
+		t.Errorf("getItem annotations = %+v", a)
+	}
+
+	del := defs[byName["items_delete_items_id"]]
+	if a := del.Annotations; *a.ReadOnlyHint || !*a.DestructiveHint || !*a.IdempotentHint {
+		t.Errorf("delete annotations = %+v", a)
+	}
+
+	create := defs[byName["items_createItem"]]
+	body := create.InputSchema["properties"].(map[string]any)["body"].(map[string]any)
+	if body["type"] != "object" || !reflect.DeepEqual(create.InputSchema["required"], []any{"body"}) {
+		t.Errorf("createItem schema = %v", create.InputSchema)
+	}
+	if create.Description != "POST /items" {
+		t.Errorf("createItem description = %q", create.Description)
+	}
+
+	put := defs[byName["items_importItems"]]
+	if body := put.InputSchema["properties"].(map[string]any)["body"]; !reflect.DeepEqual(body, map[string]any{"type": "string"}) {
+		t.Errorf("importItems body = %v, want a string", body)
+	}
+}
+
+func TestOpenAPIDefinitionsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		source  OpenAPISource
+		wantErr string
+	}{
+		{
+			name:    "no base url",
+			spec:    `{openapi: 3.0.0, paths: {}}`,
+			wantErr: "must be an absolute http(s) URL",
+		},
+		{
+			name:    "relative base url",
+			spec:    `{openapi: 3.0.0, servers: [{url: /api}], paths: {}}`,
+			wantErr: "must be an absolute http(s) URL",
+		},
+		{
+			name:    "required cookie",
+			spec:    `{openapi: 3.0.0, paths: {/a: {get: {parameters: [{name: s, in: cookie, required: true}]}}}}`,
+			source:  OpenAPISource{BaseUrl: "http://api"},
+			wantErr: `GET /a: cookie parameter "s" is not supported`,
+		},
+		{
+			name:    "same name in query and header",
+			spec:    `{openapi: 3.0.0, paths: {/a: {get: {parameters: [{name: x, in: query}, {name: x, in: header}]}}}}`,
+			source:  OpenAPISource{BaseUrl: "http://api"},
+			wantErr: `parameter "x" is defined twice`,
+		},
+		{
+			name:    "body parameter",
+			spec:    `{openapi: 3.0.0, paths: {/a: {post: {parameters: [{name: body, in: query}], requestBody: {content: {application/json: {}}}}}}}`,
+			source:  OpenAPISource{BaseUrl: "http://api"},
+			wantErr: `"body" clashes with the request body`,
+		},
+		{
+			name:    "duplicate tool name",
+			spec:    `{openapi: 3.0.0, paths: {/a: {get: {operationId: op}}, /b: {get: {operationId: op}}}}`,
+			source:  OpenAPISource{BaseUrl: "http://api"},
+			wantErr: `GET /b: tool "op" is already defined by GET /a`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := openapi.Parse([]byte(tt.spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = OpenAPIDefinitions(doc, tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("OpenAPIDefinitions error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenAPIDefinitionsOperations(t *testing.T) {
+	doc, err := openapi.Parse([]byte(itemsSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := OpenAPIDefinitions(doc, OpenAPISource{
+		Prefix:     "items_",
+		Operations: []string{"getItem", "items_delete_items_id"},
+	})
+	if err != nil {
+		t.Fatalf("OpenAPIDefinitions: %v", err)
+	}
+	var names []string
+	for _, def := range defs {
+		names = append(names, def.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"items_getItem", "items_delete_items_id"}) {
+		t.Fatalf("tools = %v", names)
+	}
+}
+
+// upstreamRequest is what the test API saw of a call.
+type upstreamRequest struct {
+	Method string
+	Path   string
+	Query  map[string][]string
+	Header http.Header
+	Body   string
+}
+
+func TestOpenAPITools(t *testing.T) {
+	var got upstreamRequest
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = upstreamRequest{
+			Method: r.Method,
+			Path:   r.URL.EscapedPath(),
+			Query:  r.URL.Query(),
+			Header: r.Header,
+			Body:   string(body),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	spec := filepath.Join(t.TempDir(), "items.yaml")
+	if err := os.WriteFile(spec, []byte(itemsSpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ITEMS_TOKEN", "s3cret")
+
+	conf := viper.New()
+	conf.Set("egress.allow_private", true)
+	logger := &log.Logger{Logger: zap.NewNop()}
+	c := &Catalog{exampleSvc: service.NewExampleService(service.NewService(nil, logger, nil), nil, egress.NewPolicy(conf))}
+	list, err := c.OpenAPI(OpenAPISource{
+		Name:    "items",
+		Spec:    spec,
+		BaseUrl: upstream.URL + "/api",
+		Headers: map[string]string{
+			"Authorization": "Bearer ${ITEMS_TOKEN}",
+			"X-Literal":     "{{ not a template }}",
+		},
+	})
+	if err != nil {
+		t.Fatalf("OpenAPI: %v", err)
+	}
+	tools := map[string]server.ServerTool{}
+	for _, tool := range list {
+		tools[tool.Tool.Name] = tool
+	}
+
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		request.Params.Arguments = args
+		result, err := tools[name].Handler(context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		tool   string
+		args   map[string]any
+		method string
+		path   string
+		query  map[string][]string
+		header map[string]string
+		body   string
+	}{
+		{
+			name:   "path, query and header parameters",
+			tool:   "getItem",
+			args:   map[string]any{"id": "a/b c", "tags": []any{"x", "y"}, "filter": map[string]any{"n": 1}, "X-Trace": "t-1"},
+			method: http.MethodGet,
+			path:   "/api/items/a%2Fb%20c",
+			query:  map[string][]string{"tags": {"x,y"}, "filter": {`{"n":1}`}},
+			header: map[string]string{"X-Trace": "t-1"},
+		},
+		{
+			name:   "absent optional parameters",
+			tool:   "getItem",
+			args:   map[string]any{"id": "1"},
+			method: http.MethodGet,
+			path:   "/api/items/1",
+			query:  map[string][]string{},
+		},
+		{
+			name:   "json body",
+			tool:   "createItem",
+			args:   map[string]any{"body": map[string]any{"name": "pen"}},
+			method: http.MethodPost,
+			path:   "/api/items",
+			query:  map[string][]string{},
+			header: map[string]string{"Content-Type": "application/json"},
+			body:   `{"name":"pen"}`,
+		},
+		{
+			name:   "text body",
+			tool:   "importItems",
+			args:   map[string]any{"body": "a,b\n1,2"},
+			method: http.MethodPut,
+			path:   "/api/items",
+			query:  map[string][]string{},
+			header: map[string]string{"Content-Type": "text/csv"},
+			body:   "a,b\n1,2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = upstreamRequest{}
+			result := call(tt.tool, tt.args)
+			if result.IsError {
+				t.Fatalf("result is an error: %+v", result.Content)
+			}
+			if got.Method != tt.method || got.Path != tt.path || got.Body != tt.body {
+				t.Fatalf("upstream got %s %s %q, want %s %s %q", got.Method, got.Path, got.Body, tt.method, tt.path, tt.body)
+			}
+			if !reflect.DeepEqual(got.Query, tt.query) {
+				t.Fatalf("query = %v, want %v", got.Query, tt.query)
+			}
+			for key, value := range tt.header {
+				if got.Header.Get(key) != value {
+					t.Fatalf("header %s = %q, want %q", key, got.Header.Get(key), value)
+				}
+			}
+			if got.Header.Get("Authorization") != "Bearer s3cret" {
+				t.Fatalf("Authorization = %q, want the expanded source header", got.Header.Get("Authorization"))
+			}
+			if got.Header.Get("X-Literal") != "{{ not a template }}" {
+				t.Fatalf("X-Literal = %q, want the literal value", got.Header.Get("X-Literal"))
+			}
+			var status struct{ Status int }
+			if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &status); err != nil || status.Status != http.StatusOK {
+				t.Fatalf("status = %v, %v", status, err)
+			}
+		})
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{}
+	if _, err := tools["createItem"].Handler(context.Background(), request); err == nil {
+		t.Fatal("createItem without body succeeded, want a validation error")
+	}
+}
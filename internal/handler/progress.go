@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/metrics"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"sync"
+	"time"
+)
+
+const defaultProgressInterval = 100 * time.Millisecond
+
+// ProgressReporter sends notifications/progress for a single tool call.
+// It is a no-op when the client did not send a progress token.
+type ProgressReporter struct {
+	srv      *server.MCPServer
+	token    mcp.ProgressToken
+	total    float64
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+type ProgressOption func(*ProgressReporter)
+
+// WithProgressInterval sets the minimum time between two notifications.
+// Intermediate updates inside the interval are dropped; the final one never is.
+func WithProgressInterval(interval time.Duration) ProgressOption {
+	return func(p *ProgressReporter) {
+		p.interval = interval
+	}
+}
+
+// NewProgressReporter creates a reporter for request. total <= 0 means the
+// total is unknown and no percentage is reported.
+func NewProgressReporter(ctx context.Context, request mcp.CallToolRequest, total float64, opts ...ProgressOption) *ProgressReporter {
+	p := &ProgressReporter{
+		srv:      server.ServerFromContext(ctx),
+		total:    total,
+		interval: defaultProgressInterval,
+	}
+	if request.Params.Meta != nil {
+		p.token = request.Params.Meta.ProgressToken
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Report sends the current progress. An empty message is replaced with the
+// percentage done. It returns ctx.Err() once the call has been cancelled so
+// loops can stop on the first report after cancellation.
+func (p *ProgressReporter) Report(ctx context.Context, progress float64, message string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.token == nil || p.srv == nil {
+		return nil
+	}
+
+	final := p.total > 0 && progress >= p.total
+	p.mu.Lock()
+	now := time.Now()
+	if !final && now.Sub(p.last) < p.interval {
+		p.mu.Unlock()
+		return nil
+	}
+	p.last = now
+	p.mu.Unlock()
+
+	params := map[string]any{
+		"progress":      progress,
+		"progressToken": p.token,
+	}
+	if p.total > 0 {
+		params["total"] = p.total
+		if message == "" {
+			message = fmt.Sprintf("Server progress %d%%", int(progress*100/p.total))
+		}
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	if err := p.srv.SendNotificationToClient(ctx, "notifications/progress", params); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	metrics.ProgressSent(ctx)
+	return nil
+}
+
+// Reportf is Report with a formatted message.
+func (p *ProgressReporter) Reportf(ctx context.Context, progress float64, format string, args ...any) error {
+	return p.Report(ctx, progress, fmt.Sprintf(format, args...))
+}
+
+// Sleep waits for d, returning early with ctx.Err() when the call is cancelled.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
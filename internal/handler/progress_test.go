@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type progressUpdate struct {
+	Progress float64
+	Total    float64
+	Message  string
+}
+
+// callWithProgress calls a tool running report on a test server and
+// returns the progress notifications the client received.
+func callWithProgress(t *testing.T, token mcp.ProgressToken, report func(ctx context.Context, request mcp.CallToolRequest) error) []progressUpdate {
+	t.Helper()
+	srv := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(false))
+	srv.AddTool(mcp.NewTool("work"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := report(ctx, request); err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText("done"), nil
+	})
+
+	// The in-process transport drops server notifications, so go through SSE.
+	ts := server.NewTestServer(srv)
+	defer ts.Close()
+	c, err := client.NewSSEMCPClient(ts.URL + "/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var (
+		mu      sync.Mutex
+		updates []progressUpdate
+	)
+	c.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method != "notifications/progress" {
+			return
+		}
+		fields := notification.Params.AdditionalFields
+		update := progressUpdate{}
+		update.Progress, _ = fields["progress"].(float64)
+		update.Total, _ = fields["total"].(float64)
+		update.Message, _ = fields["message"].(string)
+		mu.Lock()
+		updates = append(updates, update)
+		mu.Unlock()
+	})
+
+	ctx := context.Background()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{Name: "test", Version: "1.0.0"}
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		t.Fatal(err)
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "work"
+	if token != nil {
+		request.Params.Meta = &mcp.Meta{ProgressToken: token}
+	}
+	if _, err := c.CallTool(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return updates
+}
+
+func TestProgressReporter(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  mcp.ProgressToken
+		total  float64
+		opts   []ProgressOption
+		report func(ctx context.Context, p *ProgressReporter) error
+		want   []progressUpdate
+	}{
+		{
+			name:  "no progress token",
+			total: 2,
+			report: func(ctx context.Context, p *ProgressReporter) error {
+				return p.Report(ctx, 1, "")
+			},
+		},
+		{
+			name:  "percentage message",
+			token: "t",
+			total: 4,
+			report: func(ctx context.Context, p *ProgressReporter) error {
+				return p.Report(ctx, 1, "")
+			},
+			want: []progressUpdate{{Progress: 1, Total: 4, Message: "Server progress 25%"}},
+		},
+		{
+			name:  "formatted message",
+			token: "t",
+			total: 4,
+			report: func(ctx context.Context, p *ProgressReporter) error {
+				return p.Reportf(ctx, 2, "step %d", 2)
+			},
+			want: []progressUpdate{{Progress: 2, Total: 4, Message: "step 2"}},
+		},
+		{
+			name:  "unknown total",
+			token: 7,
+			report: func(ctx context.Context, p *ProgressReporter) error {
+				return p.Report(ctx, 3, "")
+			},
+			want: []progressUpdate{{Progress: 3}},
+		},
+		{
+			name:  "throttled, keeping the final update",
+			token: "t",
+			total: 5,
+			opts:  []ProgressOption{WithProgressInterval(time.Hour)},
+			report: func(ctx context.Context, p *ProgressReporter) error {
+				for i := 1; i <= 5; i++ {
+					if err := p.Report(ctx, float64(i), ""); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			want: []progressUpdate{
+				{Progress: 1, Total: 5, Message: "Server progress 20%"},
+				{Progress: 5, Total: 5, Message: "Server progress 100%"},
+			},
+		},
+		{
+			name:  "no throttling",
+			token: "t",
+			total: 3,
+			opts:  []ProgressOption{WithProgressInterval(0)},
+			report: func(ctx context.Context, p *ProgressReporter) error {
+				for i := 1; i <= 3; i++ {
+					if err := p.Report(ctx, float64(i), "working"); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			want: []progressUpdate{
+				{Progress: 1, Total: 3, Message: "working"},
+				{Progress: 2, Total: 3, Message: "working"},
+				{Progress: 3, Total: 3, Message: "working"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := callWithProgress(t, tt.token, func(ctx context.Context, request mcp.CallToolRequest) error {
+				return tt.report(ctx, NewProgressReporter(ctx, request, tt.total, tt.opts...))
+			})
+			// Notifications arrive on the SSE stream, after the response.
+			deadline := time.Now().Add(time.Second)
+			for len(got) < len(tt.want) && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("progress = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressReporterCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := NewProgressReporter(ctx, mcp.CallToolRequest{}, 1)
+	if err := p.Report(ctx, 1, ""); err != context.Canceled {
+		t.Fatalf("Report = %v, want context.Canceled", err)
+	}
+	if err := Sleep(ctx, time.Hour); err != context.Canceled {
+		t.Fatalf("Sleep = %v, want context.Canceled", err)
+	}
+}
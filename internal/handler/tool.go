@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/invopop/jsonschema"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const ctxToolRequestKey = "toolRequest"
+
+// ToolHandlerFunc handles a tool call with typed arguments. Returning an
+// *mcp.CallToolResult as Resp passes it through unchanged; any other
+// response is sent as structuredContent with its JSON as the text fallback.
+type ToolHandlerFunc[Req, Resp any] func(ctx context.Context, req *Req) (*Resp, error)
+
+// NewTool creates a tool whose input schema is generated from Req. Property
+// names come from the `json` tags and everything else from `jsonschema`
+// tags, e.g. `jsonschema:"description=HTTP method,required,enum=GET,enum=POST"`.
+// Only fields tagged `required` are required.
+func NewTool[Req any](name string, opts ...mcp.ToolOption) mcp.Tool {
+	tool := mcp.NewTool(name, opts...)
+	tool.InputSchema = mcp.ToolInputSchema{}
+	tool.RawInputSchema = schemaFor[Req]()
+	return tool
+}
+
+// WithOutputSchema declares the structuredContent returned by the tool,
+// generated from Resp the same way NewTool generates the input schema.
+func WithOutputSchema[Resp any]() mcp.ToolOption {
+	return func(tool *mcp.Tool) {
+		tool.OutputSchema = mcp.ToolOutputSchema{}
+		tool.RawOutputSchema = schemaFor[Resp]()
+	}
+}
+
+// NewToolHandler adapts a typed handler to server.ToolHandlerFunc. The
+// arguments are bound to Req and checked against its `validate` tags; any
+// failure is reported as a tool error listing the invalid fields.
+func NewToolHandler[Req, Resp any](handler ToolHandlerFunc[Req, Resp]) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var req Req
+		if err := request.BindArguments(&req); err != nil {
+			return nil, &v1.Error{Kind: v1.KindValidation, Message: validationMessage(err), Err: err}
+		}
+		if err := validate.StructCtx(ctx, &req); err != nil {
+			return nil, &v1.Error{Kind: v1.KindValidation, Message: validationMessage(err), Err: err}
+		}
+		resp, err := handler(context.WithValue(ctx, ctxToolRequestKey, request), &req)
+		if err != nil {
+			return nil, err
+		}
+		if result, ok := any(resp).(*mcp.CallToolResult); ok {
+			return result, nil
+		}
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return nil, fmt.Errorf("marshal tool response: %w", err)
+		}
+		return mcp.NewToolResultStructured(resp, string(data)), nil
+	}
+}
+
+// ToolRequestFromContext returns the raw request inside a typed handler, e.g.
+// to read the progress token.
+func ToolRequestFromContext(ctx context.Context) (mcp.CallToolRequest, bool) {
+	request, ok := ctx.Value(ctxToolRequestKey).(mcp.CallToolRequest)
+	return request, ok
+}
+
+func schemaFor[T any]() json.RawMessage {
+	reflector := jsonschema.Reflector{
+		DoNotReference:             true,
+		Anonymous:                  true,
+		AllowAdditionalProperties:  true,
+		RequiredFromJSONSchemaTags: true,
+	}
+	var zero T
+	schema := reflector.Reflect(zero)
+	schema.Version = ""
+	markWriteOnly(reflect.TypeOf(zero), schema)
+	data, err := json.Marshal(schema)
+	if err != nil {
+		panic(fmt.Sprintf("tool schema for %T: %s", zero, err.Error()))
+	}
+	return data
+}
+
+// markWriteOnly applies `jsonschema:"writeOnly=true"` to fields of any type;
+// the reflector only honours it on strings. The logging hooks redact
+// writeOnly arguments.
+func markWriteOnly(t reflect.Type, schema *jsonschema.Schema) {
+	if t.Kind() != reflect.Struct || schema.Properties == nil {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !slices.Contains(strings.Split(field.Tag.Get("jsonschema"), ","), "writeOnly=true") {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if property, ok := schema.Properties.Get(name); ok {
+			property.WriteOnly = true
+		}
+	}
+}
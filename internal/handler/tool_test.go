@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		schema     json.RawMessage
+		properties map[string]map[string]any
+		required   []any
+	}{
+		{
+			name:   "defaults and bounds",
+			schema: schemaFor[v1.LongRunningOperationRequest](),
+			properties: map[string]map[string]any{
+				"duration": {"type": "number", "default": 10.0, "minimum": 0.0, "description": "Duration of the operation in seconds"},
+				"steps":    {"type": "integer", "default": 5.0, "minimum": 1.0, "description": "Number of steps in the operation"},
+			},
+		},
+		{
+			name:   "required pointers",
+			schema: schemaFor[v1.AddToolRequest](),
+			properties: map[string]map[string]any{
+				"a": {"type": "number", "description": "First number"},
+				"b": {"type": "number", "description": "Second number"},
+			},
+			required: []any{"a", "b"},
+		},
+		{
+			name:   "enum and writeOnly",
+			schema: schemaFor[v1.HttpToolRequest](),
+			properties: map[string]map[string]any{
+				"method":  {"enum": []any{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}},
+				"headers": {"type": "object", "writeOnly": true},
+			},
+			required: []any{"method", "url"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema map[string]any
+			if err := json.Unmarshal(tt.schema, &schema); err != nil {
+				t.Fatal(err)
+			}
+			if schema["type"] != "object" {
+				t.Fatalf("type = %v, want object", schema["type"])
+			}
+			properties, _ := schema["properties"].(map[string]any)
+			for name, want := range tt.properties {
+				got, _ := properties[name].(map[string]any)
+				for key, value := range want {
+					if !reflect.DeepEqual(got[key], value) {
+						t.Errorf("%s.%s = %v, want %v", name, key, got[key], value)
+					}
+				}
+			}
+			if required, _ := schema["required"].([]any); !reflect.DeepEqual(required, tt.required) {
+				t.Errorf("required = %v, want %v", required, tt.required)
+			}
+		})
+	}
+}
+
+func TestLongRunningOperationTool(t *testing.T) {
+	h := NewExampleHandler(NewHandler(&log.Logger{Logger: zap.NewNop()}), nil, nil)
+	tests := []struct {
+		name      string
+		args      map[string]any
+		cancelled bool
+		wantErr   string
+		want      string
+	}{
+		{name: "default steps", args: map[string]any{"duration": 0}, want: "Steps: 5."},
+		{name: "cancelled", args: map[string]any{"duration": 60}, cancelled: true, wantErr: "operation cancelled at step 1 of 5: context canceled"},
+		{name: "explicit steps", args: map[string]any{"duration": 0.01, "steps": 2}, want: "Steps: 2."},
+		{name: "no steps", args: map[string]any{"steps": 0}, wantErr: "invalid arguments:\n- steps: must be >= 1"},
+		{name: "negative duration", args: map[string]any{"duration": -1}, wantErr: "invalid arguments:\n- duration: must be >= 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = tt.args
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			defer cancel()
+			result, err := NewToolHandler(h.LongRunningOperationTool)(ctx, request)
+			if tt.cancelled {
+				if !errors.Is(err, context.Canceled) || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				if v1.KindOf(err) != v1.KindValidation || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want validation error %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text := result.Content[0].(mcp.TextContent).Text; !strings.HasSuffix(text, tt.want) {
+				t.Fatalf("result = %q, want it to end with %q", text, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructuredContent(t *testing.T) {
+	h := NewExampleHandler(NewHandler(&log.Logger{Logger: zap.NewNop()}), nil, nil)
+	srv := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(false))
+	srv.AddTool(NewTool[v1.AddToolRequest]("add", WithOutputSchema[v1.AddToolResponse]()), NewToolHandler(h.AddTool))
+	srv.AddTool(NewTool[v1.EchoToolRequest]("echo", WithOutputSchema[v1.EchoToolResponse]()), NewToolHandler(h.EchoTool))
+	srv.AddTool(NewTool[v1.LongRunningOperationRequest]("longRunningOperation"), NewToolHandler(h.LongRunningOperationTool))
+
+	var list struct {
+		Tools []struct {
+			Name         string         `json:"name"`
+			OutputSchema map[string]any `json:"outputSchema"`
+		} `json:"tools"`
+	}
+	rpc(t, srv, "tools/list", nil, &list)
+	outputSchemas := map[string]map[string]any{}
+	for _, tool := range list.Tools {
+		outputSchemas[tool.Name] = tool.OutputSchema
+	}
+	if schema := outputSchemas["add"]; schema["type"] != "object" || !reflect.DeepEqual(schema["required"], []any{"sum"}) {
+		t.Errorf("add outputSchema = %v, want an object requiring sum", schema)
+	}
+	if schema := outputSchemas["longRunningOperation"]; schema != nil {
+		t.Errorf("longRunningOperation outputSchema = %v, want none", schema)
+	}
+
+	tests := []struct {
+		name       string
+		tool       string
+		args       map[string]any
+		structured any
+		text       string
+	}{
+		{name: "number", tool: "add", args: map[string]any{"a": 1, "b": 2.5}, structured: map[string]any{"sum": 3.5}, text: `{"sum":3.5}`},
+		{name: "string", tool: "echo", args: map[string]any{"message": "hi"}, structured: map[string]any{"message": "hi"}, text: `{"message":"hi"}`},
+		{name: "result passed through", tool: "longRunningOperation", args: map[string]any{"duration": 0, "steps": 1}, text: "Long running operation completed. Duration: 0.000000 seconds, Steps: 1."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result struct {
+				Content []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"content"`
+				StructuredContent any  `json:"structuredContent"`
+				IsError           bool `json:"isError"`
+			}
+			rpc(t, srv, "tools/call", map[string]any{"name": tt.tool, "arguments": tt.args}, &result)
+			if result.IsError || len(result.Content) != 1 || result.Content[0].Type != "text" || result.Content[0].Text != tt.text {
+				t.Errorf("content = %+v, want the text %q", result.Content, tt.text)
+			}
+			if !reflect.DeepEqual(result.StructuredContent, tt.structured) {
+				t.Errorf("structuredContent = %v, want %v", result.StructuredContent, tt.structured)
+			}
+		})
+	}
+}
+
+// rpc sends a JSON-RPC request to srv and decodes the result into v.
+func rpc(t *testing.T, srv *server.MCPServer, method string, params any, v any) {
+	t.Helper()
+	message, err := json.Marshal(map[string]any{"jsonrpc": mcp.JSONRPC_VERSION, "id": 1, "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, ok := srv.HandleMessage(context.Background(), message).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("%s failed", method)
+	}
+	data, err := json.Marshal(response.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Report fields by the names the client sent, not the Go field names.
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// validationMessage turns a binding or validation error into a message that
+// lists every invalid field, so the model can fix its arguments and retry.
+func validationMessage(err error) string {
+	var lines []string
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fe := range validationErrs {
+			lines = append(lines, fmt.Sprintf("- %s: %s", fieldPath(fe), ruleMessage(fe)))
+		}
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "arguments"
+		}
+		lines = append(lines, fmt.Sprintf("- %s: must be of type %s, got %s", field, jsonType(typeErr.Type), typeErr.Value))
+	case errors.As(err, &syntaxErr):
+		lines = append(lines, fmt.Sprintf("- arguments are not valid JSON: %s", syntaxErr.Error()))
+	default:
+		lines = append(lines, "- "+err.Error())
+	}
+	return "invalid arguments:\n" + strings.Join(lines, "\n")
+}
+
+// fieldPath drops the struct name from the namespace, e.g.
+// "HttpToolRequest.headers[Accept]" becomes "headers[Accept]".
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return fmt.Sprintf("must be one of [%s]", strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "url", "http_url":
+		return "must be a valid http(s) URL"
+	case "email":
+		return "must be a valid email address"
+	case "min", "gte":
+		if unit := lengthUnit(fe.Kind()); unit != "" {
+			return fmt.Sprintf("must have at least %s %s", fe.Param(), unit)
+		}
+		return fmt.Sprintf("must be >= %s", fe.Param())
+	case "max", "lte":
+		if unit := lengthUnit(fe.Kind()); unit != "" {
+			return fmt.Sprintf("must have at most %s %s", fe.Param(), unit)
+		}
+		return fmt.Sprintf("must be <= %s", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be > %s", fe.Param())
+	case "lt":
+		return fmt.Sprintf("must be < %s", fe.Param())
+	default:
+		if fe.Param() != "" {
+			return fmt.Sprintf("failed the %q rule (%s)", fe.Tag(), fe.Param())
+		}
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
+
+// lengthUnit names what min/max count for kinds validated by length.
+func lengthUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "items"
+	default:
+		return ""
+	}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return t.String()
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/mark3labs/mcp-go/mcp"
+	"testing"
+)
+
+func callTyped[Req, Resp any](t *testing.T, handler ToolHandlerFunc[Req, Resp], args any) (*mcp.CallToolResult, error) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "test"
+	request.Params.Arguments = args
+	return NewToolHandler(handler)(context.Background(), request)
+}
+
+func TestAddToolValidation(t *testing.T) {
+	add := func(ctx context.Context, req *v1.AddToolRequest) (*v1.AddToolResponse, error) {
+		return &v1.AddToolResponse{Sum: *req.A + *req.B}, nil
+	}
+	tests := []struct {
+		name    string
+		args    any
+		wantErr string
+		sum     float64
+	}{
+		{name: "both numbers", args: map[string]any{"a": 1.5, "b": 2}, sum: 3.5},
+		{name: "zero is a number", args: map[string]any{"a": 0, "b": 0}, sum: 0},
+		{name: "empty", args: map[string]any{}, wantErr: "invalid arguments:\n- a: is required\n- b: is required"},
+		{name: "one missing", args: map[string]any{"a": 1}, wantErr: "invalid arguments:\n- b: is required"},
+		{name: "wrong type", args: map[string]any{"a": "one", "b": 2}, wantErr: "invalid arguments:\n- a: must be of type number, got string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := callTyped(t, add, tt.args)
+			if tt.wantErr != "" {
+				if v1.KindOf(err) != v1.KindValidation || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want validation error %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := result.StructuredContent.(*v1.AddToolResponse).Sum; got != tt.sum {
+				t.Fatalf("sum = %v, want %v", got, tt.sum)
+			}
+		})
+	}
+}
+
+func TestValidationMessages(t *testing.T) {
+	echo := func(ctx context.Context, req *v1.HttpToolRequest) (*v1.EchoToolResponse, error) {
+		return &v1.EchoToolResponse{}, nil
+	}
+	tests := []struct {
+		name    string
+		args    any
+		wantErr string
+	}{
+		{
+			name:    "every invalid field",
+			args:    map[string]any{"method": "FETCH", "url": "ftp://example.com", "timeout": 500},
+			wantErr: "invalid arguments:\n- method: must be one of [GET, POST, PUT, PATCH, DELETE, HEAD]\n- url: must be a valid http(s) URL\n- timeout: must be <= 120",
+		},
+		{
+			name:    "empty header name",
+			args:    map[string]any{"method": "GET", "url": "https://example.com", "headers": map[string]any{"": "x"}},
+			wantErr: "invalid arguments:\n- headers[]: is required",
+		},
+		{
+			name:    "negative size",
+			args:    map[string]any{"method": "GET", "url": "https://example.com", "maxResponseBytes": -1},
+			wantErr: "invalid arguments:\n- maxResponseBytes: must be >= 0",
+		},
+		{
+			name:    "arguments are not an object",
+			args:    "GET https://example.com",
+			wantErr: "invalid arguments:\n- arguments: must be of type object, got string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := callTyped(t, echo, tt.args)
+			if v1.KindOf(err) != v1.KindValidation || err.Error() != tt.wantErr {
+				t.Fatalf("err = %v, want validation error %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+)
+
+// AdminAuth guards the admin API. It requires a valid bearer token and, when
+// roles is not empty, at least one of roles in the token.
+func AdminAuth(j *jwt.JWT, logger *log.Logger, roles []string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, err := j.ParseToken(ctx.Request.Header.Get("Authorization"))
+		if err != nil {
+			logger.WithContext(ctx).Warn("admin token error", zap.String("url", ctx.Request.URL.String()), zap.Error(err))
+			ctx.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			v1.HandleError(ctx, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)), nil)
+			return
+		}
+		if len(roles) > 0 && !slices.ContainsFunc(claims.Roles, func(role string) bool {
+			return slices.Contains(roles, role)
+		}) {
+			logger.WithContext(ctx).Warn("admin role required", zap.String("UserId", claims.UserId), zap.Strings("roles", claims.Roles))
+			v1.HandleError(ctx, http.StatusForbidden, errors.New(http.StatusText(http.StatusForbidden)), nil)
+			return
+		}
+		ctx.Set("claims", claims)
+		ctx.Request = ctx.Request.WithContext(jwt.WithClaims(ctx.Request.Context(), claims))
+		logger.WithValue(ctx, zap.String("UserId", claims.UserId))
+		ctx.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// StrictAuth rejects requests without a valid bearer token and stores the
+// parsed claims in the request context for the MCP handlers.
+func StrictAuth(j *jwt.JWT, logger *log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := r.Header.Get("Authorization")
+			if tokenString == "" {
+				logger.WithContext(r.Context()).Warn("No token", zap.String("method", r.Method), zap.String("url", r.URL.String()))
+				unauthorized(w)
+				return
+			}
+
+			claims, err := j.ParseToken(tokenString)
+			if err != nil {
+				logger.WithContext(r.Context()).Warn("token error", zap.String("method", r.Method), zap.String("url", r.URL.String()), zap.Error(err))
+				unauthorized(w)
+				return
+			}
+
+			ctx := jwt.WithClaims(r.Context(), claims)
+			ctx = logger.WithValue(ctx, zap.String("UserId", claims.UserId))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
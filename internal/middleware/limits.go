@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LimitRule sets the limits of the matching tools. Tools are path.Match
+// patterns; zero fields fall back to the mcp.limits defaults.
+type LimitRule struct {
+	Tools                    []string      `mapstructure:"tools"`
+	Timeout                  time.Duration `mapstructure:"timeout"`
+	MaxConcurrency           int           `mapstructure:"max_concurrency"`
+	MaxConcurrencyPerSession int           `mapstructure:"max_concurrency_per_session"`
+	QueueTimeout             time.Duration `mapstructure:"queue_timeout"`
+}
+
+// Limits bounds how long a tool call may run and how many calls of a tool
+// run at once, across the server and per session. A call over a concurrency
+// limit waits up to QueueTimeout for a free slot and then fails.
+type Limits struct {
+	defaults LimitRule
+	rules    []LimitRule
+
+	mu         sync.Mutex
+	semaphores map[string]*semaphore
+}
+
+// semaphore is dropped once nobody holds or waits for a slot, so sessions
+// that are gone leave nothing behind.
+type semaphore struct {
+	slots chan struct{}
+	users int
+}
+
+func NewLimits(conf *viper.Viper) *Limits {
+	l := &Limits{
+		defaults: LimitRule{
+			Timeout:                  conf.GetDuration("mcp.limits.timeout"),
+			MaxConcurrency:           conf.GetInt("mcp.limits.max_concurrency"),
+			MaxConcurrencyPerSession: conf.GetInt("mcp.limits.max_concurrency_per_session"),
+			QueueTimeout:             conf.GetDuration("mcp.limits.queue_timeout"),
+		},
+		semaphores: map[string]*semaphore{},
+	}
+	if err := conf.UnmarshalKey("mcp.limits.rules", &l.rules); err != nil {
+		panic(fmt.Sprintf("mcp.limits.rules error: %s", err.Error()))
+	}
+	return l
+}
+
+// Limit returns the limits of tool: the first rule that matches it, with
+// unset fields taken from the defaults.
+func (l *Limits) Limit(tool string) LimitRule {
+	limit := l.defaults
+	for _, rule := range l.rules {
+		if !matchAny(rule.Tools, tool) {
+			continue
+		}
+		if rule.Timeout > 0 {
+			limit.Timeout = rule.Timeout
+		}
+		if rule.MaxConcurrency > 0 {
+			limit.MaxConcurrency = rule.MaxConcurrency
+		}
+		if rule.MaxConcurrencyPerSession > 0 {
+			limit.MaxConcurrencyPerSession = rule.MaxConcurrencyPerSession
+		}
+		if rule.QueueTimeout > 0 {
+			limit.QueueTimeout = rule.QueueTimeout
+		}
+		break
+	}
+	return limit
+}
+
+// Middleware applies the limits to tool calls. The timeout cancels the
+// handler's context, so handlers must honour it.
+func (l *Limits) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.Params.Name
+		limit := l.Limit(name)
+		// Take the session slot first, so calls queued by one session do not
+		// hold server-wide slots while they wait.
+		if limit.MaxConcurrencyPerSession > 0 {
+			release, err := l.acquire(ctx, "session:"+sessionID(ctx)+"/"+name, limit.MaxConcurrencyPerSession, limit.QueueTimeout)
+			if err != nil {
+				return nil, limitError(err, "tool %s is at its limit of %d concurrent calls per session", name, limit.MaxConcurrencyPerSession)
+			}
+			defer release()
+		}
+		if limit.MaxConcurrency > 0 {
+			release, err := l.acquire(ctx, "tool:"+name, limit.MaxConcurrency, limit.QueueTimeout)
+			if err != nil {
+				return nil, limitError(err, "tool %s is at its limit of %d concurrent calls", name, limit.MaxConcurrency)
+			}
+			defer release()
+		}
+		if limit.Timeout <= 0 {
+			return next(ctx, request)
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, limit.Timeout)
+		defer cancel()
+		result, err := next(callCtx, request)
+		if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			return nil, v1.Errorf(v1.KindUnavailable, "tool %s timed out after %s: %w", name, limit.Timeout, err)
+		}
+		return result, err
+	}
+}
+
+var errBusy = errors.New("no free slot")
+
+// acquire takes a slot of the semaphore key, waiting up to wait for one.
+func (l *Limits) acquire(ctx context.Context, key string, size int, wait time.Duration) (func(), error) {
+	l.mu.Lock()
+	sem, ok := l.semaphores[key]
+	if !ok {
+		sem = &semaphore{slots: make(chan struct{}, size)}
+		l.semaphores[key] = sem
+	}
+	sem.users++
+	l.mu.Unlock()
+
+	leave := func() {
+		l.mu.Lock()
+		sem.users--
+		if sem.users == 0 {
+			delete(l.semaphores, key)
+		}
+		l.mu.Unlock()
+	}
+	release := func() {
+		<-sem.slots
+		leave()
+	}
+
+	select {
+	case sem.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case sem.slots <- struct{}{}:
+			return release, nil
+		case <-ctx.Done():
+			leave()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	leave()
+	return nil, errBusy
+}
+
+func limitError(err error, format string, args ...any) error {
+	if !errors.Is(err, errBusy) {
+		return err
+	}
+	return v1.Errorf(v1.KindUnavailable, format+"; try again later", args...)
+}
+
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// ToolFilter appends the limits of each tool to its description, e.g.
+// "Limits: times out after 30s; at most 4 calls at once, 1 per session."
+// Neither ToolAnnotations nor the tool JSON of mcp-go has room for custom
+// fields, and the description also tells the model what to expect.
+func (l *Limits) ToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	for i, tool := range tools {
+		if text := l.Limit(tool.Name).describe(); text != "" {
+			tools[i].Description = strings.TrimSpace(tool.Description + "\n\n" + text)
+		}
+	}
+	return tools
+}
+
+func (r LimitRule) describe() string {
+	var parts []string
+	if r.Timeout > 0 {
+		parts = append(parts, "times out after "+r.Timeout.String())
+	}
+	switch {
+	case r.MaxConcurrency > 0 && r.MaxConcurrencyPerSession > 0:
+		parts = append(parts, fmt.Sprintf("at most %d calls at once, %d per session", r.MaxConcurrency, r.MaxConcurrencyPerSession))
+	case r.MaxConcurrency > 0:
+		parts = append(parts, fmt.Sprintf("at most %d calls at once", r.MaxConcurrency))
+	case r.MaxConcurrencyPerSession > 0:
+		parts = append(parts, fmt.Sprintf("at most %d calls at once per session", r.MaxConcurrencyPerSession))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Limits: " + strings.Join(parts, "; ") + "."
+}
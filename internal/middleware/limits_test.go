@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"context"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLimits(rules []any) *Limits {
+	conf := viper.New()
+	conf.Set("mcp.limits.timeout", "30s")
+	conf.Set("mcp.limits.max_concurrency", 8)
+	conf.Set("mcp.limits.rules", rules)
+	return NewLimits(conf)
+}
+
+type testSession struct {
+	id string
+}
+
+func (s testSession) Initialize()                                         {}
+func (s testSession) Initialized() bool                                   { return true }
+func (s testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (s testSession) SessionID() string                                   { return s.id }
+
+var testServer = server.NewMCPServer("test", "1.0.0")
+
+func sessionContext(id string) context.Context {
+	return testServer.WithContext(context.Background(), testSession{id: id})
+}
+
+// startCall runs a call of tool that blocks until release is closed. It
+// returns once the call holds its slots or has failed.
+func startCall(l *Limits, ctx context.Context, tool string, release chan struct{}) <-chan error {
+	running := make(chan struct{})
+	done := make(chan error, 1)
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool
+	go func() {
+		_, err := l.Middleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			close(running)
+			select {
+			case <-release:
+				return mcp.NewToolResultText("ok"), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})(ctx, request)
+		done <- err
+	}()
+	select {
+	case <-running:
+	case err := <-done:
+		done <- err
+	}
+	return done
+}
+
+func TestLimit(t *testing.T) {
+	l := newTestLimits([]any{
+		map[string]any{"tools": []string{"http_*"}, "timeout": "5s", "max_concurrency_per_session": 1},
+		// Only the first matching rule applies.
+		map[string]any{"tools": []string{"http_request", "sampleLLM"}, "timeout": "1m", "max_concurrency": 2, "queue_timeout": "1s"},
+	})
+	tests := []struct {
+		tool string
+		want LimitRule
+	}{
+		{tool: "echo", want: LimitRule{Timeout: 30 * time.Second, MaxConcurrency: 8}},
+		{tool: "http_request", want: LimitRule{Timeout: 5 * time.Second, MaxConcurrency: 8, MaxConcurrencyPerSession: 1}},
+		{tool: "sampleLLM", want: LimitRule{Timeout: time.Minute, MaxConcurrency: 2, QueueTimeout: time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			got := l.Limit(tt.tool)
+			if got.Timeout != tt.want.Timeout || got.MaxConcurrency != tt.want.MaxConcurrency ||
+				got.MaxConcurrencyPerSession != tt.want.MaxConcurrencyPerSession || got.QueueTimeout != tt.want.QueueTimeout {
+				t.Fatalf("Limit = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitsTimeout(t *testing.T) {
+	l := newTestLimits([]any{map[string]any{"tools": []string{"slow"}, "timeout": "20ms"}})
+	err := <-startCall(l, context.Background(), "slow", make(chan struct{}))
+	if v1.KindOf(err) != v1.KindUnavailable || !strings.Contains(err.Error(), "tool slow timed out after 20ms") {
+		t.Fatalf("err = %v, want an unavailable timeout error", err)
+	}
+
+	// A call cancelled by the client is not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := startCall(l, ctx, "slow", make(chan struct{}))
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestLimitsConcurrency(t *testing.T) {
+	tests := []struct {
+		name string
+		rule map[string]any
+		// The contexts of the held call and the second call.
+		first, second context.Context
+		// When the first call finishes; zero finishes both once the second
+		// call has started or failed.
+		releaseAfter time.Duration
+		wantErr      string
+	}{
+		{
+			name:    "server-wide limit",
+			rule:    map[string]any{"max_concurrency": 1},
+			first:   sessionContext("a"),
+			second:  sessionContext("b"),
+			wantErr: "tool t is at its limit of 1 concurrent calls; try again later",
+		},
+		{
+			name:    "per-session limit",
+			rule:    map[string]any{"max_concurrency_per_session": 1},
+			first:   sessionContext("a"),
+			second:  sessionContext("a"),
+			wantErr: "tool t is at its limit of 1 concurrent calls per session; try again later",
+		},
+		{
+			name:   "other session",
+			rule:   map[string]any{"max_concurrency_per_session": 1},
+			first:  sessionContext("a"),
+			second: sessionContext("b"),
+		},
+		{
+			name:         "slot freed while queued",
+			rule:         map[string]any{"max_concurrency": 1, "queue_timeout": "2s"},
+			first:        sessionContext("a"),
+			second:       sessionContext("b"),
+			releaseAfter: 20 * time.Millisecond,
+		},
+		{
+			name:    "queue timeout",
+			rule:    map[string]any{"max_concurrency": 1, "queue_timeout": "20ms"},
+			first:   sessionContext("a"),
+			second:  sessionContext("b"),
+			wantErr: "tool t is at its limit of 1 concurrent calls; try again later",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := map[string]any{"tools": []string{"t"}}
+			for k, v := range tt.rule {
+				rule[k] = v
+			}
+			l := newTestLimits([]any{rule})
+			l.defaults.MaxConcurrency = 0
+
+			release := make(chan struct{})
+			first := startCall(l, tt.first, "t", release)
+			if tt.releaseAfter > 0 {
+				time.AfterFunc(tt.releaseAfter, func() { close(release) })
+			}
+			// startCall returns once the second call runs or has failed.
+			second := startCall(l, tt.second, "t", release)
+			if tt.releaseAfter == 0 {
+				close(release)
+			}
+			err := <-second
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("second call: %v", err)
+				}
+			} else if v1.KindOf(err) != v1.KindUnavailable || err.Error() != tt.wantErr {
+				t.Fatalf("second call: err = %v, want %q", err, tt.wantErr)
+			}
+			if err := <-first; err != nil {
+				t.Fatalf("first call: %v", err)
+			}
+			if len(l.semaphores) != 0 {
+				t.Fatalf("%d semaphores left after the calls", len(l.semaphores))
+			}
+		})
+	}
+}
+
+func TestLimitsToolFilter(t *testing.T) {
+	l := newTestLimits([]any{
+		map[string]any{"tools": []string{"http_request"}, "timeout": "10s", "max_concurrency": 4, "max_concurrency_per_session": 1},
+		map[string]any{"tools": []string{"echo"}, "max_concurrency_per_session": 2},
+	})
+	l.defaults = LimitRule{}
+	tools := l.ToolFilter(context.Background(), []mcp.Tool{
+		mcp.NewTool("http_request", mcp.WithDescription("Makes a request")),
+		mcp.NewTool("echo"),
+		mcp.NewTool("add", mcp.WithDescription("Adds")),
+	})
+	want := []string{
+		"Makes a request\n\nLimits: times out after 10s; at most 4 calls at once, 1 per session.",
+		"Limits: at most 2 calls at once per session.",
+		"Adds",
+	}
+	for i, tool := range tools {
+		if tool.Description != want[i] {
+			t.Errorf("%s description = %q, want %q", tool.Name, tool.Description, want[i])
+		}
+	}
+}
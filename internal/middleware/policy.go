@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"path"
+	"slices"
+)
+
+// PolicyRule grants access to the matching tools, prompts and resources.
+// Names are path.Match patterns, e.g. "http_request" or "db://resource/*".
+// A caller matches when it has at least one of Roles and all of Scopes.
+type PolicyRule struct {
+	Tools     []string `mapstructure:"tools"`
+	Prompts   []string `mapstructure:"prompts"`
+	Resources []string `mapstructure:"resources"`
+	Roles     []string `mapstructure:"roles"`
+	Scopes    []string `mapstructure:"scopes"`
+}
+
+type Policy struct {
+	enable       bool
+	defaultAllow bool
+	rules        []PolicyRule
+	logger       *log.Logger
+}
+
+func NewPolicy(conf *viper.Viper, logger *log.Logger) *Policy {
+	var rules []PolicyRule
+	if err := conf.UnmarshalKey("mcp.policy.rules", &rules); err != nil {
+		panic(fmt.Sprintf("mcp.policy.rules error: %s", err.Error()))
+	}
+	return &Policy{
+		enable:       conf.GetBool("mcp.policy.enable"),
+		defaultAllow: conf.GetString("mcp.policy.default") != "deny",
+		rules:        rules,
+		logger:       logger,
+	}
+}
+
+func (p *Policy) AllowTool(ctx context.Context, name string) bool {
+	return p.allow(ctx, func(r PolicyRule) []string { return r.Tools }, name)
+}
+
+func (p *Policy) AllowPrompt(ctx context.Context, name string) bool {
+	return p.allow(ctx, func(r PolicyRule) []string { return r.Prompts }, name)
+}
+
+func (p *Policy) AllowResource(ctx context.Context, uri string) bool {
+	return p.allow(ctx, func(r PolicyRule) []string { return r.Resources }, uri)
+}
+
+// allow applies every rule whose patterns match name. Names without a
+// matching rule fall back to mcp.policy.default.
+func (p *Policy) allow(ctx context.Context, patterns func(PolicyRule) []string, name string) bool {
+	if !p.enable {
+		return true
+	}
+	claims, _ := jwt.ClaimsFromContext(ctx)
+	matched := false
+	for _, rule := range p.rules {
+		if !matchAny(patterns(rule), name) {
+			continue
+		}
+		matched = true
+		if !rule.permits(claims) {
+			return false
+		}
+	}
+	return matched || p.defaultAllow
+}
+
+func (r PolicyRule) permits(claims *jwt.MyCustomClaims) bool {
+	var roles, scopes []string
+	if claims != nil {
+		roles, scopes = claims.Roles, claims.Scopes
+	}
+	if len(r.Roles) > 0 && !slices.ContainsFunc(r.Roles, func(role string) bool {
+		return slices.Contains(roles, role)
+	}) {
+		return false
+	}
+	for _, scope := range r.Scopes {
+		if !slices.Contains(scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolFilter hides tools the caller may not call from tools/list.
+func (p *Policy) ToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	return slices.DeleteFunc(tools, func(tool mcp.Tool) bool {
+		return !p.AllowTool(ctx, tool.Name)
+	})
+}
+
+// Middleware rejects calls of tools the caller may not call. The error is
+// of kind forbidden, so it reaches the client as a tool error result, like
+// an egress denial, and the metrics and traces of the call record it.
+func (p *Policy) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !p.AllowTool(ctx, request.Params.Name) {
+			return nil, p.deny(ctx, "tool", request.Params.Name)
+		}
+		return next(ctx, request)
+	}
+}
+
+// PromptMiddleware rejects prompts/get for prompts the caller may not get.
+func (p *Policy) PromptMiddleware(next server.PromptHandlerFunc) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		if !p.AllowPrompt(ctx, request.Params.Name) {
+			return nil, p.deny(ctx, "prompt", request.Params.Name)
+		}
+		return next(ctx, request)
+	}
+}
+
+// ResourceMiddleware rejects resources/read for resources the caller may
+// not read, including the ones served by a template.
+func (p *Policy) ResourceMiddleware(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if !p.AllowResource(ctx, request.Params.URI) {
+			return nil, p.deny(ctx, "resource", request.Params.URI)
+		}
+		return next(ctx, request)
+	}
+}
+
+func (p *Policy) deny(ctx context.Context, kind, name string) error {
+	p.logger.WithContext(ctx).Warn("policy denied request", zap.String(kind, name))
+	return v1.Errorf(v1.KindForbidden, "%s %q is not allowed for this caller", kind, name)
+}
+
+// AfterListPrompts removes prompts the caller may not get from prompts/list.
+func (p *Policy) AfterListPrompts(ctx context.Context, id any, message *mcp.ListPromptsRequest, result *mcp.ListPromptsResult) {
+	result.Prompts = slices.DeleteFunc(result.Prompts, func(prompt mcp.Prompt) bool {
+		return !p.AllowPrompt(ctx, prompt.Name)
+	})
+}
+
+// AfterListResources removes resources the caller may not read from resources/list.
+func (p *Policy) AfterListResources(ctx context.Context, id any, message *mcp.ListResourcesRequest, result *mcp.ListResourcesResult) {
+	result.Resources = slices.DeleteFunc(result.Resources, func(resource mcp.Resource) bool {
+		return !p.AllowResource(ctx, resource.URI)
+	})
+}
+
+// AfterListResourceTemplates removes templates the caller may not read from resources/templates/list.
+func (p *Policy) AfterListResourceTemplates(ctx context.Context, id any, message *mcp.ListResourceTemplatesRequest, result *mcp.ListResourceTemplatesResult) {
+	result.ResourceTemplates = slices.DeleteFunc(result.ResourceTemplates, func(template mcp.ResourceTemplate) bool {
+		return template.URITemplate != nil && !p.AllowResource(ctx, template.URITemplate.Raw())
+	})
+}
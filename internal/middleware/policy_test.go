@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"context"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"slices"
+	"testing"
+)
+
+func newTestPolicy(enable bool, defaultPolicy string) *Policy {
+	conf := viper.New()
+	conf.Set("mcp.policy.enable", enable)
+	conf.Set("mcp.policy.default", defaultPolicy)
+	conf.Set("mcp.policy.rules", []any{
+		map[string]any{"tools": []string{"admin_*"}, "roles": []string{"admin", "ops"}},
+		map[string]any{"tools": []string{"http_request"}, "scopes": []string{"net", "read"}},
+		// Both rules match admin_reset, so it also needs the write scope.
+		map[string]any{"tools": []string{"admin_reset"}, "scopes": []string{"write"}},
+		map[string]any{"prompts": []string{"secret_*"}, "roles": []string{"admin"}},
+		map[string]any{"resources": []string{"audit://*", "audit://records/*"}, "roles": []string{"admin"}},
+		map[string]any{"tools": []string{"echo"}, "prompts": []string{"greeting"}, "resources": []string{"docs://*"}},
+	})
+	return NewPolicy(conf, &log.Logger{Logger: zap.NewNop()})
+}
+
+func claimsContext(roles, scopes []string) context.Context {
+	if roles == nil && scopes == nil {
+		return context.Background()
+	}
+	return jwt.WithClaims(context.Background(), &jwt.MyCustomClaims{UserId: "u1", Roles: roles, Scopes: scopes})
+}
+
+func TestPolicyAllow(t *testing.T) {
+	tests := []struct {
+		name          string
+		enable        bool
+		defaultPolicy string
+		roles         []string
+		scopes        []string
+		kind          string
+		target        string
+		allow         bool
+	}{
+		{name: "disabled", enable: false, kind: "tool", target: "admin_users", allow: true},
+		{name: "no claims", enable: true, kind: "tool", target: "admin_users", allow: false},
+		{name: "one of the roles", enable: true, roles: []string{"ops"}, kind: "tool", target: "admin_users", allow: true},
+		{name: "other role", enable: true, roles: []string{"user"}, kind: "tool", target: "admin_users", allow: false},
+		{name: "all scopes", enable: true, scopes: []string{"read", "net"}, kind: "tool", target: "http_request", allow: true},
+		{name: "missing scope", enable: true, scopes: []string{"read"}, kind: "tool", target: "http_request", allow: false},
+		{name: "every matching rule", enable: true, roles: []string{"admin"}, kind: "tool", target: "admin_reset", allow: false},
+		{name: "every matching rule satisfied", enable: true, roles: []string{"admin"}, scopes: []string{"write"}, kind: "tool", target: "admin_reset", allow: true},
+		{name: "rule without conditions", enable: true, defaultPolicy: "deny", kind: "tool", target: "echo", allow: true},
+		{name: "default allow", enable: true, kind: "tool", target: "unknown", allow: true},
+		{name: "default deny", enable: true, defaultPolicy: "deny", roles: []string{"admin"}, kind: "tool", target: "unknown", allow: false},
+		{name: "prompt", enable: true, roles: []string{"user"}, kind: "prompt", target: "secret_plan", allow: false},
+		{name: "prompt by role", enable: true, roles: []string{"admin"}, kind: "prompt", target: "secret_plan", allow: true},
+		{name: "tool pattern does not cover prompts", enable: true, kind: "prompt", target: "admin_users", allow: true},
+		{name: "resource", enable: true, kind: "resource", target: "audit://records/1", allow: false},
+		{name: "resource by role", enable: true, roles: []string{"admin"}, kind: "resource", target: "audit://records/1", allow: true},
+		{name: "pattern does not cross slashes", enable: true, defaultPolicy: "deny", kind: "resource", target: "docs://a/b", allow: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPolicy(tt.enable, tt.defaultPolicy)
+			ctx := claimsContext(tt.roles, tt.scopes)
+			var got bool
+			switch tt.kind {
+			case "tool":
+				got = p.AllowTool(ctx, tt.target)
+			case "prompt":
+				got = p.AllowPrompt(ctx, tt.target)
+			case "resource":
+				got = p.AllowResource(ctx, tt.target)
+			}
+			if got != tt.allow {
+				t.Fatalf("allow %s %q = %v, want %v", tt.kind, tt.target, got, tt.allow)
+			}
+		})
+	}
+}
+
+func TestPolicyFilters(t *testing.T) {
+	p := newTestPolicy(true, "")
+	tests := []struct {
+		name      string
+		roles     []string
+		tools     []string
+		prompts   []string
+		resources []string
+		templates []string
+	}{
+		{
+			name:      "anonymous",
+			tools:     []string{"echo", "other"},
+			prompts:   []string{"greeting"},
+			resources: []string{"docs://readme"},
+			templates: []string{"docs://{name}"},
+		},
+		{
+			name:      "admin",
+			roles:     []string{"admin"},
+			tools:     []string{"admin_users", "echo", "other"},
+			prompts:   []string{"greeting", "secret_plan"},
+			resources: []string{"audit://records", "docs://readme"},
+			templates: []string{"audit://records/{id}", "docs://{name}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := claimsContext(tt.roles, nil)
+
+			tools := p.ToolFilter(ctx, []mcp.Tool{
+				mcp.NewTool("admin_users"), mcp.NewTool("echo"), mcp.NewTool("http_request"), mcp.NewTool("other"),
+			})
+			if got := names(tools, func(tool mcp.Tool) string { return tool.Name }); !slices.Equal(got, tt.tools) {
+				t.Errorf("tools = %v, want %v", got, tt.tools)
+			}
+
+			prompts := &mcp.ListPromptsResult{Prompts: []mcp.Prompt{mcp.NewPrompt("greeting"), mcp.NewPrompt("secret_plan")}}
+			p.AfterListPrompts(ctx, 1, &mcp.ListPromptsRequest{}, prompts)
+			if got := names(prompts.Prompts, func(prompt mcp.Prompt) string { return prompt.Name }); !slices.Equal(got, tt.prompts) {
+				t.Errorf("prompts = %v, want %v", got, tt.prompts)
+			}
+
+			resources := &mcp.ListResourcesResult{Resources: []mcp.Resource{
+				mcp.NewResource("audit://records", "audit"), mcp.NewResource("docs://readme", "readme"),
+			}}
+			p.AfterListResources(ctx, 1, &mcp.ListResourcesRequest{}, resources)
+			if got := names(resources.Resources, func(resource mcp.Resource) string { return resource.URI }); !slices.Equal(got, tt.resources) {
+				t.Errorf("resources = %v, want %v", got, tt.resources)
+			}
+
+			templates := &mcp.ListResourceTemplatesResult{ResourceTemplates: []mcp.ResourceTemplate{
+				mcp.NewResourceTemplate("audit://records/{id}", "record"), mcp.NewResourceTemplate("docs://{name}", "doc"),
+			}}
+			p.AfterListResourceTemplates(ctx, 1, &mcp.ListResourceTemplatesRequest{}, templates)
+			if got := names(templates.ResourceTemplates, func(template mcp.ResourceTemplate) string { return template.URITemplate.Raw() }); !slices.Equal(got, tt.templates) {
+				t.Errorf("templates = %v, want %v", got, tt.templates)
+			}
+		})
+	}
+}
+
+func names[T any](items []T, name func(T) string) []string {
+	out := []string{}
+	for _, item := range items {
+		out = append(out, name(item))
+	}
+	return out
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	p := newTestPolicy(true, "")
+	tests := []struct {
+		name   string
+		roles  []string
+		kind   string
+		target string
+		denied bool
+	}{
+		{name: "denied tool", kind: "tool", target: "admin_users", denied: true},
+		{name: "allowed tool", roles: []string{"admin"}, kind: "tool", target: "admin_users"},
+		{name: "denied prompt", kind: "prompt", target: "secret_plan", denied: true},
+		{name: "allowed prompt", kind: "prompt", target: "greeting"},
+		{name: "denied resource", kind: "resource", target: "audit://records/1", denied: true},
+		{name: "allowed resource", roles: []string{"admin"}, kind: "resource", target: "audit://records/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := claimsContext(tt.roles, nil)
+			called := false
+			var err error
+			switch tt.kind {
+			case "tool":
+				request := mcp.CallToolRequest{}
+				request.Params.Name = tt.target
+				_, err = p.Middleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+					called = true
+					return mcp.NewToolResultText("ok"), nil
+				})(ctx, request)
+			case "prompt":
+				request := mcp.GetPromptRequest{}
+				request.Params.Name = tt.target
+				_, err = p.PromptMiddleware(func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+					called = true
+					return &mcp.GetPromptResult{}, nil
+				})(ctx, request)
+			case "resource":
+				request := mcp.ReadResourceRequest{}
+				request.Params.URI = tt.target
+				_, err = p.ResourceMiddleware(func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+					called = true
+					return nil, nil
+				})(ctx, request)
+			}
+			if tt.denied {
+				if v1.KindOf(err) != v1.KindForbidden || called {
+					t.Fatalf("got %v (handler called %v), want a forbidden error", err, called)
+				}
+				return
+			}
+			if err != nil || !called {
+				t.Fatalf("got %v (handler called %v), want the handler to run", err, called)
+			}
+		})
+	}
+}
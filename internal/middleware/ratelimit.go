@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// RateLimitRule limits the calls of the matching tools with a token bucket
+// and a daily quota. Calls are counted separately for every combination of
+// the By dimensions: "subject" (the JWT subject), "session" and "tool".
+// Without By, all matching calls share one bucket.
+type RateLimitRule struct {
+	Tools      []string `mapstructure:"tools"`
+	By         []string `mapstructure:"by"`
+	Rate       float64  `mapstructure:"rate"` // calls per second, 0 for no rate limit
+	Burst      int      `mapstructure:"burst"`
+	DailyQuota int      `mapstructure:"daily_quota"` // calls per UTC day, 0 for no quota
+}
+
+// RateLimit rejects tool calls over the rate limits and quotas of
+// mcp.rate_limit.rules. Unlike mcp.limits, every matching rule applies.
+type RateLimit struct {
+	enable   bool
+	failOpen bool
+	rules    []RateLimitRule
+	repo     repository.RateLimitRepository
+	logger   *log.Logger
+}
+
+func NewRateLimit(conf *viper.Viper, logger *log.Logger, repo repository.RateLimitRepository) *RateLimit {
+	var rules []RateLimitRule
+	if err := conf.UnmarshalKey("mcp.rate_limit.rules", &rules); err != nil {
+		panic(fmt.Sprintf("mcp.rate_limit.rules error: %s", err.Error()))
+	}
+	for i, rule := range rules {
+		if rule.Rate > 0 && rule.Burst < 1 {
+			panic(fmt.Sprintf("mcp.rate_limit.rules error: rule %d needs a burst of at least 1", i))
+		}
+		for _, dim := range rule.By {
+			if dim != "subject" && dim != "session" && dim != "tool" {
+				panic(fmt.Sprintf("mcp.rate_limit.rules error: rule %d: unknown dimension %q", i, dim))
+			}
+		}
+	}
+	conf.SetDefault("mcp.rate_limit.fail_open", true)
+	return &RateLimit{
+		enable:   conf.GetBool("mcp.rate_limit.enable") && repo != nil,
+		failOpen: conf.GetBool("mcp.rate_limit.fail_open"),
+		rules:    rules,
+		repo:     repo,
+		logger:   logger,
+	}
+}
+
+// Middleware rejects calls over a limit with an unavailable error that
+// tells the client when to retry.
+func (l *RateLimit) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !l.enable {
+			return next(ctx, request)
+		}
+		if err := l.check(ctx, request.Params.Name); err != nil {
+			return nil, err
+		}
+		return next(ctx, request)
+	}
+}
+
+// check takes a token and a quota call from every matching rule. When a
+// rule rejects the call, what the earlier rules took is given back, so
+// rejected calls use up neither rates nor quotas.
+func (l *RateLimit) check(ctx context.Context, tool string) error {
+	var taken []func(context.Context) error
+	reject := func(err error) error {
+		l.giveBack(ctx, taken)
+		return err
+	}
+	for i, rule := range l.rules {
+		if !matchAny(rule.Tools, tool) {
+			continue
+		}
+		key := l.key(ctx, i, rule, tool)
+		if rule.Rate > 0 {
+			// With fail_open, a failed check lets the call past this limit
+			// only; the other limits are still checked.
+			ok, wait, err := l.repo.Allow(ctx, key, rule.Rate, rule.Burst)
+			switch {
+			case err != nil:
+				if err := l.failure(ctx, err); err != nil {
+					return reject(err)
+				}
+			case !ok:
+				return reject(rejected(wait, "rate limit of %s exceeded", tool))
+			default:
+				taken = append(taken, func(ctx context.Context) error {
+					return l.repo.Refund(ctx, key, rule.Burst)
+				})
+			}
+		}
+		if rule.DailyQuota > 0 {
+			ok, wait, err := l.repo.Count(ctx, key, rule.DailyQuota)
+			switch {
+			case err != nil:
+				if err := l.failure(ctx, err); err != nil {
+					return reject(err)
+				}
+			case !ok:
+				return reject(rejected(wait, "daily quota of %d calls to %s used up", rule.DailyQuota, tool))
+			default:
+				taken = append(taken, func(ctx context.Context) error {
+					return l.repo.Uncount(ctx, key)
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func (l *RateLimit) giveBack(ctx context.Context, taken []func(context.Context) error) {
+	for _, fn := range taken {
+		if err := fn(ctx); err != nil {
+			l.logger.WithContext(ctx).Warn("rate limit give back failed", zap.Error(err))
+		}
+	}
+}
+
+// key names the bucket of a call, e.g. "1:subject=alice:tool=http_request".
+func (l *RateLimit) key(ctx context.Context, index int, rule RateLimitRule, tool string) string {
+	parts := []string{fmt.Sprint(index)}
+	for _, dim := range rule.By {
+		var value string
+		switch dim {
+		case "subject":
+			if claims, ok := jwt.ClaimsFromContext(ctx); ok {
+				value = claims.Subject
+			}
+		case "session":
+			value = sessionID(ctx)
+		case "tool":
+			value = tool
+		}
+		parts = append(parts, dim+"="+value)
+	}
+	return strings.Join(parts, ":")
+}
+
+// failure lets calls through when the backend fails, unless
+// mcp.rate_limit.fail_open is off.
+func (l *RateLimit) failure(ctx context.Context, err error) error {
+	l.logger.WithContext(ctx).Warn("rate limit backend failed", zap.Error(err))
+	if l.failOpen {
+		return nil
+	}
+	return &v1.Error{Kind: v1.KindUnavailable, Message: "rate limiter unavailable", Err: err, RetryAfter: time.Second}
+}
+
+func rejected(wait time.Duration, format string, args ...any) error {
+	wait = max(wait.Round(time.Second), time.Second)
+	return &v1.Error{
+		Kind:       v1.KindUnavailable,
+		Message:    fmt.Sprintf(format, args...) + fmt.Sprintf("; retry after %s", wait),
+		RetryAfter: wait,
+	}
+}
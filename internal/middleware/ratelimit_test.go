@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func newTestRateLimit(t *testing.T, rules []any) *RateLimit {
+	t.Helper()
+	conf := viper.New()
+	conf.Set("mcp.rate_limit.enable", true)
+	conf.Set("mcp.rate_limit.rules", rules)
+	return NewRateLimit(conf, &log.Logger{Logger: zap.NewNop()}, repository.NewMemoryRateLimitRepository())
+}
+
+func callTool(l *RateLimit, tool string) error {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool
+	_, err := l.Middleware(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})(context.Background(), request)
+	return err
+}
+
+func TestRateLimitRejectedCallsUseNoQuota(t *testing.T) {
+	// The shipped config: a daily quota on every tool, and a rate limit on
+	// http_request checked after it.
+	l := newTestRateLimit(t, []any{
+		map[string]any{"tools": []string{"*"}, "daily_quota": 3},
+		map[string]any{"tools": []string{"http_request"}, "rate": 0.001, "burst": 1},
+	})
+	if err := callTool(l, "http_request"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		err := callTool(l, "http_request")
+		if v1.KindOf(err) != v1.KindUnavailable {
+			t.Fatalf("throttled call %d: got %v, want an unavailable error", i, err)
+		}
+	}
+	// The throttled retries gave their quota back, so two calls are left.
+	for i := 0; i < 2; i++ {
+		if err := callTool(l, "echo"); err != nil {
+			t.Fatalf("echo %d: %v, want quota left", i, err)
+		}
+	}
+	if err := callTool(l, "echo"); v1.KindOf(err) != v1.KindUnavailable {
+		t.Fatalf("echo past quota: got %v, want an unavailable error", err)
+	}
+}
+
+func TestRateLimitQuotaRejectionRefundsToken(t *testing.T) {
+	l := newTestRateLimit(t, []any{
+		map[string]any{"tools": []string{"*"}, "rate": 0.001, "burst": 2},
+		map[string]any{"tools": []string{"*"}, "daily_quota": 1},
+	})
+	if err := callTool(l, "echo"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := callTool(l, "echo"); err == nil {
+			t.Fatalf("call %d past quota allowed", i)
+		}
+	}
+	// One token is left, since the quota rejections gave theirs back.
+	ok, _, _ := l.repo.Allow(context.Background(), "0", 0.001, 2)
+	if !ok {
+		t.Fatal("no token left, want the quota rejections refunded")
+	}
+}
+
+// failingAllowRepository fails every rate check and keeps the daily quotas.
+type failingAllowRepository struct {
+	repository.RateLimitRepository
+}
+
+func (r failingAllowRepository) Allow(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestRateLimitBackendFailure(t *testing.T) {
+	rules := []any{
+		map[string]any{"tools": []string{"*"}, "rate": 1, "burst": 1},
+		map[string]any{"tools": []string{"echo"}, "daily_quota": 1},
+	}
+	tests := []struct {
+		name     string
+		failOpen bool
+		want     []bool
+	}{
+		// The failed rate check is skipped, and the quota of the next rule
+		// still applies.
+		{name: "fail open", failOpen: true, want: []bool{true, false}},
+		{name: "fail closed", want: []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := viper.New()
+			conf.Set("mcp.rate_limit.enable", true)
+			conf.Set("mcp.rate_limit.fail_open", tt.failOpen)
+			conf.Set("mcp.rate_limit.rules", rules)
+			l := NewRateLimit(conf, &log.Logger{Logger: zap.NewNop()}, failingAllowRepository{repository.NewMemoryRateLimitRepository()})
+			for i, allowed := range tt.want {
+				err := callTool(l, "echo")
+				if allowed != (err == nil) {
+					t.Fatalf("call %d: got %v, want allowed %v", i, err, allowed)
+				}
+				if err != nil && v1.KindOf(err) != v1.KindUnavailable {
+					t.Fatalf("call %d: got %v, want an unavailable error", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	l := NewRateLimit(viper.New(), &log.Logger{Logger: zap.NewNop()}, nil)
+	for i := 0; i < 3; i++ {
+		if err := callTool(l, "echo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package migration
+
+import (
+	"github.com/go-nunu/nunu-layout-mcp/pkg/migrate"
+	"gorm.io/gorm"
+	"time"
+)
+
+// auditRecord is model.AuditRecord as this migration creates it. Migrations
+// keep their own copy, so later changes to the model do not change them.
+type auditRecord struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	SessionID string    `gorm:"size:64;index"`
+	Caller    string    `gorm:"size:255;index"`
+	Tool      string    `gorm:"size:255;index"`
+	Arguments string    `gorm:"type:text"`
+	Status    string    `gorm:"size:16"`
+	Error     string    `gorm:"type:text"`
+	Duration  time.Duration
+}
+
+func (auditRecord) TableName() string {
+	return "audit_records"
+}
+
+func init() {
+	register(&migrate.Migration{
+		Version: 20261017000000,
+		Name:    "create_audit_records",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&auditRecord{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&auditRecord{})
+		},
+	})
+}
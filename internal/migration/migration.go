@@ -0,0 +1,148 @@
+// Package migration holds the schema migrations of this service. Go
+// migrations register themselves from files named <version>_<name>.go; SQL
+// migrations live in sql/ (see migrate.LoadSQL). Create both with
+// `go run ./cmd/migration create <name>`.
+package migration
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/migrate"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed all:sql
+var sqlFiles embed.FS
+
+var goMigrations []*migrate.Migration
+
+func register(m *migrate.Migration) {
+	goMigrations = append(goMigrations, m)
+}
+
+func NewMigrator(db *gorm.DB) (*migrate.Migrator, error) {
+	sqlDir, err := fs.Sub(sqlFiles, "sql")
+	if err != nil {
+		return nil, err
+	}
+	sqlMigrations, err := migrate.LoadSQL(sqlDir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, append(goMigrations, sqlMigrations...)...)
+}
+
+// NewCheckedDB opens data.db.user like repository.NewDB, then acts on
+// data.migrations.on_start: "up" applies the pending migrations, "check"
+// refuses to start while some are pending, and "off" does neither. It
+// returns nil, without opening the database or checking the migrations,
+// when neither the audit log nor the database resources are enabled.
+func NewCheckedDB(conf *viper.Viper, logger *log.Logger) (*gorm.DB, error) {
+	if !conf.GetBool("audit.enable") && !conf.GetBool("data.resources.enable") {
+		logger.Info("audit and database resources are disabled, not opening data.db.user")
+		return nil, nil
+	}
+	db := repository.NewDB(conf, logger)
+	conf.SetDefault("data.migrations.on_start", "check")
+	onStart := conf.GetString("data.migrations.on_start")
+	if onStart == "off" {
+		return db, nil
+	}
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	switch onStart {
+	case "check":
+		if err := migrator.Check(ctx); err != nil {
+			return nil, fmt.Errorf("%w; run `go run ./cmd/migration up`", err)
+		}
+	case "up":
+		done, err := migrator.Up(ctx, 0)
+		for _, m := range done {
+			logger.Info("migration applied", zap.Int64("version", m.Version), zap.String("name", m.Name))
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("data.migrations.on_start error: unknown value %q", onStart)
+	}
+	return db, nil
+}
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+var goTemplate = template.Must(template.New("migration").Parse(`package migration
+
+import (
+	"github.com/go-nunu/nunu-layout-mcp/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(&migrate.Migration{
+		Version: {{ .Version }},
+		Name:    "{{ .Name }}",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`))
+
+// Create writes an empty migration named name to dir, the directory of this
+// package, and returns the paths it wrote. The version is the current UTC
+// time. SQL migrations go to dir/sql.
+func Create(dir, name string, sql bool) ([]string, error) {
+	name = strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name must contain letters or digits")
+	}
+	version := time.Now().UTC().Format("20060102150405")
+	files := map[string]string{}
+	if sql {
+		base := filepath.Join(dir, "sql", version+"_"+name)
+		files[base+".up.sql"] = "-- " + name + "\n"
+		files[base+".down.sql"] = "-- undo " + name + "\n"
+	} else {
+		var b strings.Builder
+		if err := goTemplate.Execute(&b, map[string]string{"Version": version, "Name": name}); err != nil {
+			return nil, err
+		}
+		files[filepath.Join(dir, version+"_"+name+".go")] = b.String()
+	}
+
+	var paths []string
+	for path, content := range files {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return paths, err
+		}
+		_, err = f.WriteString(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,112 @@
+package migration
+
+import (
+	"context"
+	"github.com/glebarez/sqlite"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewCheckedDB(t *testing.T) {
+	tests := []struct {
+		name      string
+		audit     bool
+		resources bool
+		driver    string
+		onStart   string
+		wantDB    bool
+		wantTable bool
+		wantErr   string
+	}{
+		// An unknown driver would panic if the database were opened.
+		{name: "nothing uses the database", driver: "none", onStart: "check"},
+		{name: "audit applies migrations", audit: true, driver: "sqlite", onStart: "up", wantDB: true, wantTable: true},
+		{name: "resources check migrations", resources: true, driver: "sqlite", onStart: "check", wantErr: "cmd/migration up"},
+		{name: "migrations off", resources: true, driver: "sqlite", onStart: "off", wantDB: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := viper.New()
+			conf.Set("audit.enable", tt.audit)
+			conf.Set("data.resources.enable", tt.resources)
+			conf.Set("data.db.user.driver", tt.driver)
+			conf.Set("data.db.user.dsn", filepath.Join(t.TempDir(), "test.db"))
+			conf.Set("data.migrations.on_start", tt.onStart)
+
+			db, err := NewCheckedDB(conf, &log.Logger{Logger: zap.NewNop()})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewCheckedDB = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewCheckedDB: %v", err)
+			}
+			if (db != nil) != tt.wantDB {
+				t.Fatalf("db = %v, want opened %v", db, tt.wantDB)
+			}
+			if db == nil {
+				return
+			}
+			if got := db.Migrator().HasTable("audit_records"); got != tt.wantTable {
+				t.Fatalf("audit_records exists = %v, want %v", got, tt.wantTable)
+			}
+		})
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := []string{"audit_records", "resources"}
+	hasTables := func(want bool) {
+		t.Helper()
+		for _, table := range tables {
+			if got := db.Migrator().HasTable(table); got != want {
+				t.Fatalf("table %s exists = %v, want %v", table, got, want)
+			}
+		}
+	}
+
+	// Up, down and up again: every migration must be reversible.
+	for i := 0; i < 2; i++ {
+		done, err := migrator.Up(ctx, 0)
+		if err != nil {
+			t.Fatalf("up: %v", err)
+		}
+		if len(done) != len(tables) {
+			t.Fatalf("up applied %d migrations, want %d", len(done), len(tables))
+		}
+		hasTables(true)
+		if err := migrator.Check(ctx); err != nil {
+			t.Fatalf("check after up: %v", err)
+		}
+		if i == 1 {
+			break
+		}
+		done, err = migrator.Down(ctx, len(tables))
+		if err != nil {
+			t.Fatalf("down: %v", err)
+		}
+		if len(done) != len(tables) {
+			t.Fatalf("down rolled back %d migrations, want %d", len(done), len(tables))
+		}
+		hasTables(false)
+		if err := migrator.Check(ctx); err == nil {
+			t.Fatal("check after down = nil, want pending migrations")
+		}
+	}
+}
@@ -0,0 +1,12 @@
+CREATE TABLE resources (
+    id           BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    uri          VARCHAR(255) NOT NULL DEFAULT '',
+    name         VARCHAR(255) NOT NULL DEFAULT '',
+    description  VARCHAR(1024) NOT NULL DEFAULT '',
+    mime_type    VARCHAR(255) NOT NULL DEFAULT '',
+    text_content LONGTEXT,
+    blob_content LONGBLOB,
+    created_at   DATETIME(3),
+    updated_at   DATETIME(3),
+    INDEX idx_resources_uri (uri)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE resources (
+    id           BIGSERIAL PRIMARY KEY,
+    uri          VARCHAR(255) NOT NULL DEFAULT '',
+    name         VARCHAR(255) NOT NULL DEFAULT '',
+    description  VARCHAR(1024) NOT NULL DEFAULT '',
+    mime_type    VARCHAR(255) NOT NULL DEFAULT '',
+    text_content TEXT,
+    blob_content BYTEA,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ
+);
+CREATE INDEX idx_resources_uri ON resources (uri);
@@ -0,0 +1,12 @@
+CREATE TABLE resources (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    uri          VARCHAR(255) NOT NULL DEFAULT '',
+    name         VARCHAR(255) NOT NULL DEFAULT '',
+    description  VARCHAR(1024) NOT NULL DEFAULT '',
+    mime_type    VARCHAR(255) NOT NULL DEFAULT '',
+    text_content TEXT,
+    blob_content BLOB,
+    created_at   DATETIME,
+    updated_at   DATETIME
+);
+CREATE INDEX idx_resources_uri ON resources (uri);
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Statuses of an audited tool call.
+const (
+	AuditStatusOK        = "ok"
+	AuditStatusError     = "error"     // the handler failed or returned IsError
+	AuditStatusCancelled = "cancelled" // the client cancelled or went away
+	AuditStatusDenied    = "denied"    // mcp.policy rejected the call
+)
+
+// AuditRecord is one tool call in the audit log. Arguments is the redacted
+// JSON of the call's arguments, and Error the message the client saw.
+type AuditRecord struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	SessionID string    `gorm:"size:64;index"`
+	Caller    string    `gorm:"size:255;index"`
+	Tool      string    `gorm:"size:255;index"`
+	Arguments string    `gorm:"type:text"`
+	Status    string    `gorm:"size:16"`
+	Error     string    `gorm:"type:text"`
+	Duration  time.Duration
+}
+
+func (AuditRecord) TableName() string {
+	return "audit_records"
+}
@@ -0,0 +1,82 @@
+package model
+
+// Kinds of catalog definitions, set with `kind:`. Files without one are tools.
+const (
+	KindTool     = "tool"
+	KindPrompt   = "prompt"
+	KindResource = "resource"
+)
+
+// ToolDefinition is one file of the tool catalog (mcp.tools.dir). Exactly one
+// of Http, Static and Handler selects the backend.
+type ToolDefinition struct {
+	Kind        string           `yaml:"kind,omitempty"`
+	Name        string           `yaml:"name,omitempty"`
+	Description string           `yaml:"description,omitempty"`
+	Annotations *ToolAnnotations `yaml:"annotations,omitempty"`
+	// InputSchema is a JSON Schema object written in YAML.
+	InputSchema map[string]any `yaml:"inputSchema,omitempty"`
+
+	Http    *HttpBackend   `yaml:"http,omitempty"`
+	Static  *StaticBackend `yaml:"static,omitempty"`
+	Handler string         `yaml:"handler,omitempty"`
+}
+
+type ToolAnnotations struct {
+	Title           string `yaml:"title,omitempty"`
+	ReadOnlyHint    *bool  `yaml:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `yaml:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `yaml:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `yaml:"openWorldHint,omitempty"`
+}
+
+// HttpBackend calls an HTTP endpoint through the http_request tool service.
+// Url, Query, Headers and Body are text/template templates over the tool
+// arguments, e.g. "https://api.example.com/users/{{ pathescape .id }}".
+type HttpBackend struct {
+	Method           string            `yaml:"method,omitempty"`
+	Url              string            `yaml:"url,omitempty"`
+	Query            map[string]string `yaml:"query,omitempty"`
+	Headers          map[string]string `yaml:"headers,omitempty"`
+	Body             string            `yaml:"body,omitempty"`
+	Timeout          float64           `yaml:"timeout,omitempty"` // seconds
+	MaxResponseBytes int64             `yaml:"maxResponseBytes,omitempty"`
+}
+
+// StaticBackend returns a fixed response. Text is a text/template over the
+// tool arguments.
+type StaticBackend struct {
+	Text    string `yaml:"text,omitempty"`
+	IsError bool   `yaml:"isError,omitempty"`
+}
+
+// PromptDefinition is a catalog prompt. Message texts are text/template
+// templates over the prompt arguments.
+type PromptDefinition struct {
+	Kind        string           `yaml:"kind,omitempty"`
+	Name        string           `yaml:"name,omitempty"`
+	Description string           `yaml:"description,omitempty"`
+	Arguments   []PromptArgument `yaml:"arguments,omitempty"`
+	Messages    []PromptMessage  `yaml:"messages,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+}
+
+type PromptMessage struct {
+	Role string `yaml:"role,omitempty"` // user or assistant
+	Text string `yaml:"text,omitempty"`
+}
+
+// ResourceDefinition is a catalog resource with fixed text content.
+type ResourceDefinition struct {
+	Kind        string `yaml:"kind,omitempty"`
+	URI         string `yaml:"uri,omitempty"`
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	MimeType    string `yaml:"mimeType,omitempty"`
+	Text        string `yaml:"text,omitempty"`
+}
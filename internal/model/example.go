@@ -8,6 +8,8 @@ const (
 	LONG_RUNNING_OPERATION ToolName = "longRunningOperation"
 	SAMPLE_LLM             ToolName = "sampleLLM"
 	GET_TINY_IMAGE         ToolName = "getTinyImage"
+	HTTP_REQUEST           ToolName = "http_request"
+	NOTIFY                 ToolName = "notify"
 )
 
 type PromptName string
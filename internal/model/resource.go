@@ -0,0 +1,31 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// ResourceURIPrefix starts the URI of every database resource.
+const ResourceURIPrefix = "db://resource/"
+
+// Resource is a resource served from the database. It has either Text or
+// Blob content; URI is ResourceURI(ID), set when the row is created.
+type Resource struct {
+	ID          uint   `gorm:"primarykey"`
+	URI         string `gorm:"size:255;index"`
+	Name        string `gorm:"size:255"`
+	Description string `gorm:"size:1024"`
+	MIMEType    string `gorm:"size:255"`
+	Text        string `gorm:"column:text_content;type:text"`
+	Blob        []byte `gorm:"column:blob_content"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (Resource) TableName() string {
+	return "resources"
+}
+
+func ResourceURI(id uint) string {
+	return ResourceURIPrefix + strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+// AuditFilter selects audit records. Zero fields match everything. Records
+// come newest first; BeforeID pages through them.
+type AuditFilter struct {
+	SessionID string
+	Caller    string
+	Tool      string
+	Status    string
+	Since     time.Time
+	Until     time.Time
+	BeforeID  uint
+	Limit     int
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, records []*model.AuditRecord) error
+	Get(ctx context.Context, id uint) (*model.AuditRecord, error)
+	List(ctx context.Context, filter AuditFilter) ([]*model.AuditRecord, error)
+	// DeleteBefore deletes the records created before t and returns how many.
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+func NewAuditRepository(
+	r *Repository,
+) AuditRepository {
+	return &auditRepository{
+		Repository: r,
+	}
+}
+
+type auditRepository struct {
+	*Repository
+}
+
+func (r *auditRepository) Create(ctx context.Context, records []*model.AuditRecord) error {
+	return r.DB(ctx).Create(records).Error
+}
+
+func (r *auditRepository) Get(ctx context.Context, id uint) (*model.AuditRecord, error) {
+	var record model.AuditRecord
+	if err := r.DB(ctx).First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.Errorf(v1.KindNotFound, "audit record %d not found", id)
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *auditRepository) List(ctx context.Context, filter AuditFilter) ([]*model.AuditRecord, error) {
+	db := r.DB(ctx)
+	if filter.SessionID != "" {
+		db = db.Where("session_id = ?", filter.SessionID)
+	}
+	if filter.Caller != "" {
+		db = db.Where("caller = ?", filter.Caller)
+	}
+	if filter.Tool != "" {
+		db = db.Where("tool = ?", filter.Tool)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		db = db.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		db = db.Where("created_at < ?", filter.Until)
+	}
+	if filter.BeforeID > 0 {
+		db = db.Where("id < ?", filter.BeforeID)
+	}
+	var records []*model.AuditRecord
+	err := db.Order("id DESC").Limit(filter.Limit).Find(&records).Error
+	return records, err
+}
+
+func (r *auditRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := r.DB(ctx).Where("created_at < ?", t).Delete(&model.AuditRecord{})
+	return result.RowsAffected, result.Error
+}
@@ -2,10 +2,24 @@ package repository
 
 import (
 	"context"
+	"errors"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"gorm.io/gorm"
 )
 
 type ExampleRepository interface {
 	FetchImage(ctx context.Context) (string, error)
+
+	// ListResources returns up to limit resources with an ID above afterID,
+	// oldest first and without their content.
+	ListResources(ctx context.Context, afterID uint, limit int) ([]*model.Resource, error)
+	GetResource(ctx context.Context, id uint) (*model.Resource, error)
+	// CreateResource inserts resource and sets its ID and URI. Call it in a
+	// transaction, as it writes the row twice.
+	CreateResource(ctx context.Context, resource *model.Resource) error
+	UpdateResource(ctx context.Context, resource *model.Resource) error
+	DeleteResource(ctx context.Context, id uint) error
 }
 
 func NewExampleRepository(
@@ -26,3 +40,49 @@ func (r *exampleRepository) FetchImage(ctx context.Context) (string, error) {
 	// TODO: Implement
 	return exampleImg, nil
 }
+
+func (r *exampleRepository) ListResources(ctx context.Context, afterID uint, limit int) ([]*model.Resource, error) {
+	var resources []*model.Resource
+	err := r.DB(ctx).
+		Omit("text_content", "blob_content").
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&resources).Error
+	return resources, err
+}
+
+func (r *exampleRepository) GetResource(ctx context.Context, id uint) (*model.Resource, error) {
+	var resource model.Resource
+	if err := r.DB(ctx).First(&resource, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, v1.Errorf(v1.KindNotFound, "resource %d not found", id)
+		}
+		return nil, err
+	}
+	return &resource, nil
+}
+
+func (r *exampleRepository) CreateResource(ctx context.Context, resource *model.Resource) error {
+	db := r.DB(ctx)
+	if err := db.Create(resource).Error; err != nil {
+		return err
+	}
+	resource.URI = model.ResourceURI(resource.ID)
+	return db.Model(resource).UpdateColumn("uri", resource.URI).Error
+}
+
+func (r *exampleRepository) UpdateResource(ctx context.Context, resource *model.Resource) error {
+	return r.DB(ctx).Save(resource).Error
+}
+
+func (r *exampleRepository) DeleteResource(ctx context.Context, id uint) error {
+	result := r.DB(ctx).Delete(&model.Resource{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return v1.Errorf(v1.KindNotFound, "resource %d not found", id)
+	}
+	return nil
+}
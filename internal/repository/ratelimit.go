@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitRepository stores token buckets and daily call counters. The
+// memory backend suits a single instance; the Redis backend shares the
+// state between instances.
+type RateLimitRepository interface {
+	// Allow takes a token from bucket key, which holds up to burst tokens and
+	// refills at rate tokens per second. When the bucket is empty it returns
+	// false and the time until the next token.
+	Allow(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error)
+	// Refund puts back a token taken by Allow, up to burst.
+	Refund(ctx context.Context, key string, burst int) error
+	// Count adds a call to key's counter for the current UTC day. Past limit
+	// it returns false, without counting the call, and the time until the
+	// counter resets at midnight.
+	Count(ctx context.Context, key string, limit int) (bool, time.Duration, error)
+	// Uncount takes back a call added by Count.
+	Uncount(ctx context.Context, key string) error
+}
+
+// NewRateLimitRepository returns the backend set in mcp.rate_limit.backend,
+// memory or redis. The Redis backend uses data.redis. It returns nil when
+// mcp.rate_limit is disabled, so Redis is not dialed for nothing.
+func NewRateLimitRepository(conf *viper.Viper) RateLimitRepository {
+	if !conf.GetBool("mcp.rate_limit.enable") {
+		return nil
+	}
+	switch backend := conf.GetString("mcp.rate_limit.backend"); backend {
+	case "", "memory":
+		return NewMemoryRateLimitRepository()
+	case "redis":
+		return NewRedisRateLimitRepository(NewRedis(conf))
+	default:
+		panic(fmt.Sprintf("mcp.rate_limit.backend error: unknown backend %q", backend))
+	}
+}
+
+// untilMidnight returns the time left in the UTC day of now.
+func untilMidnight(now time.Time) time.Duration {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
+
+// retryAfter is the time until a bucket with tokens left refills to one.
+func retryAfter(tokens, rate float64) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) / rate * float64(time.Second)))
+}
+
+type memoryRateLimitRepository struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	full   time.Time // when the bucket is full again and can be forgotten
+	last   time.Time
+}
+
+type counter struct {
+	day   string
+	count int
+}
+
+func NewMemoryRateLimitRepository() RateLimitRepository {
+	return &memoryRateLimitRepository{
+		buckets:   map[string]*bucket{},
+		counters:  map[string]*counter{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (r *memoryRateLimitRepository) Allow(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sweep(now)
+
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		r.buckets[key] = b
+	}
+	b.tokens = min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, retryAfter(b.tokens, rate), nil
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	return true, 0, nil
+}
+
+func (r *memoryRateLimitRepository) Refund(ctx context.Context, key string, burst int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.buckets[key]; ok {
+		b.tokens = min(float64(burst), b.tokens+1)
+	}
+	return nil
+}
+
+func (r *memoryRateLimitRepository) Count(ctx context.Context, key string, limit int) (bool, time.Duration, error) {
+	now := time.Now()
+	day := now.UTC().Format(time.DateOnly)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sweep(now)
+
+	c, ok := r.counters[key]
+	if !ok || c.day != day {
+		c = &counter{day: day}
+		r.counters[key] = c
+	}
+	if c.count >= limit {
+		return false, untilMidnight(now), nil
+	}
+	c.count++
+	return true, 0, nil
+}
+
+func (r *memoryRateLimitRepository) Uncount(ctx context.Context, key string) error {
+	day := time.Now().UTC().Format(time.DateOnly)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.counters[key]; ok && c.day == day && c.count > 0 {
+		c.count--
+	}
+	return nil
+}
+
+// sweep forgets full buckets and counters of past days once a minute, so
+// keys of closed sessions do not pile up.
+func (r *memoryRateLimitRepository) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < time.Minute {
+		return
+	}
+	r.lastSweep = now
+	for key, b := range r.buckets {
+		if now.After(b.full) {
+			delete(r.buckets, key)
+		}
+	}
+	day := now.UTC().Format(time.DateOnly)
+	for key, c := range r.counters {
+		if c.day != day {
+			delete(r.counters, key)
+		}
+	}
+}
+
+// allowScript refills and takes from a token bucket stored as a hash. Time
+// comes from the Redis server, so instances with skewed clocks agree.
+var allowScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// refundScript puts a token back into a bucket that still exists. The
+// refill since "last" is added by the next allowScript run, capped at burst.
+var refundScript = redis.NewScript(`
+local tokens = tonumber(redis.call("HGET", KEYS[1], "tokens"))
+if tokens then
+	redis.call("HSET", KEYS[1], "tokens", tostring(math.min(tonumber(ARGV[1]), tokens + 1)))
+end
+return 0
+`)
+
+// countScript counts a call unless the counter has reached the limit, so
+// rejected calls do not count.
+var countScript = redis.NewScript(`
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+if count >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+
+// uncountScript decrements a counter, never below zero.
+var uncountScript = redis.NewScript(`
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+if count > 0 then
+	redis.call("DECR", KEYS[1])
+end
+return 0
+`)
+
+type redisRateLimitRepository struct {
+	rdb *redis.Client
+}
+
+func NewRedisRateLimitRepository(rdb *redis.Client) RateLimitRepository {
+	return &redisRateLimitRepository{rdb: rdb}
+}
+
+func (r *redisRateLimitRepository) Allow(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	res, err := allowScript.Run(ctx, r.rdb, []string{"ratelimit:bucket:" + key}, rate, burst).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if allowed, _ := res[0].(int64); allowed == 1 {
+		return true, 0, nil
+	}
+	var tokens float64
+	if s, ok := res[1].(string); ok {
+		fmt.Sscan(s, &tokens)
+	}
+	return false, retryAfter(tokens, rate), nil
+}
+
+func (r *redisRateLimitRepository) Refund(ctx context.Context, key string, burst int) error {
+	return refundScript.Run(ctx, r.rdb, []string{"ratelimit:bucket:" + key}, burst).Err()
+}
+
+func (r *redisRateLimitRepository) Count(ctx context.Context, key string, limit int) (bool, time.Duration, error) {
+	now := time.Now()
+	ttl := untilMidnight(now) + time.Hour
+	counted, err := countScript.Run(ctx, r.rdb, []string{quotaKey(now, key)}, limit, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, 0, err
+	}
+	if counted == 0 {
+		return false, untilMidnight(now), nil
+	}
+	return true, 0, nil
+}
+
+func (r *redisRateLimitRepository) Uncount(ctx context.Context, key string) error {
+	return uncountScript.Run(ctx, r.rdb, []string{quotaKey(time.Now(), key)}).Err()
+}
+
+func quotaKey(now time.Time, key string) string {
+	return "ratelimit:quota:" + now.UTC().Format(time.DateOnly) + ":" + key
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"testing"
+)
+
+// rateLimitBackends returns a memory and a miniredis-backed repository.
+func rateLimitBackends(t *testing.T) map[string]RateLimitRepository {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return map[string]RateLimitRepository{
+		"memory": NewMemoryRateLimitRepository(),
+		"redis":  NewRedisRateLimitRepository(rdb),
+	}
+}
+
+func TestRateLimitAllow(t *testing.T) {
+	ctx := context.Background()
+	for name, repo := range rateLimitBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if ok, _, err := repo.Allow(ctx, "k", 0.001, 2); err != nil || !ok {
+					t.Fatalf("call %d: got %v, %v, want allowed", i, ok, err)
+				}
+			}
+			ok, wait, err := repo.Allow(ctx, "k", 0.001, 2)
+			if err != nil || ok {
+				t.Fatalf("call 2: got %v, %v, want rejected", ok, err)
+			}
+			if wait <= 0 {
+				t.Errorf("wait = %s, want > 0", wait)
+			}
+			if ok, _, _ := repo.Allow(ctx, "other", 0.001, 2); !ok {
+				t.Error("other key rejected, want its own bucket")
+			}
+
+			if err := repo.Refund(ctx, "k", 2); err != nil {
+				t.Fatal(err)
+			}
+			if ok, _, err := repo.Allow(ctx, "k", 0.001, 2); err != nil || !ok {
+				t.Fatalf("after refund: got %v, %v, want allowed", ok, err)
+			}
+		})
+	}
+}
+
+func TestRateLimitRefundCapsAtBurst(t *testing.T) {
+	ctx := context.Background()
+	for name, repo := range rateLimitBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo.Allow(ctx, "k", 0.001, 1)
+			repo.Refund(ctx, "k", 1)
+			repo.Refund(ctx, "k", 1)
+			if ok, _, _ := repo.Allow(ctx, "k", 0.001, 1); !ok {
+				t.Fatal("first call rejected")
+			}
+			if ok, _, _ := repo.Allow(ctx, "k", 0.001, 1); ok {
+				t.Fatal("second call allowed, want the bucket capped at burst 1")
+			}
+		})
+	}
+}
+
+func TestRateLimitCount(t *testing.T) {
+	ctx := context.Background()
+	for name, repo := range rateLimitBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if ok, _, err := repo.Count(ctx, "k", 2); err != nil || !ok {
+					t.Fatalf("call %d: got %v, %v, want counted", i, ok, err)
+				}
+			}
+			// Rejected calls do not count, so one Uncount frees a call.
+			for i := 0; i < 3; i++ {
+				ok, wait, err := repo.Count(ctx, "k", 2)
+				if err != nil || ok {
+					t.Fatalf("over quota: got %v, %v, want rejected", ok, err)
+				}
+				if wait <= 0 {
+					t.Errorf("wait = %s, want the time until midnight", wait)
+				}
+			}
+			if err := repo.Uncount(ctx, "k"); err != nil {
+				t.Fatal(err)
+			}
+			if ok, _, _ := repo.Count(ctx, "k", 2); !ok {
+				t.Fatal("after uncount: rejected, want counted")
+			}
+			if ok, _, _ := repo.Count(ctx, "k", 2); ok {
+				t.Fatal("counted past the quota")
+			}
+		})
+	}
+}
+
+func TestRateLimitUncountNotBelowZero(t *testing.T) {
+	ctx := context.Background()
+	for name, repo := range rateLimitBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			repo.Uncount(ctx, "k")
+			repo.Count(ctx, "k", 1)
+			if ok, _, _ := repo.Count(ctx, "k", 1); ok {
+				t.Fatal("counted past the quota after an Uncount of an empty counter")
+			}
+		})
+	}
+}
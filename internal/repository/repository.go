@@ -6,7 +6,6 @@ import (
 	"github.com/glebarez/sqlite"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/zapgorm2"
-	"github.com/mark3labs/mcp-go/client"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"gorm.io/driver/mysql"
@@ -25,11 +24,11 @@ type Repository struct {
 
 func NewRepository(
 	logger *log.Logger,
-	// db *gorm.DB,
+	db *gorm.DB,
 	// rdb *redis.Client,
 ) *Repository {
 	return &Repository{
-		//db: db,
+		db: db,
 		//rdb:    rdb,
 		logger: logger,
 	}
@@ -82,16 +81,19 @@ func NewDB(conf *viper.Viper, l *log.Logger) *gorm.DB {
 		db, err = gorm.Open(postgres.New(postgres.Config{
 			DSN:                  dsn,
 			PreferSimpleProtocol: true, // disables implicit prepared statement usage
-		}), &gorm.Config{})
+		}), &gorm.Config{
+			Logger: logger,
+		})
 	case "sqlite":
-		db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+		db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{
+			Logger: logger,
+		})
 	default:
 		panic("unknown db driver")
 	}
 	if err != nil {
 		panic(err)
 	}
-	db = db.Debug()
 
 	// Connection Pool config
 	sqlDB, err := db.DB()
@@ -120,14 +122,3 @@ func NewRedis(conf *viper.Viper) *redis.Client {
 
 	return rdb
 }
-
-func NewStdioMCPClient() *client.Client {
-	c, err := client.NewStdioMCPClient(
-		"go", []string{}, "run", "/path/to/server/main.go",
-	)
-	if err != nil {
-		panic(err)
-	}
-	defer c.Close()
-	return c
-}
@@ -0,0 +1,433 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/telemetry"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrUpstreamUnavailable is returned while an upstream is not connected.
+var ErrUpstreamUnavailable = errors.New("upstream is not connected")
+
+// UpstreamConfig is one entry of mcp.gateway.upstreams.
+type UpstreamConfig struct {
+	Name      string `mapstructure:"name"`
+	Transport string `mapstructure:"transport"` // stdio, sse or http
+	// Command, Args and Env start a stdio upstream.
+	Command string   `mapstructure:"command"`
+	Args    []string `mapstructure:"args"`
+	Env     []string `mapstructure:"env"` // KEY=VALUE
+	// Url and Headers reach an sse or http upstream.
+	Url     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+	// Prefix is prepended to the upstream's tool and prompt names. It
+	// defaults to "<name>_".
+	Prefix string `mapstructure:"prefix"`
+	// Timeout bounds the handshake, list calls and pings. Tool calls are
+	// only bounded by the downstream request.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// ProgressFunc receives the notifications/progress an upstream sends for a call.
+type ProgressFunc func(params mcp.ProgressNotificationParams)
+
+// Upstream is a client connection to another MCP server. Calls forward
+// progress back to the caller and send notifications/cancelled upstream
+// when their ctx is cancelled.
+type Upstream struct {
+	UpstreamConfig
+	logger *log.Logger
+
+	mu            sync.RWMutex
+	client        *client.Client
+	capabilities  mcp.ServerCapabilities
+	onListChanged func()
+
+	nextID   atomic.Int64
+	progress sync.Map // progress token -> ProgressFunc
+}
+
+var upstreamNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func NewUpstreams(conf *viper.Viper, logger *log.Logger) []*Upstream {
+	var configs []UpstreamConfig
+	if err := conf.UnmarshalKey("mcp.gateway.upstreams", &configs); err != nil {
+		panic(fmt.Sprintf("mcp.gateway.upstreams error: %s", err.Error()))
+	}
+	upstreams := make([]*Upstream, 0, len(configs))
+	seen := map[string]bool{}
+	for _, c := range configs {
+		if err := c.validate(); err != nil {
+			panic(fmt.Sprintf("mcp.gateway.upstreams error: %s", err.Error()))
+		}
+		if seen[c.Name] {
+			panic(fmt.Sprintf("mcp.gateway.upstreams error: upstream %q is defined twice", c.Name))
+		}
+		seen[c.Name] = true
+		if c.Prefix == "" {
+			c.Prefix = c.Name + "_"
+		}
+		if c.Timeout <= 0 {
+			c.Timeout = 30 * time.Second
+		}
+		upstreams = append(upstreams, &Upstream{UpstreamConfig: c, logger: logger})
+	}
+	return upstreams
+}
+
+func (c UpstreamConfig) validate() error {
+	if !upstreamNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("upstream name %q must only contain letters, digits, _ and -", c.Name)
+	}
+	switch c.Transport {
+	case "stdio":
+		if c.Command == "" {
+			return fmt.Errorf("upstream %s: command is required", c.Name)
+		}
+	case "sse", "http":
+		if c.Url == "" {
+			return fmt.Errorf("upstream %s: url is required", c.Name)
+		}
+	default:
+		return fmt.Errorf("upstream %s: transport %q must be stdio, sse or http", c.Name, c.Transport)
+	}
+	return nil
+}
+
+// OnListChanged registers fn to run when the upstream reports that its
+// tools, prompts or resources changed.
+func (u *Upstream) OnListChanged(fn func()) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.onListChanged = fn
+}
+
+// Connect starts the upstream and runs the initialize handshake. ctx bounds
+// the whole connection, not just the handshake.
+func (u *Upstream) Connect(ctx context.Context, clientInfo mcp.Implementation) error {
+	c, err := u.newClient()
+	if err != nil {
+		return err
+	}
+	c.OnNotification(u.notification)
+	if err := c.Start(ctx); err != nil {
+		c.Close()
+		return err
+	}
+	request := mcp.InitializeRequest{}
+	request.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	request.Params.ClientInfo = clientInfo
+	initCtx, cancel := context.WithTimeout(ctx, u.Timeout)
+	defer cancel()
+	result, err := c.Initialize(initCtx, request)
+	if err != nil {
+		c.Close()
+		return fmt.Errorf("initialize: %w", err)
+	}
+
+	u.mu.Lock()
+	u.client = c
+	u.capabilities = result.Capabilities
+	u.mu.Unlock()
+	return nil
+}
+
+func (u *Upstream) newClient() (*client.Client, error) {
+	headers := make(map[string]string, len(u.Headers))
+	for k, v := range u.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	switch u.Transport {
+	case "stdio":
+		env := make([]string, len(u.Env))
+		for i, e := range u.Env {
+			env[i] = os.ExpandEnv(e)
+		}
+		c, err := client.NewStdioMCPClient(u.Command, env, u.Args...)
+		if err != nil {
+			return nil, err
+		}
+		if stderr, ok := client.GetStderr(c); ok {
+			go u.logStderr(bufio.NewScanner(stderr))
+		}
+		return c, nil
+	case "sse":
+		return client.NewSSEMCPClient(u.Url, client.WithHeaders(headers))
+	default:
+		return client.NewStreamableHttpClient(u.Url, transport.WithHTTPHeaders(headers))
+	}
+}
+
+// logStderr keeps the pipe drained so a chatty upstream never blocks.
+func (u *Upstream) logStderr(scanner *bufio.Scanner) {
+	for scanner.Scan() {
+		u.logger.Debug("upstream stderr", zap.String("upstream", u.Name), zap.String("line", scanner.Text()))
+	}
+}
+
+// Close disconnects the upstream. It can be connected again afterwards.
+func (u *Upstream) Close() error {
+	u.mu.Lock()
+	c := u.client
+	u.client = nil
+	u.mu.Unlock()
+	if c == nil {
+		return nil
+	}
+	return c.Close()
+}
+
+// Capabilities returns what the upstream declared when it connected.
+func (u *Upstream) Capabilities() mcp.ServerCapabilities {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.capabilities
+}
+
+func (u *Upstream) current() (*client.Client, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if u.client == nil {
+		return nil, ErrUpstreamUnavailable
+	}
+	return u.client, nil
+}
+
+func (u *Upstream) Ping(ctx context.Context) error {
+	c, err := u.current()
+	if err != nil {
+		return err
+	}
+	return c.Ping(ctx)
+}
+
+// ListTools keeps the upstream's input and output schemas verbatim, as
+// mcp.Tool only decodes part of JSON Schema.
+func (u *Upstream) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	var tools []mcp.Tool
+	err := u.list(ctx, string(mcp.MethodToolsList), func(result *json.RawMessage) (mcp.Cursor, error) {
+		var page struct {
+			mcp.PaginatedResult
+			Tools []json.RawMessage `json:"tools"`
+		}
+		if err := json.Unmarshal(*result, &page); err != nil {
+			return "", err
+		}
+		for _, raw := range page.Tools {
+			var tool mcp.Tool
+			var schemas struct {
+				InputSchema  json.RawMessage `json:"inputSchema"`
+				OutputSchema json.RawMessage `json:"outputSchema"`
+			}
+			if err := json.Unmarshal(raw, &tool); err != nil {
+				return "", err
+			}
+			if err := json.Unmarshal(raw, &schemas); err != nil {
+				return "", err
+			}
+			tool.InputSchema = mcp.ToolInputSchema{}
+			tool.RawInputSchema = schemas.InputSchema
+			tool.OutputSchema = mcp.ToolOutputSchema{}
+			tool.RawOutputSchema = schemas.OutputSchema
+			tools = append(tools, tool)
+		}
+		return page.NextCursor, nil
+	})
+	return tools, err
+}
+
+func (u *Upstream) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	var prompts []mcp.Prompt
+	err := u.list(ctx, string(mcp.MethodPromptsList), func(result *json.RawMessage) (mcp.Cursor, error) {
+		var page mcp.ListPromptsResult
+		if err := json.Unmarshal(*result, &page); err != nil {
+			return "", err
+		}
+		prompts = append(prompts, page.Prompts...)
+		return page.NextCursor, nil
+	})
+	return prompts, err
+}
+
+func (u *Upstream) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	var resources []mcp.Resource
+	err := u.list(ctx, string(mcp.MethodResourcesList), func(result *json.RawMessage) (mcp.Cursor, error) {
+		var page mcp.ListResourcesResult
+		if err := json.Unmarshal(*result, &page); err != nil {
+			return "", err
+		}
+		resources = append(resources, page.Resources...)
+		return page.NextCursor, nil
+	})
+	return resources, err
+}
+
+// list requests every page of method, passing each result to page.
+func (u *Upstream) list(ctx context.Context, method string, page func(*json.RawMessage) (mcp.Cursor, error)) error {
+	var cursor mcp.Cursor
+	for {
+		result, err := u.request(ctx, u.newRequestID(), method, mcp.PaginatedParams{Cursor: cursor})
+		if err != nil {
+			return err
+		}
+		if cursor, err = page(result); err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		if cursor == "" {
+			return nil
+		}
+	}
+}
+
+// CallTool calls the upstream tool name with arguments. When progress is
+// set, the upstream's progress notifications are passed to it. The trace
+// context of ctx is sent along in _meta.
+func (u *Upstream) CallTool(ctx context.Context, name string, arguments any, progress ProgressFunc) (_ *mcp.CallToolResult, err error) {
+	ctx, span := u.startSpan(ctx, string(mcp.MethodToolsCall)+" "+name)
+	defer func() { telemetry.End(span, err) }()
+
+	params := mcp.CallToolParams{Name: name, Arguments: arguments, Meta: &mcp.Meta{}}
+	carrier := map[string]string{}
+	telemetry.Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		params.Meta.AdditionalFields = make(map[string]any, len(carrier))
+		for k, v := range carrier {
+			params.Meta.AdditionalFields[k] = v
+		}
+	}
+	id := u.newRequestID()
+	if progress != nil {
+		token := id.Value()
+		params.Meta.ProgressToken = token
+		u.progress.Store(token, progress)
+		defer u.progress.Delete(token)
+	}
+	result, err := u.request(ctx, id, string(mcp.MethodToolsCall), params)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.ParseCallToolResult(result)
+}
+
+func (u *Upstream) GetPrompt(ctx context.Context, name string, arguments map[string]string) (_ *mcp.GetPromptResult, err error) {
+	ctx, span := u.startSpan(ctx, string(mcp.MethodPromptsGet)+" "+name)
+	defer func() { telemetry.End(span, err) }()
+
+	params := mcp.GetPromptParams{Name: name, Arguments: arguments}
+	result, err := u.request(ctx, u.newRequestID(), string(mcp.MethodPromptsGet), params)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.ParseGetPromptResult(result)
+}
+
+func (u *Upstream) ReadResource(ctx context.Context, uri string) (_ *mcp.ReadResourceResult, err error) {
+	ctx, span := u.startSpan(ctx, string(mcp.MethodResourcesRead))
+	defer func() { telemetry.End(span, err) }()
+
+	params := mcp.ReadResourceParams{URI: uri}
+	result, err := u.request(ctx, u.newRequestID(), string(mcp.MethodResourcesRead), params)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.ParseReadResourceResult(result)
+}
+
+func (u *Upstream) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return telemetry.Start(ctx, "upstream "+name,
+		attribute.String("mcp.upstream.name", u.Name),
+		attribute.String("mcp.upstream.transport", u.Transport),
+	)
+}
+
+// newRequestID uses string IDs so they never collide with the numeric IDs
+// the mcp-go client assigns to its own requests on the same connection.
+func (u *Upstream) newRequestID() mcp.RequestId {
+	return mcp.NewRequestId(fmt.Sprintf("gateway-%d", u.nextID.Add(1)))
+}
+
+// request sends a request with our own ID, so it can be cancelled upstream
+// when ctx is cancelled.
+func (u *Upstream) request(ctx context.Context, id mcp.RequestId, method string, params any) (*json.RawMessage, error) {
+	c, err := u.current()
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.GetTransport().SendRequest(ctx, transport.JSONRPCRequest{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		u.cancel(c, id, ctxErr)
+		return nil, ctxErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error.AsError()
+	}
+	return &response.Result, nil
+}
+
+func (u *Upstream) cancel(c *client.Client, id mcp.RequestId, reason error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := c.GetTransport().SendNotification(ctx, mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: "notifications/cancelled",
+			Params: mcp.NotificationParams{
+				AdditionalFields: map[string]any{
+					"requestId": id.Value(),
+					"reason":    reason.Error(),
+				},
+			},
+		},
+	})
+	if err != nil {
+		u.logger.Warn("failed to cancel upstream request", zap.String("upstream", u.Name), zap.Error(err))
+	}
+}
+
+func (u *Upstream) notification(notification mcp.JSONRPCNotification) {
+	switch method := notification.Method; {
+	case method == "notifications/progress":
+		fields := notification.Params.AdditionalFields
+		fn, ok := u.progress.Load(fields["progressToken"])
+		if !ok {
+			return
+		}
+		params := mcp.ProgressNotificationParams{ProgressToken: fields["progressToken"]}
+		params.Progress, _ = fields["progress"].(float64)
+		params.Total, _ = fields["total"].(float64)
+		params.Message, _ = fields["message"].(string)
+		fn.(ProgressFunc)(params)
+	case strings.HasSuffix(method, "/list_changed"):
+		u.mu.RLock()
+		fn := u.onListChanged
+		u.mu.RUnlock()
+		if fn != nil {
+			fn()
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/internal/middleware"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"github.com/go-nunu/nunu-layout-mcp/internal/service"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/redact"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// auditTools records every tool call in the audit log. It runs outside
+// toolErrors, so failures arrive as IsError results carrying the message
+// the client saw. Calls the policy rejected are recorded as denied.
+func auditTools(audit service.AuditService, policy *middleware.Policy, redactor *redact.Redactor) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			start := time.Now()
+			result, err := next(ctx, request)
+
+			record := newAuditRecord(ctx, start, request.Params.Name, request.GetArguments(), redactor, sensitiveArguments(ctx, &request))
+			record.Duration = time.Since(start)
+			switch {
+			case err != nil && ctx.Err() != nil:
+				record.Status, record.Error = model.AuditStatusCancelled, err.Error()
+			case err != nil:
+				record.Status, record.Error = model.AuditStatusError, err.Error()
+			case result != nil && result.IsError:
+				record.Status, record.Error = model.AuditStatusError, resultText(result)
+				if !policy.AllowTool(ctx, request.Params.Name) {
+					record.Status = model.AuditStatusDenied
+				}
+			}
+			audit.Record(ctx, record)
+			return result, err
+		}
+	}
+}
+
+func newAuditRecord(ctx context.Context, start time.Time, tool string, args map[string]any, redactor *redact.Redactor, sensitive []string) *model.AuditRecord {
+	record := &model.AuditRecord{
+		CreatedAt: start,
+		Caller:    handler.GetUserIdFromContext(ctx),
+		Tool:      tool,
+		Status:    model.AuditStatusOK,
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		record.SessionID = session.SessionID()
+	}
+	if len(args) > 0 {
+		data, _ := json.Marshal(redactor.Redact(args, sensitive...))
+		record.Arguments = string(data)
+	}
+	return record
+}
+
+func resultText(result *mcp.CallToolResult) string {
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// AuditWorker writes the queued audit records every audit.flush_interval
+// and prunes old ones every audit.prune_interval. Records still queued at
+// shutdown are written on Stop.
+type AuditWorker struct {
+	logger        *log.Logger
+	audit         service.AuditService
+	flushInterval time.Duration
+	pruneInterval time.Duration
+}
+
+// NewAuditWorker returns nil when the audit log is disabled.
+func NewAuditWorker(
+	conf *viper.Viper,
+	logger *log.Logger,
+	audit service.AuditService,
+) *AuditWorker {
+	if audit == nil {
+		return nil
+	}
+	conf.SetDefault("audit.flush_interval", time.Second)
+	conf.SetDefault("audit.prune_interval", time.Hour)
+	return &AuditWorker{
+		logger:        logger,
+		audit:         audit,
+		flushInterval: conf.GetDuration("audit.flush_interval"),
+		pruneInterval: conf.GetDuration("audit.prune_interval"),
+	}
+}
+
+func (w *AuditWorker) Start(ctx context.Context) error {
+	w.prune(ctx)
+	flush := time.NewTicker(w.flushInterval)
+	defer flush.Stop()
+	prune := time.NewTicker(w.pruneInterval)
+	defer prune.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-flush.C:
+			w.flush(ctx)
+		case <-prune.C:
+			w.prune(ctx)
+		}
+	}
+}
+
+func (w *AuditWorker) Stop(ctx context.Context) error {
+	// ctx may already be cancelled at shutdown.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	return w.audit.Flush(ctx)
+}
+
+func (w *AuditWorker) flush(ctx context.Context) {
+	if err := w.audit.Flush(ctx); err != nil {
+		w.logger.Error("audit flush failed", zap.Error(err))
+	}
+}
+
+func (w *AuditWorker) prune(ctx context.Context) {
+	if err := w.audit.Prune(ctx); err != nil {
+		w.logger.Error("audit prune failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/middleware"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/redact"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"strings"
+	"testing"
+)
+
+// recordingAudit keeps the records instead of queueing them.
+type recordingAudit struct {
+	records []*model.AuditRecord
+}
+
+func (a *recordingAudit) Record(ctx context.Context, record *model.AuditRecord) {
+	a.records = append(a.records, record)
+}
+func (a *recordingAudit) Flush(ctx context.Context) error { return nil }
+func (a *recordingAudit) Prune(ctx context.Context) error { return nil }
+func (a *recordingAudit) List(ctx context.Context, req *v1.ListAuditRequest) (*v1.ListAuditResponse, error) {
+	return nil, nil
+}
+func (a *recordingAudit) Get(ctx context.Context, id uint) (*v1.AuditRecord, error) {
+	return nil, nil
+}
+
+func TestAuditDenied(t *testing.T) {
+	conf := viper.New()
+	conf.Set("mcp.policy.enable", true)
+	conf.Set("mcp.policy.rules", []map[string]any{{"tools": []string{"admin_*"}, "roles": []string{"admin"}}})
+	conf.Set("log.redact.fields", []string{"password"})
+	logger := &log.Logger{Logger: zap.NewNop()}
+	policy := middleware.NewPolicy(conf, logger)
+	redactor := redact.NewRedactor(conf)
+	audit := &recordingAudit{}
+
+	srv := server.NewMCPServer("test", "1.0.0",
+		server.WithToolCapabilities(false),
+		server.WithToolHandlerMiddleware(auditTools(audit, policy, redactor)),
+		server.WithToolHandlerMiddleware(toolErrors(logger)),
+		server.WithToolHandlerMiddleware(policy.Middleware),
+	)
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+	srv.AddTool(mcp.NewTool("admin_reset"), handler)
+	srv.AddTool(mcp.NewTool("echo"), handler)
+
+	tests := []struct {
+		name   string
+		method string
+		params map[string]any
+		tool   string
+		status string
+	}{
+		{
+			name:   "denied call",
+			method: "tools/call",
+			params: map[string]any{"name": "admin_reset", "arguments": map[string]any{"user": "bob", "password": "hunter2"}},
+			tool:   "admin_reset",
+			status: model.AuditStatusDenied,
+		},
+		{
+			name:   "allowed call",
+			method: "tools/call",
+			params: map[string]any{"name": "echo"},
+			tool:   "echo",
+			status: model.AuditStatusOK,
+		},
+		{
+			name:   "other method",
+			method: "tools/list",
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit.records = nil
+			message, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": i, "method": tt.method, "params": tt.params})
+			srv.HandleMessage(context.Background(), message)
+
+			if tt.tool == "" {
+				if len(audit.records) != 0 {
+					t.Fatalf("got %d records for %s, want none", len(audit.records), tt.method)
+				}
+				return
+			}
+			if len(audit.records) != 1 {
+				t.Fatalf("got %d records, want 1", len(audit.records))
+			}
+			record := audit.records[0]
+			if record.Tool != tt.tool || record.Status != tt.status {
+				t.Fatalf("record = %s %s, want %s %s", record.Tool, record.Status, tt.tool, tt.status)
+			}
+			if tt.status == model.AuditStatusDenied {
+				if !strings.Contains(record.Error, "is not allowed") {
+					t.Errorf("error = %q, want the policy message", record.Error)
+				}
+				if strings.Contains(record.Arguments, "hunter2") || !strings.Contains(record.Arguments, "bob") {
+					t.Errorf("arguments = %s, want them redacted", record.Arguments)
+				}
+			}
+		})
+	}
+}
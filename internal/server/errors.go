@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"math"
+)
+
+// toolErrors recovers panics in tool handlers and reports every error as a
+// tool result with IsError set, so handlers only return errors of a v1 kind.
+// Errors of cancelled calls are passed on, as nobody reads the result.
+func toolErrors(logger *log.Logger) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					result, err = nil, recovered(ctx, logger, "tool", request.Params.Name, r)
+				}
+				if err != nil && ctx.Err() == nil {
+					result, err = toolError(normalize(ctx, logger, err)), nil
+				}
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+// promptErrors recovers panics in prompt handlers and hides internal errors.
+// mcp-go answers every handler error with the JSON-RPC code -32603, so the
+// kind only shows in the message.
+func promptErrors(logger *log.Logger) servermcp.PromptHandlerMiddleware {
+	return func(next server.PromptHandlerFunc) server.PromptHandlerFunc {
+		return func(ctx context.Context, request mcp.GetPromptRequest) (result *mcp.GetPromptResult, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					result, err = nil, recovered(ctx, logger, "prompt", request.Params.Name, r)
+				}
+				if err != nil {
+					err = normalize(ctx, logger, err)
+				}
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+// resourceErrors is promptErrors for resources and resource templates.
+func resourceErrors(logger *log.Logger) server.ResourceHandlerMiddleware {
+	return func(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+		return func(ctx context.Context, request mcp.ReadResourceRequest) (contents []mcp.ResourceContents, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					contents, err = nil, recovered(ctx, logger, "resource", request.Params.URI, r)
+				}
+				if err != nil {
+					err = normalize(ctx, logger, err)
+				}
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+func toolError(err error) *mcp.CallToolResult {
+	result := mcp.NewToolResultError(err.Error())
+	var e *v1.Error
+	if errors.As(err, &e) && e.RetryAfter > 0 {
+		result.Meta = mcp.NewMetaFromMap(map[string]any{"retryAfter": math.Ceil(e.RetryAfter.Seconds())})
+	}
+	return result
+}
+
+func recovered(ctx context.Context, logger *log.Logger, kind, name string, r any) error {
+	logger.WithContext(ctx).Error("panic in "+kind+" handler",
+		zap.String(kind, name),
+		zap.Any("panic", r),
+		zap.Stack("stack"),
+	)
+	return v1.ErrInternal
+}
+
+// normalize returns the error the client sees: err itself for the kinds
+// meant for the client, and a generic one for internal errors, which are
+// logged instead.
+func normalize(ctx context.Context, logger *log.Logger, err error) error {
+	if err == error(v1.ErrInternal) || v1.KindOf(err) != v1.KindInternal {
+		return err
+	}
+	logger.WithContext(ctx).Error("handler failed", zap.Error(err))
+	return v1.ErrInternal
+}
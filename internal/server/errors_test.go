@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"errors"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToolErrors(t *testing.T) {
+	logger := &log.Logger{Logger: zap.NewNop()}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		handler func() (*mcp.CallToolResult, error)
+		text    string
+		isError bool
+		meta    map[string]any
+		err     error
+	}{
+		{
+			name:    "result",
+			handler: func() (*mcp.CallToolResult, error) { return mcp.NewToolResultText("ok"), nil },
+			text:    "ok",
+		},
+		{
+			name:    "tool error result",
+			handler: func() (*mcp.CallToolResult, error) { return mcp.NewToolResultError("bad"), nil },
+			text:    "bad",
+			isError: true,
+		},
+		{
+			name:    "validation",
+			handler: func() (*mcp.CallToolResult, error) { return nil, v1.Errorf(v1.KindValidation, "a is required") },
+			text:    "a is required",
+			isError: true,
+		},
+		{
+			name:    "not found",
+			handler: func() (*mcp.CallToolResult, error) { return nil, v1.ErrNotFound },
+			text:    "not found",
+			isError: true,
+		},
+		{
+			name: "unavailable with retry hint",
+			handler: func() (*mcp.CallToolResult, error) {
+				return nil, &v1.Error{Kind: v1.KindUnavailable, Message: "rate limited", RetryAfter: 1500 * time.Millisecond}
+			},
+			text:    "rate limited",
+			isError: true,
+			meta:    map[string]any{"retryAfter": 2.0},
+		},
+		{
+			name:    "internal error hidden",
+			handler: func() (*mcp.CallToolResult, error) { return nil, errors.New("dial tcp 10.0.0.1:5432: refused") },
+			text:    "internal error",
+			isError: true,
+		},
+		{
+			name:    "panic",
+			handler: func() (*mcp.CallToolResult, error) { panic("boom") },
+			text:    "internal error",
+			isError: true,
+		},
+		{
+			name:    "cancelled call",
+			ctx:     cancelled,
+			handler: func() (*mcp.CallToolResult, error) { return nil, context.Canceled },
+			err:     context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			result, err := toolErrors(logger)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return tt.handler()
+			})(ctx, mcp.CallToolRequest{})
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) || result != nil {
+					t.Fatalf("got %+v, %v, want the error %v", result, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v, want a tool result", err)
+			}
+			if text := result.Content[0].(mcp.TextContent).Text; text != tt.text || result.IsError != tt.isError {
+				t.Fatalf("result = %q (isError %v), want %q (isError %v)", text, result.IsError, tt.text, tt.isError)
+			}
+			var meta map[string]any
+			if result.Meta != nil {
+				meta = result.Meta.AdditionalFields
+			}
+			if !reflect.DeepEqual(meta, tt.meta) {
+				t.Fatalf("_meta = %v, want %v", meta, tt.meta)
+			}
+		})
+	}
+}
+
+func TestPromptAndResourceErrors(t *testing.T) {
+	logger := &log.Logger{Logger: zap.NewNop()}
+	tests := []struct {
+		name string
+		err  func() error
+		want error
+	}{
+		{name: "no error", err: func() error { return nil }},
+		{name: "kind kept", err: func() error { return v1.Errorf(v1.KindValidation, "argument %q is required", "code") }, want: v1.ErrValidation},
+		{name: "internal error hidden", err: func() error { return errors.New("secret detail") }, want: v1.ErrInternal},
+		{name: "panic", err: func() error { panic("boom") }, want: v1.ErrInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, promptErr := promptErrors(logger)(func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+				return &mcp.GetPromptResult{}, tt.err()
+			})(context.Background(), mcp.GetPromptRequest{})
+			_, resourceErr := resourceErrors(logger)(func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+				return nil, tt.err()
+			})(context.Background(), mcp.ReadResourceRequest{})
+
+			for kind, err := range map[string]error{"prompt": promptErr, "resource": resourceErr} {
+				if tt.want == nil {
+					if err != nil {
+						t.Errorf("%s: err = %v, want nil", kind, err)
+					}
+					continue
+				}
+				if !errors.Is(err, tt.want) {
+					t.Errorf("%s: err = %v, want %v", kind, err, tt.want)
+				}
+				if tt.want == v1.ErrInternal && err.Error() != "internal error" {
+					t.Errorf("%s: message = %q, want the details hidden", kind, err.Error())
+				}
+			}
+		})
+	}
+}
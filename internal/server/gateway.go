@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// GatewayServer keeps the upstream MCP servers in mcp.gateway.upstreams
+// connected. Each upstream is pinged regularly; when it goes away its
+// entries are removed and it is reconnected with exponential backoff.
+type GatewayServer struct {
+	logger       *log.Logger
+	gateway      *handler.Gateway
+	upstreams    []*repository.Upstream
+	clientInfo   mcp.Implementation
+	pingInterval time.Duration
+	reconnectMax time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewGatewayServer returns nil when no upstreams are configured.
+func NewGatewayServer(
+	conf *viper.Viper,
+	logger *log.Logger,
+	gateway *handler.Gateway,
+	upstreams []*repository.Upstream,
+) *GatewayServer {
+	if len(upstreams) == 0 {
+		return nil
+	}
+	conf.SetDefault("mcp.gateway.ping_interval", 15*time.Second)
+	conf.SetDefault("mcp.gateway.reconnect_max", 30*time.Second)
+	return &GatewayServer{
+		logger:    logger,
+		gateway:   gateway,
+		upstreams: upstreams,
+		clientInfo: mcp.Implementation{
+			Name:    conf.GetString("mcp.name"),
+			Version: conf.GetString("mcp.version"),
+		},
+		pingInterval: conf.GetDuration("mcp.gateway.ping_interval"),
+		reconnectMax: conf.GetDuration("mcp.gateway.reconnect_max"),
+	}
+}
+
+func (s *GatewayServer) Start(ctx context.Context) error {
+	s.mu.Lock()
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, u := range s.upstreams {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.run(ctx, u)
+		}()
+	}
+	s.mu.Unlock()
+	<-ctx.Done()
+	return nil
+}
+
+func (s *GatewayServer) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// run connects u until ctx is done, backing off between failed attempts.
+func (s *GatewayServer) run(ctx context.Context, u *repository.Upstream) {
+	backoff := time.Second
+	for {
+		if err := s.connect(ctx, u); err != nil {
+			s.logger.Warn("upstream unavailable", zap.String("upstream", u.Name), zap.Duration("retry_in", backoff), zap.Error(err))
+		} else {
+			backoff = time.Second
+			err := s.serve(ctx, u)
+			if ctx.Err() == nil {
+				s.logger.Warn("upstream disconnected", zap.String("upstream", u.Name), zap.Duration("retry_in", backoff), zap.Error(err))
+			}
+		}
+		s.gateway.Clear(u)
+		if err := u.Close(); err != nil {
+			s.logger.Debug("failed to close upstream", zap.String("upstream", u.Name), zap.Error(err))
+		}
+		if handler.Sleep(ctx, backoff) != nil {
+			return
+		}
+		backoff = min(backoff*2, s.reconnectMax)
+	}
+}
+
+func (s *GatewayServer) connect(ctx context.Context, u *repository.Upstream) error {
+	if err := u.Connect(ctx, s.clientInfo); err != nil {
+		return err
+	}
+	syncCtx, cancel := context.WithTimeout(ctx, u.Timeout)
+	defer cancel()
+	if err := s.gateway.Sync(syncCtx, u); err != nil {
+		return err
+	}
+	s.logger.Info("connected to upstream", zap.String("upstream", u.Name), zap.String("transport", u.Transport))
+	return nil
+}
+
+// serve resyncs u when it reports changes and returns once it stops
+// answering pings.
+func (s *GatewayServer) serve(ctx context.Context, u *repository.Upstream) error {
+	changed := make(chan struct{}, 1)
+	u.OnListChanged(func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	defer u.OnListChanged(nil)
+
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+			syncCtx, cancel := context.WithTimeout(ctx, u.Timeout)
+			err := s.gateway.Sync(syncCtx, u)
+			cancel()
+			if err != nil {
+				return err
+			}
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, u.Timeout)
+			err := u.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/internal/middleware"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/metrics"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/server/http"
+	"github.com/spf13/viper"
+)
+
+// NewHTTPServer serves the admin API and the Prometheus metrics. It returns
+// nil when http.enable is off.
+func NewHTTPServer(
+	conf *viper.Viper,
+	logger *log.Logger,
+	jwt *jwt.JWT,
+	adminHandler *handler.AdminHandler,
+	auditHandler *handler.AuditHandler,
+	m *metrics.Metrics,
+) *http.Server {
+	if !conf.GetBool("http.enable") {
+		return nil
+	}
+	// Release mode keeps gin's debug output off stdout, which the STDIO
+	// transport owns.
+	gin.SetMode(gin.ReleaseMode)
+	conf.SetDefault("http.admin.roles", []string{"admin"})
+	conf.SetDefault("metrics.path", "/metrics")
+
+	s := http.NewServer(
+		gin.New(),
+		logger,
+		http.WithServerHost(conf.GetString("http.host")),
+		http.WithServerPort(conf.GetInt("http.port")),
+	)
+	s.Use(gin.Recovery())
+
+	admin := s.Group("/admin", middleware.AdminAuth(jwt, logger, conf.GetStringSlice("http.admin.roles")))
+	{
+		admin.GET("/registry", adminHandler.GetRegistry)
+		admin.POST("/catalog/reload", adminHandler.ReloadCatalog)
+		admin.PUT("/tools", adminHandler.PutTool)
+		admin.DELETE("/tools/:name", adminHandler.DeleteTool)
+		admin.PUT("/prompts", adminHandler.PutPrompt)
+		admin.DELETE("/prompts/:name", adminHandler.DeletePrompt)
+		admin.PUT("/resources", adminHandler.PutResource)
+		admin.DELETE("/resources", adminHandler.DeleteResource)
+		if conf.GetBool("data.resources.enable") {
+			admin.GET("/db/resources", adminHandler.ListDBResources)
+			admin.POST("/db/resources", adminHandler.CreateDBResource)
+			admin.GET("/db/resources/:id", adminHandler.GetDBResource)
+			admin.PUT("/db/resources/:id", adminHandler.UpdateDBResource)
+			admin.DELETE("/db/resources/:id", adminHandler.DeleteDBResource)
+		}
+		if auditHandler != nil {
+			admin.GET("/audit", auditHandler.ListAudit)
+			admin.GET("/audit/:id", auditHandler.GetAudit)
+		}
+	}
+
+	if m != nil {
+		s.GET(conf.GetString("metrics.path"), gin.WrapH(m.Handler()))
+	}
+
+	return s
+}
@@ -2,23 +2,41 @@ package server
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
 	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/internal/middleware"
 	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"github.com/go-nunu/nunu-layout-mcp/internal/service"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/metrics"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/redact"
 	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/telemetry"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"net/http"
 )
 
 func NewMCPServer(
 	conf *viper.Viper,
 	logger *log.Logger,
+	jwt *jwt.JWT,
+	policy *middleware.Policy,
+	limits *middleware.Limits,
+	rateLimit *middleware.RateLimit,
+	cancellation *handler.Cancellation,
+	m *metrics.Metrics,
+	tracing *telemetry.Tracing,
+	redactor *redact.Redactor,
+	audit service.AuditService,
 	exampleHandler handler.ExampleHandler,
+	auditHandler *handler.AuditHandler,
 ) *servermcp.Server {
-	s := setupSrv(conf, logger)
+	s := setupSrv(conf, logger, jwt, policy, limits, rateLimit, cancellation, m, tracing, redactor, audit, exampleHandler)
 
 	s.AddResource(mcp.NewResource("test://static/resource",
 		"Static Resource",
@@ -32,9 +50,10 @@ func NewMCPServer(
 		exampleHandler.ResourceTemplate,
 	)
 
-	resources := generateResources()
-	for _, resource := range resources {
-		s.AddResource(resource, exampleHandler.GeneratedResource)
+	if conf.GetBool("data.resources.enable") {
+		s.AddResourceTemplate(mcp.NewResourceTemplate(handler.DBResourceTemplate, "Database Resource",
+			mcp.WithTemplateDescription("A resource stored in the database, as listed by resources/list"),
+		), exampleHandler.DBResource)
 	}
 
 	s.AddPrompt(mcp.NewPrompt(string(model.SIMPLE),
@@ -51,197 +70,277 @@ func NewMCPServer(
 			mcp.RequiredArgument(),
 		),
 	), exampleHandler.ComplexPrompt)
-	s.AddTool(mcp.NewTool(string(model.ECHO),
+	s.AddTool(handler.NewTool[v1.EchoToolRequest](string(model.ECHO),
 		mcp.WithDescription("Echoes back the input"),
-		mcp.WithString("message",
-			mcp.Description("Message to echo"),
-			mcp.Required(),
-		),
-	), exampleHandler.EchoTool)
-	s.AddTool(mcp.NewTool("http_request",
+		handler.WithOutputSchema[v1.EchoToolResponse](),
+	), handler.NewToolHandler(exampleHandler.EchoTool))
+	s.AddTool(handler.NewTool[v1.HttpToolRequest](string(model.HTTP_REQUEST),
 		mcp.WithDescription("Make HTTP requests to external APIs"),
-		mcp.WithString("method",
-			mcp.Required(),
-			mcp.Description("HTTP method to use"),
-			mcp.Enum("GET", "POST", "PUT", "DELETE"),
-		),
-		mcp.WithString("url",
-			mcp.Required(),
-			mcp.Description("URL to send the request to"),
-			mcp.Pattern("^https?://.*"),
-		),
-		mcp.WithString("body",
-			mcp.Description("Request body (for POST/PUT)"),
-		),
-	), exampleHandler.HttpTool)
+		handler.WithOutputSchema[v1.HttpToolResponse](),
+	), handler.NewToolHandler(exampleHandler.HttpTool))
 	s.AddTool(
-		mcp.NewTool("notify"),
+		mcp.NewTool(string(model.NOTIFY)),
 		exampleHandler.SendNotification,
 	)
 
-	s.AddTool(mcp.NewTool(string(model.ADD),
+	s.AddTool(handler.NewTool[v1.AddToolRequest](string(model.ADD),
 		mcp.WithDescription("Adds two numbers"),
-		mcp.WithNumber("a",
-			mcp.Description("First number"),
-			mcp.Required(),
-		),
-		mcp.WithNumber("b",
-			mcp.Description("Second number"),
-			mcp.Required(),
-		),
-	), exampleHandler.AddTool)
-	s.AddTool(mcp.NewTool(
-		string(model.LONG_RUNNING_OPERATION),
-		mcp.WithDescription(
-			"Demonstrates a long running operation with progress updates",
-		),
-		mcp.WithNumber("duration",
-			mcp.Description("Duration of the operation in seconds"),
-			mcp.DefaultNumber(10),
-		),
-		mcp.WithNumber("steps",
-			mcp.Description("Number of steps in the operation"),
-			mcp.DefaultNumber(5),
-		),
-	), exampleHandler.LongRunningOperationTool)
-
-	s.AddTool(mcp.Tool{
-		Name:        string(model.SAMPLE_LLM),
-		Description: "Samples from an LLM using MCP's sampling feature",
-		InputSchema: mcp.ToolInputSchema{
-			Type: "object",
-			Properties: map[string]any{
-				"prompt": map[string]any{
-					"type":        "string",
-					"description": "The prompt to send to the LLM",
-				},
-				"maxTokens": map[string]any{
-					"type":        "number",
-					"description": "Maximum number of tokens to generate",
-					"default":     100,
-				},
-			},
-		},
-	}, exampleHandler.SampleLLMTool)
+		handler.WithOutputSchema[v1.AddToolResponse](),
+	), handler.NewToolHandler(exampleHandler.AddTool))
+	s.AddTool(handler.NewTool[v1.LongRunningOperationRequest](string(model.LONG_RUNNING_OPERATION),
+		mcp.WithDescription("Demonstrates a long running operation with progress updates"),
+	), handler.NewToolHandler(exampleHandler.LongRunningOperationTool))
+
+	s.AddTool(handler.NewTool[v1.SampleLLMToolRequest](string(model.SAMPLE_LLM),
+		mcp.WithDescription("Samples from an LLM using MCP's sampling feature"),
+		handler.WithOutputSchema[v1.SamplingResponse](),
+	), handler.NewToolHandler(exampleHandler.SampleLLMTool))
 	s.AddTool(mcp.NewTool(string(model.GET_TINY_IMAGE),
 		mcp.WithDescription("Returns the MCP_TINY_IMAGE"),
 	), exampleHandler.GetTinyImageTool)
 
 	s.AddNotificationHandler("notification", exampleHandler.Notification)
 
+	conf.SetDefault("audit.resource", false)
+	if auditHandler != nil && conf.GetBool("audit.resource") {
+		s.AddResourceTemplate(mcp.NewResourceTemplate(handler.AuditRecordsTemplate, "Audit Log",
+			mcp.WithTemplateDescription("Recent tool calls, newest first, filtered by the query parameters"),
+			mcp.WithTemplateMIMEType("application/json"),
+		), auditHandler.ReadRecords)
+		s.AddResourceTemplate(mcp.NewResourceTemplate(handler.AuditRecordTemplate, "Audit Record",
+			mcp.WithTemplateDescription("A tool call of the audit log"),
+			mcp.WithTemplateMIMEType("application/json"),
+		), auditHandler.ReadRecord)
+	}
+
 	return s
 }
 
-func setupSrv(conf *viper.Viper, logger *log.Logger) *servermcp.Server {
-	mcpServer := server.NewMCPServer(
-		conf.GetString("mcp.name"),
-		conf.GetString("mcp.version"),
-		server.WithResourceCapabilities(true, true),
+func setupSrv(conf *viper.Viper, logger *log.Logger, jwt *jwt.JWT, policy *middleware.Policy, limits *middleware.Limits, rateLimit *middleware.RateLimit, cancellation *handler.Cancellation, m *metrics.Metrics, tracing *telemetry.Tracing, redactor *redact.Redactor, audit service.AuditService, exampleHandler handler.ExampleHandler) *servermcp.Server {
+	serverOpts := []server.ServerOption{
+		// mcp-go answers resources/subscribe with "method not found", so
+		// subscriptions are not advertised and changes go out as list_changed.
+		server.WithResourceCapabilities(false, true),
 		server.WithPromptCapabilities(true),
 		server.WithToolCapabilities(true),
 		server.WithLogging(),
-		server.WithHooks(newHooks(logger)),
+		server.WithHooks(newHooks(logger, policy, cancellation, m, tracing, redactor, exampleHandler, conf.GetBool("data.resources.enable"))),
+		server.WithToolFilter(policy.ToolFilter),
+		server.WithToolFilter(limits.ToolFilter),
+	}
+	// The first middleware is the outermost, so tool timings include the others.
+	if m != nil {
+		serverOpts = append(serverOpts, server.WithToolHandlerMiddleware(m.Middleware))
+	}
+	serverOpts = append(serverOpts,
+		server.WithToolHandlerMiddleware(tracing.Middleware),
+		server.WithToolHandlerMiddleware(cancellation.Middleware),
+	)
+	if audit != nil {
+		serverOpts = append(serverOpts, server.WithToolHandlerMiddleware(auditTools(audit, policy, redactor)))
+	}
+	serverOpts = append(serverOpts,
+		server.WithToolHandlerMiddleware(toolErrors(logger)),
+		// Innermost, so toolErrors reports their rejections and timeouts as tool
+		// errors, denied calls take no rate limit token, and calls over a rate
+		// limit take no concurrency slot.
+		server.WithToolHandlerMiddleware(policy.Middleware),
+		server.WithToolHandlerMiddleware(rateLimit.Middleware),
+		server.WithToolHandlerMiddleware(limits.Middleware),
+	)
+	mcpServer := server.NewMCPServer(
+		conf.GetString("mcp.name"),
+		conf.GetString("mcp.version"),
+		serverOpts...,
 	)
+	mcpServer.AddNotificationHandler("notifications/cancelled", cancellation.Notification)
+	mcpServer.EnableSampling()
 
-	return servermcp.NewServer(logger,
+	opts := []servermcp.Option{
 		servermcp.WithMCPSrv(mcpServer),
-		// STDIO
-		servermcp.WithStdioSrv(true),
-		// SSE
-		servermcp.WithSSESrv(conf.GetString("mcp.sse_addr"), server.NewSSEServer(
-			mcpServer,
-			server.WithSSEEndpoint("/sse"),
-		)),
-		// StreamableHTTP
-		servermcp.WithStreamableHTTPSrv(conf.GetString("mcp.http_addr"), server.NewStreamableHTTPServer(
-			mcpServer,
-			server.WithEndpointPath("/mcp"),
-		)),
-	)
+		servermcp.WithOnStop(cancellation.CancelAll),
+		servermcp.WithPromptMiddleware(promptErrors(logger), policy.PromptMiddleware),
+		servermcp.WithResourceMiddleware(resourceErrors(logger), policy.ResourceMiddleware),
+	}
+	// STDIO
+	if conf.GetBool("mcp.transports.stdio.enable") {
+		opts = append(opts, servermcp.WithStdioSrv(true))
+	}
+	// SSE
+	if conf.GetBool("mcp.transports.sse.enable") {
+		httpSrv := &http.Server{}
+		sseSrv := server.NewSSEServer(mcpServer, append(sseOptions(conf), server.WithHTTPServer(httpSrv))...)
+		httpSrv.Handler = httpMiddleware(conf, logger, jwt)(metrics.WithTransport("sse")(sseSrv))
+		opts = append(opts, servermcp.WithSSESrv(conf.GetString("mcp.transports.sse.addr"), sseSrv))
+	}
+	// StreamableHTTP
+	if conf.GetBool("mcp.transports.http.enable") {
+		httpSrv := &http.Server{}
+		streamableSrv := server.NewStreamableHTTPServer(mcpServer, append(streamableHTTPOptions(conf), server.WithStreamableHTTPServer(httpSrv))...)
+		mux := http.NewServeMux()
+		mux.Handle(conf.GetString("mcp.transports.http.path"), streamableSrv)
+		httpSrv.Handler = httpMiddleware(conf, logger, jwt)(metrics.WithTransport("http")(mux))
+		opts = append(opts, servermcp.WithStreamableHTTPSrv(conf.GetString("mcp.transports.http.addr"), streamableSrv))
+	}
+
+	return servermcp.NewServer(logger, opts...)
 }
-func newHooks(logger *log.Logger) *server.Hooks {
+
+func sseOptions(conf *viper.Viper) []server.SSEOption {
+	conf.SetDefault("mcp.transports.sse.path", "/sse")
+	conf.SetDefault("mcp.transports.sse.message_path", "/message")
+
+	opts := []server.SSEOption{
+		server.WithSSEEndpoint(conf.GetString("mcp.transports.sse.path")),
+		server.WithMessageEndpoint(conf.GetString("mcp.transports.sse.message_path")),
+	}
+	if baseURL := conf.GetString("mcp.transports.sse.base_url"); baseURL != "" {
+		opts = append(opts, server.WithBaseURL(baseURL))
+	}
+	if basePath := conf.GetString("mcp.transports.sse.base_path"); basePath != "" {
+		opts = append(opts, server.WithStaticBasePath(basePath))
+	}
+	if interval := conf.GetDuration("mcp.transports.sse.keep_alive_interval"); interval > 0 {
+		opts = append(opts, server.WithKeepAliveInterval(interval))
+	}
+	return opts
+}
+
+func streamableHTTPOptions(conf *viper.Viper) []server.StreamableHTTPOption {
+	conf.SetDefault("mcp.transports.http.path", "/mcp")
+
+	opts := []server.StreamableHTTPOption{
+		server.WithEndpointPath(conf.GetString("mcp.transports.http.path")),
+		server.WithStateLess(conf.GetBool("mcp.transports.http.stateless")),
+	}
+	if interval := conf.GetDuration("mcp.transports.http.heartbeat_interval"); interval > 0 {
+		opts = append(opts, server.WithHeartbeatInterval(interval))
+	}
+	return opts
+}
+
+// httpMiddleware wraps the SSE and StreamableHTTP handlers. STDIO is a local
+// transport and is never authenticated.
+func httpMiddleware(conf *viper.Viper, logger *log.Logger, jwt *jwt.JWT) func(http.Handler) http.Handler {
+	if !conf.GetBool("mcp.auth.enable") {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+	return middleware.StrictAuth(jwt, logger)
+}
+
+func newHooks(logger *log.Logger, policy *middleware.Policy, cancellation *handler.Cancellation, m *metrics.Metrics, tracing *telemetry.Tracing, redactor *redact.Redactor, exampleHandler handler.ExampleHandler, dbResources bool) *server.Hooks {
 	hooks := &server.Hooks{}
+	if m != nil {
+		m.AddHooks(hooks)
+	}
+
+	hooks.AddAfterListPrompts(policy.AfterListPrompts)
+	if dbResources {
+		// Before the policy hook, so it filters the database resources too.
+		hooks.AddAfterListResources(exampleHandler.ListDBResources)
+	}
+	hooks.AddAfterListResources(policy.AfterListResources)
+	hooks.AddAfterListResourceTemplates(policy.AfterListResourceTemplates)
+	hooks.AddBeforeCallTool(cancellation.BeforeCallTool)
 
 	hooks.AddBeforeAny(func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
-		logger.Info("AddBeforeAny",
+		logger.WithContext(tracing.RequestContext(ctx, id)).Info("AddBeforeAny",
 			zap.Any("method", method),
 			zap.Any("id", id),
-			zap.Any("message", message),
+			redactor.Any("message", message, sensitiveArguments(ctx, message)...),
 		)
 	})
 
 	hooks.AddOnSuccess(func(ctx context.Context, id any, method mcp.MCPMethod, message any, result any) {
-		logger.Info("AddOnSuccess",
+		logger.WithContext(tracing.RequestContext(ctx, id)).Info("AddOnSuccess",
 			zap.Any("method", method),
 			zap.Any("id", id),
-			zap.Any("message", message),
-			zap.Any("result", result),
+			redactor.Any("message", message, sensitiveArguments(ctx, message)...),
+			redactor.Any("result", result),
 		)
 	})
 
 	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
-		logger.Info("AddOnError",
+		logger.WithContext(tracing.RequestContext(ctx, id)).Info("AddOnError",
 			zap.Any("method", method),
 			zap.Any("id", id),
-			zap.Any("message", message),
-			zap.Error(err),
+			redactor.Any("message", message, sensitiveArguments(ctx, message)...),
+			redactor.Error(err),
 		)
 	})
 
 	hooks.AddBeforeInitialize(func(ctx context.Context, id any, message *mcp.InitializeRequest) {
-		logger.Info("AddBeforeInitialize",
+		logger.WithContext(tracing.RequestContext(ctx, id)).Info("AddBeforeInitialize",
 			zap.Any("id", id),
-			zap.Any("message", message),
+			redactor.Any("message", message),
 		)
 	})
 
 	hooks.AddOnRequestInitialization(func(ctx context.Context, id any, message any) error {
-		logger.Info("AddOnRequestInitialization: ", zap.Any("id", id), zap.Any("message", message))
-		// authorization verification and other preprocessing tasks are performed.
+		logger.WithContext(ctx).Info("AddOnRequestInitialization: ", zap.Any("id", id), redactor.Any("message", message, sensitiveArguments(ctx, message)...))
+		// Bearer tokens are verified by middleware.StrictAuth before a request reaches the MCP server.
+		// middleware.Policy rejects calls the caller is not allowed to make from the handler middleware.
 		return nil
 	})
 	hooks.AddAfterInitialize(func(ctx context.Context, id any, message *mcp.InitializeRequest, result *mcp.InitializeResult) {
-		logger.Info("AddAfterInitialize",
+		logger.WithContext(tracing.RequestContext(ctx, id)).Info("AddAfterInitialize",
 			zap.Any("id", id),
-			zap.Any("message", message),
-			zap.Any("result", result),
+			redactor.Any("message", message),
+			redactor.Any("result", result),
 		)
 	})
 
 	hooks.AddAfterCallTool(func(ctx context.Context, id any, message *mcp.CallToolRequest, result *mcp.CallToolResult) {
-		logger.Info("AddAfterCallTool",
+		logger.WithContext(tracing.RequestContext(ctx, id)).Info("AddAfterCallTool",
 			zap.Any("id", id),
-			zap.Any("message", message),
-			zap.Any("result", result),
+			redactor.Any("message", message, sensitiveArguments(ctx, message)...),
+			redactor.Any("result", result),
 		)
 	})
 
 	hooks.AddBeforeCallTool(func(ctx context.Context, id any, message *mcp.CallToolRequest) {
-		logger.Info("AddBeforeCallTool",
+		logger.WithContext(tracing.RequestContext(ctx, id)).Info("AddBeforeCallTool",
 			zap.Any("id", id),
-			zap.Any("message", message),
+			redactor.Any("message", message, sensitiveArguments(ctx, message)...),
 		)
 	})
 
+	// Last, so the hooks above still see the request span.
+	tracing.AddHooks(hooks)
+
 	return hooks
 }
-func generateResources() []mcp.Resource {
-	resources := make([]mcp.Resource, 100)
-	for i := 0; i < 100; i++ {
-		uri := fmt.Sprintf("test://static/resource/%d", i+1)
-		if i%2 == 0 {
-			resources[i] = mcp.Resource{
-				URI:      uri,
-				Name:     fmt.Sprintf("Resource %d", i+1),
-				MIMEType: "text/plain",
-			}
-		} else {
-			resources[i] = mcp.Resource{
-				URI:      uri,
-				Name:     fmt.Sprintf("Resource %d", i+1),
-				MIMEType: "application/octet-stream",
-			}
+
+// sensitiveArguments returns the writeOnly arguments of the tool called by
+// message, so the logging hooks mask them.
+func sensitiveArguments(ctx context.Context, message any) []string {
+	var name string
+	switch message := message.(type) {
+	case *mcp.CallToolRequest:
+		name = message.Params.Name
+	case json.RawMessage:
+		var request struct {
+			Method mcp.MCPMethod `json:"method"`
+			Params struct {
+				Name string `json:"name"`
+			} `json:"params"`
+		}
+		if json.Unmarshal(message, &request) != nil || request.Method != mcp.MethodToolsCall {
+			return nil
 		}
+		name = request.Params.Name
+	default:
+		return nil
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return nil
+	}
+	tool := srv.GetTool(name)
+	if tool == nil {
+		return nil
 	}
-	return resources
+	return redact.SensitiveArguments(tool.Tool)
 }
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/internal/middleware"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/jwt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/metrics"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/redact"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/telemetry"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSrv builds the MCP server the way wire does, without the example
+// handler or any storage.
+func newTestSrv(t *testing.T, conf *viper.Viper) *servermcp.Server {
+	t.Helper()
+	conf.SetDefault("mcp.name", "test")
+	conf.SetDefault("mcp.version", "1.0.0")
+	logger := &log.Logger{Logger: zap.NewNop()}
+	tracing, cleanup := telemetry.NewTracing(conf, logger)
+	t.Cleanup(cleanup)
+	j, stop := jwt.NewJwt(conf, logger)
+	t.Cleanup(stop)
+	return setupSrv(conf, logger, j, middleware.NewPolicy(conf, logger), middleware.NewLimits(conf),
+		middleware.NewRateLimit(conf, logger, nil), handler.NewCancellation(), metrics.NewMetrics(conf), tracing,
+		redact.NewRedactor(conf), nil, nil)
+}
+
+// handle sends a JSON-RPC request to srv and decodes the result into v.
+func handle(t *testing.T, srv *servermcp.Server, method string, params any, v any) {
+	t.Helper()
+	message, err := json.Marshal(map[string]any{"jsonrpc": mcp.JSONRPC_VERSION, "id": 1, "method": method, "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, ok := srv.HandleMessage(context.Background(), message).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("%s failed", method)
+	}
+	data, err := json.Marshal(response.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResourceCapabilities(t *testing.T) {
+	srv := newTestSrv(t, viper.New())
+	var result mcp.InitializeResult
+	handle(t, srv, "initialize", map[string]any{
+		"protocolVersion": mcp.LATEST_PROTOCOL_VERSION,
+		"clientInfo":      map[string]any{"name": "test", "version": "1.0.0"},
+	}, &result)
+	resources := result.Capabilities.Resources
+	if resources == nil || resources.Subscribe || !resources.ListChanged {
+		t.Fatalf("resources capability = %+v, want list changes without subscriptions", resources)
+	}
+}
+
+// freeAddr returns a local address nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestTransportSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		sse  bool
+		http bool
+	}{
+		{name: "sse", sse: true},
+		{name: "http", http: true},
+		{name: "both", sse: true, http: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sseAddr, httpAddr := freeAddr(t), freeAddr(t)
+			conf := viper.New()
+			conf.Set("mcp.transports.sse.enable", tt.sse)
+			conf.Set("mcp.transports.sse.addr", sseAddr)
+			conf.Set("mcp.transports.http.enable", tt.http)
+			conf.Set("mcp.transports.http.addr", httpAddr)
+			srv := newTestSrv(t, conf)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- srv.Start(ctx) }()
+
+			transports := []struct {
+				addr    string
+				enabled bool
+			}{{sseAddr, tt.sse}, {httpAddr, tt.http}}
+			// Wait for the enabled transports first, so a disabled one has had
+			// the same time to come up.
+			for _, transport := range transports {
+				if transport.enabled && !listening(transport.addr, time.Second) {
+					t.Errorf("%s not listening", transport.addr)
+				}
+			}
+			for _, transport := range transports {
+				if !transport.enabled && listening(transport.addr, 0) {
+					t.Errorf("%s listening for a disabled transport", transport.addr)
+				}
+			}
+
+			cancel()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("Start = %v, want a clean stop", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("Start did not return after cancel")
+			}
+			for _, addr := range []string{sseAddr, httpAddr} {
+				if listening(addr, 0) {
+					t.Errorf("%s still listening after Stop", addr)
+				}
+			}
+		})
+	}
+}
+
+// listening reports whether addr accepts connections within wait.
+func listening(addr string, wait time.Duration) bool {
+	deadline := time.Now().Add(wait)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// startTestSrv starts srv and stops it when the test ends.
+func startTestSrv(t *testing.T, srv *servermcp.Server, addrs ...string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	for _, addr := range addrs {
+		if !listening(addr, time.Second) {
+			t.Fatalf("%s not listening", addr)
+		}
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	sseAddr, httpAddr := freeAddr(t), freeAddr(t)
+	conf := viper.New()
+	conf.Set("mcp.auth.enable", true)
+	conf.Set("security.jwt.key", "secret")
+	conf.Set("mcp.transports.sse.enable", true)
+	conf.Set("mcp.transports.sse.addr", sseAddr)
+	conf.Set("mcp.transports.http.enable", true)
+	conf.Set("mcp.transports.http.addr", httpAddr)
+	srv := newTestSrv(t, conf)
+	srv.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		claims, ok := jwt.ClaimsFromContext(ctx)
+		if !ok {
+			return mcp.NewToolResultText("anonymous"), nil
+		}
+		return mcp.NewToolResultText(claims.UserId), nil
+	})
+	startTestSrv(t, srv, sseAddr, httpAddr)
+
+	token, err := jwt.New([]byte("secret")).GenToken("alice", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := jwt.New([]byte("other")).GenToken("alice", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := jwt.New([]byte("secret")).GenToken("alice", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connect := map[string]func(headers map[string]string) (*client.Client, error){
+		"sse": func(headers map[string]string) (*client.Client, error) {
+			return client.NewSSEMCPClient("http://"+sseAddr+"/sse", client.WithHeaders(headers))
+		},
+		"http": func(headers map[string]string) (*client.Client, error) {
+			return client.NewStreamableHttpClient("http://"+httpAddr+"/mcp", transport.WithHTTPHeaders(headers))
+		},
+	}
+	tests := []struct {
+		name          string
+		authorization string
+		user          string
+	}{
+		{name: "no token"},
+		{name: "not a token", authorization: "Bearer nonsense"},
+		{name: "wrong key", authorization: "Bearer " + forged},
+		{name: "expired", authorization: "Bearer " + expired},
+		{name: "valid", authorization: "Bearer " + token, user: "alice"},
+		{name: "without the Bearer prefix", authorization: token, user: "alice"},
+	}
+	for transportName, newClient := range connect {
+		for _, tt := range tests {
+			t.Run(transportName+"/"+tt.name, func(t *testing.T) {
+				headers := map[string]string{}
+				if tt.authorization != "" {
+					headers["Authorization"] = tt.authorization
+				}
+				c, err := newClient(headers)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer c.Close()
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				user, err := whoami(ctx, c)
+				if tt.user == "" {
+					if err == nil || !strings.Contains(err.Error(), "401") {
+						t.Fatalf("got %q, %v, want a 401", user, err)
+					}
+					return
+				}
+				if err != nil || user != tt.user {
+					t.Fatalf("got %q, %v, want %q", user, err, tt.user)
+				}
+			})
+		}
+	}
+}
+
+// whoami connects c and calls the whoami tool.
+func whoami(ctx context.Context, c *client.Client) (string, error) {
+	if err := c.Start(ctx); err != nil {
+		return "", err
+	}
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{Name: "test", Version: "1.0.0"}
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		return "", err
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "whoami"
+	result, err := c.CallTool(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	return result.Content[0].(mcp.TextContent).Text, nil
+}
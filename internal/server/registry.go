@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// NewRegistry loads the catalog and the OpenAPI specs in mcp.openapi into
+// the MCP server. Unlike later catalog reloads, errors at startup are fatal.
+func NewRegistry(conf *viper.Viper, logger *log.Logger, s *servermcp.Server, catalog *handler.Catalog) *servermcp.Registry {
+	registry := servermcp.NewRegistry(s)
+	entries, err := catalog.Reload(registry)
+	if err != nil {
+		panic(fmt.Sprintf("mcp.tools.dir error: %s", err.Error()))
+	}
+	logger.Info("loaded catalog",
+		zap.String("dir", catalog.Dir()),
+		zap.Int("tools", len(entries.Tools)),
+		zap.Int("prompts", len(entries.Prompts)),
+		zap.Int("resources", len(entries.Resources)),
+	)
+
+	var sources []handler.OpenAPISource
+	if err := conf.UnmarshalKey("mcp.openapi", &sources); err != nil {
+		panic(fmt.Sprintf("mcp.openapi error: %s", err.Error()))
+	}
+	for _, source := range sources {
+		if source.Name == "" {
+			panic("mcp.openapi error: name is required")
+		}
+		tools, err := catalog.OpenAPI(source)
+		if err == nil {
+			err = registry.SetTools("openapi:"+source.Name, tools...)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("mcp.openapi error: %s: %s", source.Name, err.Error()))
+		}
+		logger.Info("loaded openapi spec",
+			zap.String("name", source.Name),
+			zap.String("spec", source.Spec),
+			zap.Int("tools", len(tools)),
+		)
+	}
+	return registry
+}
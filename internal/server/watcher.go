@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CatalogWatcher reloads the catalog when files in mcp.tools.dir change.
+// A reload that fails is logged and the previous catalog stays in place.
+type CatalogWatcher struct {
+	logger   *log.Logger
+	catalog  *handler.Catalog
+	registry *servermcp.Registry
+	debounce time.Duration
+
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher
+}
+
+// NewCatalogWatcher returns nil when mcp.tools.watch is off.
+func NewCatalogWatcher(
+	conf *viper.Viper,
+	logger *log.Logger,
+	catalog *handler.Catalog,
+	registry *servermcp.Registry,
+) *CatalogWatcher {
+	if !conf.GetBool("mcp.tools.watch") {
+		return nil
+	}
+	conf.SetDefault("mcp.tools.watch_debounce", 500*time.Millisecond)
+	return &CatalogWatcher{
+		logger:   logger,
+		catalog:  catalog,
+		registry: registry,
+		debounce: conf.GetDuration("mcp.tools.watch_debounce"),
+	}
+}
+
+func (w *CatalogWatcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.watcher = watcher
+	w.mu.Unlock()
+	if err := w.watchRoot(); err != nil {
+		w.Stop(ctx)
+		return err
+	}
+	w.logger.Sugar().Infof("Watching catalog %s...", w.catalog.Dir())
+
+	// Editors write a file in several steps, so reload once things settle.
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return w.Stop(ctx)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			root := w.catalog.Dir()
+			if event.Has(fsnotify.Create) {
+				switch {
+				case within(event.Name, root):
+					// The missing catalog directory, or one of its parents,
+					// was created.
+					_ = w.watchRoot()
+					timer.Reset(w.debounce)
+				case within(root, event.Name):
+					// Watch directories created after startup as well. Files
+					// may land in them before the watch is added, so reload.
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						_ = w.addDirs(event.Name)
+						timer.Reset(w.debounce)
+					}
+				}
+			}
+			if !within(root, event.Name) {
+				continue
+			}
+			if filepath.Clean(event.Name) == filepath.Clean(root) && (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)) {
+				// Wait for the catalog directory to come back.
+				_ = w.watchRoot()
+			}
+			if handler.IsCatalogFile(event.Name) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				timer.Reset(w.debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Error("catalog watcher error", zap.Error(err))
+		case <-timer.C:
+			w.reload()
+		}
+	}
+}
+
+func (w *CatalogWatcher) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watcher == nil {
+		return nil
+	}
+	err := w.watcher.Close()
+	w.watcher = nil
+	return err
+}
+
+func (w *CatalogWatcher) reload() {
+	entries, err := w.catalog.Reload(w.registry)
+	if err != nil {
+		w.logger.Error("catalog reload failed, keeping the previous catalog", zap.Error(err))
+		return
+	}
+	w.logger.Info("reloaded catalog",
+		zap.Int("tools", len(entries.Tools)),
+		zap.Int("prompts", len(entries.Prompts)),
+		zap.Int("resources", len(entries.Resources)),
+	)
+}
+
+// watchRoot watches the catalog directory. While it does not exist, like
+// Catalog.Load, it is treated as empty, and its nearest existing parent is
+// watched instead so the directory is picked up once it is created.
+func (w *CatalogWatcher) watchRoot() error {
+	root := filepath.Clean(w.catalog.Dir())
+	dir := root
+	for {
+		_, err := os.Stat(dir)
+		if err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if !errors.Is(err, fs.ErrNotExist) || parent == dir {
+			return err
+		}
+		dir = parent
+	}
+	if dir == root {
+		return w.addDirs(root)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watcher == nil {
+		return nil
+	}
+	w.logger.Sugar().Infof("Catalog %s does not exist, watching %s for it...", root, dir)
+	return w.watcher.Add(dir)
+}
+
+// within reports whether path is dir or below it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// addDirs watches root and every directory below it. fsnotify does not
+// watch recursively.
+func (w *CatalogWatcher) addDirs(root string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watcher == nil {
+		return nil
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path != root {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return w.watcher.Add(path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"github.com/go-nunu/nunu-layout-mcp/internal/handler"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	servermcp "github.com/go-nunu/nunu-layout-mcp/pkg/server/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const greetTool = `name: greet
+description: Greets someone
+static:
+  text: "Hello, {{ .name }}!"
+`
+
+func TestCatalogWatcher(t *testing.T) {
+	tests := []struct {
+		name string
+		// dir is mcp.tools.dir below the temp dir, and create is the part of
+		// it that exists when the watcher starts.
+		dir    string
+		create string
+	}{
+		{name: "existing dir", dir: "tools", create: "tools"},
+		{name: "missing dir", dir: "tools"},
+		{name: "missing parents", dir: "a/b/tools"},
+		{name: "missing below an existing parent", dir: "a/tools", create: "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			dir := filepath.Join(base, tt.dir)
+			if tt.create != "" {
+				if err := os.MkdirAll(filepath.Join(base, tt.create), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			logger := &log.Logger{Logger: zap.NewNop()}
+			conf := viper.New()
+			conf.Set("mcp.tools.dir", dir)
+			conf.Set("mcp.tools.watch", true)
+			conf.Set("mcp.tools.watch_debounce", 20*time.Millisecond)
+			h := handler.NewHandler(logger)
+			catalog := handler.NewCatalog(conf, h, nil, handler.NewExampleHandler(h, nil, nil))
+			srv := servermcp.NewServer(logger, servermcp.WithMCPSrv(server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))))
+
+			// Catalog.Load treats a missing dir as empty, and so does the watcher.
+			if entries, err := catalog.Load(dir); err != nil || len(entries.Tools) != 0 {
+				t.Fatalf("Load = %+v, %v, want an empty catalog", entries, err)
+			}
+			w := NewCatalogWatcher(conf, logger, catalog, servermcp.NewRegistry(srv))
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- w.Start(ctx) }()
+			defer func() {
+				cancel()
+				if err := <-done; err != nil {
+					t.Errorf("Start = %v", err)
+				}
+			}()
+			waitFor(t, done, func() bool { return watching(w) })
+
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "greet.yaml"), []byte(greetTool), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, done, func() bool { return srv.GetTool("greet") != nil })
+		})
+	}
+}
+
+func watching(w *CatalogWatcher) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.watcher != nil && len(w.watcher.WatchList()) > 0
+}
+
+// waitFor polls cond until it holds, failing when Start returns first.
+func waitFor(t *testing.T, done chan error, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		select {
+		case err := <-done:
+			done <- err
+			t.Fatalf("watcher stopped: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
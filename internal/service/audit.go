@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"time"
+)
+
+const (
+	defaultAuditListLimit = 50
+	maxAuditListLimit     = 500
+)
+
+// AuditService keeps the audit log of tool calls. Calls are queued and
+// written in batches, so the database is off the path of every call.
+type AuditService interface {
+	// Record queues a record. It never blocks: when the queue is full the
+	// record is dropped and logged.
+	Record(ctx context.Context, record *model.AuditRecord)
+	// Flush writes the queued records, one transaction per batch.
+	Flush(ctx context.Context) error
+	// Prune deletes the records older than audit.retention.
+	Prune(ctx context.Context) error
+	List(ctx context.Context, req *v1.ListAuditRequest) (*v1.ListAuditResponse, error)
+	Get(ctx context.Context, id uint) (*v1.AuditRecord, error)
+}
+
+// NewAuditService returns nil when audit.enable is off.
+func NewAuditService(
+	service *Service,
+	conf *viper.Viper,
+	auditRepo repository.AuditRepository,
+) AuditService {
+	if !conf.GetBool("audit.enable") {
+		return nil
+	}
+	conf.SetDefault("audit.buffer", 10000)
+	conf.SetDefault("audit.batch_size", 100)
+	return &auditService{
+		auditRepo: auditRepo,
+		queue:     make(chan *model.AuditRecord, conf.GetInt("audit.buffer")),
+		batchSize: max(conf.GetInt("audit.batch_size"), 1),
+		retention: conf.GetDuration("audit.retention"),
+		Service:   service,
+	}
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+	queue     chan *model.AuditRecord
+	batchSize int
+	retention time.Duration
+	*Service
+}
+
+func (s *auditService) Record(ctx context.Context, record *model.AuditRecord) {
+	select {
+	case s.queue <- record:
+	default:
+		s.logger.WithContext(ctx).Warn("audit queue full, record dropped",
+			zap.String("tool", record.Tool), zap.String("status", record.Status))
+	}
+}
+
+func (s *auditService) Flush(ctx context.Context) error {
+	for {
+		batch := s.next()
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+			return s.auditRepo.Create(ctx, batch)
+		})
+		if err != nil {
+			return fmt.Errorf("%d audit records lost: %w", len(batch), err)
+		}
+	}
+}
+
+// next takes up to batchSize records off the queue.
+func (s *auditService) next() []*model.AuditRecord {
+	var batch []*model.AuditRecord
+	for len(batch) < s.batchSize {
+		select {
+		case record := <-s.queue:
+			batch = append(batch, record)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+func (s *auditService) Prune(ctx context.Context) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	n, err := s.auditRepo.DeleteBefore(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.logger.WithContext(ctx).Info("audit records pruned", zap.Int64("count", n), zap.Duration("retention", s.retention))
+	}
+	return nil
+}
+
+func (s *auditService) List(ctx context.Context, req *v1.ListAuditRequest) (*v1.ListAuditResponse, error) {
+	switch req.Status {
+	case "", model.AuditStatusOK, model.AuditStatusError, model.AuditStatusCancelled, model.AuditStatusDenied:
+	default:
+		return nil, v1.Errorf(v1.KindValidation, "unknown status %q", req.Status)
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultAuditListLimit
+	}
+	if limit < 0 || limit > maxAuditListLimit {
+		return nil, v1.Errorf(v1.KindValidation, "limit must be between 1 and %d", maxAuditListLimit)
+	}
+	records, err := s.auditRepo.List(ctx, repository.AuditFilter{
+		SessionID: req.Session,
+		Caller:    req.Caller,
+		Tool:      req.Tool,
+		Status:    req.Status,
+		Since:     req.Since,
+		Until:     req.Until,
+		BeforeID:  req.Before,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := &v1.ListAuditResponse{Records: make([]*v1.AuditRecord, 0, len(records))}
+	for _, record := range records {
+		resp.Records = append(resp.Records, toAuditRecord(record))
+	}
+	if len(records) == limit {
+		resp.NextBefore = records[len(records)-1].ID
+	}
+	return resp, nil
+}
+
+func (s *auditService) Get(ctx context.Context, id uint) (*v1.AuditRecord, error) {
+	record, err := s.auditRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toAuditRecord(record), nil
+}
+
+func toAuditRecord(record *model.AuditRecord) *v1.AuditRecord {
+	r := &v1.AuditRecord{
+		ID:         record.ID,
+		Time:       record.CreatedAt,
+		Session:    record.SessionID,
+		Caller:     record.Caller,
+		Tool:       record.Tool,
+		Status:     record.Status,
+		Error:      record.Error,
+		DurationMs: record.Duration.Milliseconds(),
+	}
+	if json.Valid([]byte(record.Arguments)) {
+		r.Arguments = json.RawMessage(record.Arguments)
+	}
+	return r
+}
@@ -1,37 +1,70 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/model"
 	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/egress"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/telemetry"
 	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type ExampleService interface {
 	HttpTool(ctx context.Context, params *v1.HttpToolRequest) (*mcp.CallToolResult, error)
 	GetTinyImageTool(ctx context.Context) (*mcp.CallToolResult, error)
+
+	ListResources(ctx context.Context, req *v1.ListResourcesRequest) (*v1.ListResourcesResponse, error)
+	GetResource(ctx context.Context, id uint) (*v1.Resource, error)
+	CreateResource(ctx context.Context, req *v1.ResourceRequest) (*v1.Resource, error)
+	UpdateResource(ctx context.Context, id uint, req *v1.ResourceRequest) (*v1.Resource, error)
+	DeleteResource(ctx context.Context, id uint) error
 }
 
 func NewExampleService(
 	service *Service,
 	exampleRepo repository.ExampleRepository,
+	egress *egress.Policy,
 ) ExampleService {
 	return &exampleService{
 		exampleRepo: exampleRepo,
+		httpClient:  egress.Client(),
 		Service:     service,
 	}
 }
 
+const (
+	defaultHttpToolTimeout       = 30 * time.Second
+	maxHttpToolTimeout           = 2 * time.Minute
+	defaultHttpToolResponseBytes = 1 << 20
+	maxHttpToolResponseBytes     = 10 << 20
+	defaultResourceListLimit     = 50
+	maxResourceListLimit         = 500
+)
+
 type exampleService struct {
 	exampleRepo repository.ExampleRepository
+	httpClient  *http.Client
 	*Service
 }
 
 func (s *exampleService) GetTinyImageTool(ctx context.Context) (*mcp.CallToolResult, error) {
+	ctx, span := telemetry.Start(ctx, "ExampleService.GetTinyImageTool")
+	defer span.End()
 	img, _ := s.exampleRepo.FetchImage(ctx)
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
@@ -52,32 +85,254 @@ func (s *exampleService) GetTinyImageTool(ctx context.Context) (*mcp.CallToolRes
 	}, nil
 }
 
-func (s *exampleService) HttpTool(ctx context.Context, params *v1.HttpToolRequest) (*mcp.CallToolResult, error) {
+func (s *exampleService) HttpTool(ctx context.Context, params *v1.HttpToolRequest) (_ *mcp.CallToolResult, err error) {
+	ctx, span := telemetry.Start(ctx, "ExampleService.HttpTool")
+	defer func() { telemetry.End(span, err) }()
+
+	timeout := defaultHttpToolTimeout
+	if params.Timeout > 0 {
+		timeout = min(time.Duration(params.Timeout*float64(time.Second)), maxHttpToolTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	limit := int64(defaultHttpToolResponseBytes)
+	if params.MaxResponseBytes > 0 {
+		limit = min(params.MaxResponseBytes, maxHttpToolResponseBytes)
+	}
+
+	u, err := url.Parse(params.Url)
+	if err != nil {
+		return nil, v1.Errorf(v1.KindValidation, "invalid url: %w", err)
+	}
+	if len(params.Query) > 0 {
+		query := u.Query()
+		for k, v := range params.Query {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+	}
 
 	// Create and send request
-	var req *http.Request
-	var err error
-	if params.Body != "" {
-		req, err = http.NewRequest(params.Method, params.Url, strings.NewReader(params.Body))
-	} else {
-		req, err = http.NewRequest(params.Method, params.Url, nil)
+	var body io.Reader
+	if params.Body != "" && params.Method != http.MethodHead {
+		body = strings.NewReader(params.Body)
 	}
+	req, err := http.NewRequestWithContext(ctx, params.Method, u.String(), body)
 	if err != nil {
-		return mcp.NewToolResultErrorFromErr("unable to create request", err), nil
+		return nil, v1.Errorf(v1.KindValidation, "unable to create request: %w", err)
+	}
+	for k, v := range params.Headers {
+		req.Header.Set(k, v)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	req, httpSpan := telemetry.StartHTTP(req)
+	resp, err := s.httpClient.Do(req)
+	if err == nil {
+		httpSpan.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+	telemetry.End(httpSpan, err)
+	if errors.Is(err, egress.ErrDenied) {
+		s.logger.WithContext(ctx).Warn("http_request blocked", zap.String("url", params.Url), zap.Error(err))
+		return nil, v1.Errorf(v1.KindForbidden, "request blocked by egress policy: %w", err)
+	}
 	if err != nil {
-		return mcp.NewToolResultErrorFromErr("unable to execute request", err), nil
+		return nil, v1.Errorf(v1.KindUpstream, "unable to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Return response
-	respBody, err := io.ReadAll(resp.Body)
+	// Read one byte past the limit to know whether the body was cut off
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, v1.Errorf(v1.KindUpstream, "unable to read request response: %w", err)
+	}
+	truncated := int64(len(respBody)) > limit
+	if truncated {
+		respBody = respBody[:limit]
+	}
+
+	result := v1.HttpToolResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		Headers:     make(map[string]string, len(resp.Header)),
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        len(respBody),
+		Truncated:   truncated,
+	}
+	for k, v := range resp.Header {
+		result.Headers[k] = strings.Join(v, ", ")
+	}
+	meta, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return mcp.NewToolResultErrorFromErr("unable to read request response", err), nil
+		return nil, err
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Status: %d\nBody: %s", resp.StatusCode, string(respBody))), nil
+	content := []mcp.Content{mcp.NewTextContent(string(meta))}
+	if len(respBody) > 0 {
+		content = append(content, responseBodyContent(u.String(), result.ContentType, respBody, truncated))
+	}
+	return &mcp.CallToolResult{
+		Content:           content,
+		StructuredContent: result,
+	}, nil
+}
+
+// responseBodyContent returns text bodies as text, pretty-printing JSON,
+// and everything else as an embedded base64 resource.
+func responseBodyContent(uri, contentType string, body []byte, truncated bool) mcp.Content {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	text := body
+	if truncated {
+		text = trimPartialRune(body)
+	}
+	if !isTextMediaType(mediaType) && (mediaType != "" || !utf8.Valid(text)) {
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+		return mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+			URI:      uri,
+			MIMEType: mediaType,
+			Blob:     base64.StdEncoding.EncodeToString(body),
+		})
+	}
+
+	if !truncated && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		var buf bytes.Buffer
+		if json.Indent(&buf, body, "", "  ") == nil {
+			text = buf.Bytes()
+		}
+	}
+	if truncated {
+		return mcp.NewTextContent(fmt.Sprintf("%s\n...[truncated after %d bytes]", text, len(text)))
+	}
+	return mcp.NewTextContent(string(text))
+}
+
+// trimPartialRune drops the incomplete UTF-8 character that cutting body at
+// a byte limit may leave at its end.
+func trimPartialRune(body []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(body); i++ {
+		if utf8.RuneStart(body[len(body)-i]) {
+			if !utf8.FullRune(body[len(body)-i:]) {
+				return body[:len(body)-i]
+			}
+			break
+		}
+	}
+	return body
+}
+
+func isTextMediaType(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		mediaType == "application/x-www-form-urlencoded",
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return false
+}
+
+func (s *exampleService) ListResources(ctx context.Context, req *v1.ListResourcesRequest) (*v1.ListResourcesResponse, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultResourceListLimit
+	}
+	if limit < 0 || limit > maxResourceListLimit {
+		return nil, v1.Errorf(v1.KindValidation, "limit must be between 1 and %d", maxResourceListLimit)
+	}
+	resources, err := s.exampleRepo.ListResources(ctx, req.After, limit)
+	if err != nil {
+		return nil, err
+	}
+	resp := &v1.ListResourcesResponse{Resources: make([]*v1.Resource, 0, len(resources))}
+	for _, resource := range resources {
+		resp.Resources = append(resp.Resources, toResource(resource))
+	}
+	if len(resources) == limit {
+		resp.NextAfter = resources[len(resources)-1].ID
+	}
+	return resp, nil
+}
+
+func (s *exampleService) GetResource(ctx context.Context, id uint) (*v1.Resource, error) {
+	resource, err := s.exampleRepo.GetResource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toResource(resource), nil
+}
+
+func (s *exampleService) CreateResource(ctx context.Context, req *v1.ResourceRequest) (*v1.Resource, error) {
+	resource := &model.Resource{}
+	if err := setResource(resource, req); err != nil {
+		return nil, err
+	}
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		return s.exampleRepo.CreateResource(ctx, resource)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toResource(resource), nil
+}
+
+func (s *exampleService) UpdateResource(ctx context.Context, id uint, req *v1.ResourceRequest) (*v1.Resource, error) {
+	var resource *model.Resource
+	err := s.tm.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if resource, err = s.exampleRepo.GetResource(ctx, id); err != nil {
+			return err
+		}
+		if err := setResource(resource, req); err != nil {
+			return err
+		}
+		return s.exampleRepo.UpdateResource(ctx, resource)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toResource(resource), nil
+}
+
+func (s *exampleService) DeleteResource(ctx context.Context, id uint) error {
+	return s.exampleRepo.DeleteResource(ctx, id)
+}
+
+// setResource copies req into resource, replacing its content.
+func setResource(resource *model.Resource, req *v1.ResourceRequest) error {
+	if req.Name == "" {
+		return v1.Errorf(v1.KindValidation, "name is required")
+	}
+	if req.Text != "" && len(req.Blob) > 0 {
+		return v1.Errorf(v1.KindValidation, "text and blob are exclusive")
+	}
+	resource.Name = req.Name
+	resource.Description = req.Description
+	resource.MIMEType = req.MIMEType
+	resource.Text, resource.Blob = req.Text, req.Blob
+	if resource.MIMEType == "" {
+		resource.MIMEType = "text/plain"
+		if len(resource.Blob) > 0 {
+			resource.MIMEType = "application/octet-stream"
+		}
+	}
+	return nil
+}
+
+func toResource(resource *model.Resource) *v1.Resource {
+	return &v1.Resource{
+		ID:          resource.ID,
+		URI:         resource.URI,
+		Name:        resource.Name,
+		Description: resource.Description,
+		MIMEType:    resource.MIMEType,
+		Text:        resource.Text,
+		Blob:        resource.Blob,
+		CreatedAt:   resource.CreatedAt,
+		UpdatedAt:   resource.UpdatedAt,
+	}
 }
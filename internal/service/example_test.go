@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/egress"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTrimPartialRune(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "ascii", body: "abc", want: "abc"},
+		{name: "empty", body: "", want: ""},
+		{name: "complete 2-byte", body: "café", want: "café"},
+		{name: "split 2-byte", body: "caf\xc3", want: "caf"},
+		{name: "complete 3-byte", body: "日本", want: "日本"},
+		{name: "split 3-byte after 1", body: "日\xe6", want: "日"},
+		{name: "split 3-byte after 2", body: "日\xe6\x9c", want: "日"},
+		{name: "complete 4-byte", body: "a😀", want: "a😀"},
+		{name: "split 4-byte after 3", body: "a\xf0\x9f\x98", want: "a"},
+		{name: "invalid byte is kept", body: "a\xff", want: "a\xff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(trimPartialRune([]byte(tt.body))); got != tt.want {
+				t.Fatalf("trimPartialRune(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHttpToolTruncation(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		_, _ = w.Write([]byte("日本語のテキスト"))
+	}))
+	defer upstream.Close()
+
+	conf := viper.New()
+	conf.Set("egress.allow_private", true)
+	svc := NewExampleService(NewService(nil, &log.Logger{Logger: zap.NewNop()}, nil), nil, egress.NewPolicy(conf))
+
+	tests := []struct {
+		name        string
+		contentType string
+		maxBytes    int64
+		want        string
+	}{
+		{name: "cut inside a character", contentType: "text/plain; charset=utf-8", maxBytes: 4, want: "日\n...[truncated after 3 bytes]"},
+		{name: "cut at a boundary", contentType: "text/plain", maxBytes: 6, want: "日本\n...[truncated after 6 bytes]"},
+		{name: "no content type", maxBytes: 8, want: "日本\n...[truncated after 6 bytes]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := svc.HttpTool(context.Background(), &v1.HttpToolRequest{
+				Method:           http.MethodGet,
+				Url:              upstream.URL,
+				Query:            map[string]string{"type": tt.contentType},
+				MaxResponseBytes: tt.maxBytes,
+			})
+			if err != nil {
+				t.Fatalf("HttpTool: %v", err)
+			}
+			if len(result.Content) != 2 {
+				t.Fatalf("got %d contents, want metadata and body", len(result.Content))
+			}
+			text, ok := result.Content[1].(mcp.TextContent)
+			if !ok {
+				t.Fatalf("body = %T, want text", result.Content[1])
+			}
+			if !utf8.ValidString(text.Text) || text.Text != tt.want {
+				t.Fatalf("body = %q, want %q", text.Text, tt.want)
+			}
+			if !strings.Contains(result.Content[0].(mcp.TextContent).Text, `"truncated": true`) {
+				t.Fatalf("metadata = %s, want truncated", result.Content[0].(mcp.TextContent).Text)
+			}
+		})
+	}
+}
+
+// deadlineTransport records the deadline of each request's context.
+type deadlineTransport struct {
+	base     http.RoundTripper
+	deadline time.Time
+}
+
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.deadline, _ = req.Context().Deadline()
+	return t.base.RoundTrip(req)
+}
+
+func TestHttpToolTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte("slow"))
+	}))
+	defer upstream.Close()
+
+	conf := viper.New()
+	conf.Set("egress.allow_private", true)
+	svc := NewExampleService(NewService(nil, &log.Logger{Logger: zap.NewNop()}, nil), nil, egress.NewPolicy(conf)).(*exampleService)
+	if svc.httpClient.Timeout != 0 {
+		t.Fatalf("client timeout = %s, want none so the per-call timeout applies", svc.httpClient.Timeout)
+	}
+	transport := &deadlineTransport{base: svc.httpClient.Transport}
+	svc.httpClient.Transport = transport
+
+	tests := []struct {
+		name    string
+		timeout float64
+		want    time.Duration
+	}{
+		{name: "default", want: defaultHttpToolTimeout},
+		{name: "above the old client timeout", timeout: 60, want: 60 * time.Second},
+		{name: "capped", timeout: 600, want: maxHttpToolTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			result, err := svc.HttpTool(context.Background(), &v1.HttpToolRequest{
+				Method:  http.MethodGet,
+				Url:     upstream.URL,
+				Timeout: tt.timeout,
+			})
+			if err != nil || result.IsError {
+				t.Fatalf("HttpTool: %v %+v", err, result)
+			}
+			if got := transport.deadline.Sub(start); got < tt.want || got > tt.want+time.Second {
+				t.Fatalf("request deadline in %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
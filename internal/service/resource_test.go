@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/internal/migration"
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/egress"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"path/filepath"
+	"testing"
+)
+
+// newTestResourceService returns an ExampleService on a migrated SQLite
+// database.
+func newTestResourceService(t *testing.T) ExampleService {
+	t.Helper()
+	logger := &log.Logger{Logger: zap.NewNop()}
+	conf := viper.New()
+	conf.Set("data.resources.enable", true)
+	conf.Set("data.db.user.driver", "sqlite")
+	conf.Set("data.db.user.dsn", filepath.Join(t.TempDir(), "test.db"))
+	conf.Set("data.migrations.on_start", "up")
+	db, err := migration.NewCheckedDB(conf, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := repository.NewRepository(logger, db)
+	return NewExampleService(NewService(repository.NewTransaction(repo), logger, nil), repository.NewExampleRepository(repo), egress.NewPolicy(viper.New()))
+}
+
+func TestResourceCRUD(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestResourceService(t)
+
+	createTests := []struct {
+		name     string
+		req      v1.ResourceRequest
+		wantMIME string
+		wantErr  v1.ErrorKind
+	}{
+		{name: "text", req: v1.ResourceRequest{Name: "notes", Text: "hello"}, wantMIME: "text/plain"},
+		{name: "blob", req: v1.ResourceRequest{Name: "logo", Blob: []byte{0x89, 'P', 'N', 'G'}}, wantMIME: "application/octet-stream"},
+		{name: "mime type", req: v1.ResourceRequest{Name: "doc", MIMEType: "text/markdown", Text: "# doc"}, wantMIME: "text/markdown"},
+		{name: "no name", req: v1.ResourceRequest{Text: "hello"}, wantErr: v1.KindValidation},
+		{name: "text and blob", req: v1.ResourceRequest{Name: "both", Text: "hello", Blob: []byte("x")}, wantErr: v1.KindValidation},
+	}
+	var created []*v1.Resource
+	for _, tt := range createTests {
+		t.Run("create "+tt.name, func(t *testing.T) {
+			resource, err := svc.CreateResource(ctx, &tt.req)
+			if tt.wantErr != "" {
+				if v1.KindOf(err) != tt.wantErr {
+					t.Fatalf("CreateResource = %v, want a %s error", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateResource: %v", err)
+			}
+			if resource.ID == 0 || resource.URI != fmt.Sprintf("db://resource/%d", resource.ID) {
+				t.Fatalf("id %d, uri %q, want db://resource/<id>", resource.ID, resource.URI)
+			}
+			if resource.MIMEType != tt.wantMIME || resource.Text != tt.req.Text || string(resource.Blob) != string(tt.req.Blob) {
+				t.Fatalf("resource = %+v, want the request with MIME type %s", resource, tt.wantMIME)
+			}
+			got, err := svc.GetResource(ctx, resource.ID)
+			if err != nil {
+				t.Fatalf("GetResource: %v", err)
+			}
+			if got.URI != resource.URI || got.Name != tt.req.Name || got.Text != tt.req.Text || string(got.Blob) != string(tt.req.Blob) {
+				t.Fatalf("GetResource = %+v, want %+v", got, resource)
+			}
+			created = append(created, resource)
+		})
+	}
+	if len(created) != 3 {
+		t.Fatalf("created %d resources, want 3", len(created))
+	}
+
+	// Updating replaces the content, so the blob can become text.
+	blob := created[1]
+	updated, err := svc.UpdateResource(ctx, blob.ID, &v1.ResourceRequest{Name: "logo", Description: "as text", Text: "PNG"})
+	if err != nil {
+		t.Fatalf("UpdateResource: %v", err)
+	}
+	if updated.URI != blob.URI || updated.Text != "PNG" || len(updated.Blob) != 0 || updated.MIMEType != "text/plain" || updated.Description != "as text" {
+		t.Fatalf("UpdateResource = %+v, want the text PNG at %s", updated, blob.URI)
+	}
+	if got, _ := svc.GetResource(ctx, blob.ID); got == nil || got.Text != "PNG" || len(got.Blob) != 0 {
+		t.Fatalf("GetResource after update = %+v, want the text PNG", got)
+	}
+	if _, err := svc.UpdateResource(ctx, blob.ID, &v1.ResourceRequest{Text: "no name"}); v1.KindOf(err) != v1.KindValidation {
+		t.Fatalf("UpdateResource without a name = %v, want a validation error", err)
+	}
+
+	if err := svc.DeleteResource(ctx, blob.ID); err != nil {
+		t.Fatalf("DeleteResource: %v", err)
+	}
+	missing := []struct {
+		name string
+		call func() error
+	}{
+		{name: "get", call: func() error { _, err := svc.GetResource(ctx, blob.ID); return err }},
+		{name: "update", call: func() error {
+			_, err := svc.UpdateResource(ctx, blob.ID, &v1.ResourceRequest{Name: "logo"})
+			return err
+		}},
+		{name: "delete", call: func() error { return svc.DeleteResource(ctx, blob.ID) }},
+	}
+	for _, tt := range missing {
+		t.Run(tt.name+" deleted", func(t *testing.T) {
+			if err := tt.call(); v1.KindOf(err) != v1.KindNotFound {
+				t.Fatalf("%s = %v, want a not found error", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestListResources(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestResourceService(t)
+	var ids []uint
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		resource, err := svc.CreateResource(ctx, &v1.ResourceRequest{Name: name, Text: "content of " + name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, resource.ID)
+	}
+	if err := svc.DeleteResource(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		wantPages [][]string
+		wantErr   v1.ErrorKind
+	}{
+		{name: "default limit", wantPages: [][]string{{"a", "c", "d", "e"}}},
+		{name: "limit 2", limit: 2, wantPages: [][]string{{"a", "c"}, {"d", "e"}, {}}},
+		{name: "limit 3", limit: 3, wantPages: [][]string{{"a", "c", "d"}, {"e"}}},
+		{name: "negative limit", limit: -1, wantErr: v1.KindValidation},
+		{name: "limit too high", limit: maxResourceListLimit + 1, wantErr: v1.KindValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				pages [][]string
+				after uint
+			)
+			for {
+				resp, err := svc.ListResources(ctx, &v1.ListResourcesRequest{After: after, Limit: tt.limit})
+				if tt.wantErr != "" {
+					if v1.KindOf(err) != tt.wantErr {
+						t.Fatalf("ListResources = %v, want a %s error", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("ListResources: %v", err)
+				}
+				page := []string{}
+				for _, resource := range resp.Resources {
+					if resource.Text != "" || resource.URI == "" {
+						t.Fatalf("listed %+v, want the URI without the content", resource)
+					}
+					page = append(page, resource.Name)
+				}
+				pages = append(pages, page)
+				if resp.NextAfter == 0 {
+					break
+				}
+				after = resp.NextAfter
+			}
+			if fmt.Sprint(pages) != fmt.Sprint(tt.wantPages) {
+				t.Fatalf("pages = %v, want %v", pages, tt.wantPages)
+			}
+		})
+	}
+}
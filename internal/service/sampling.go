@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/telemetry"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"time"
+)
+
+var ErrSamplingUnsupported = &v1.Error{Kind: v1.KindUpstream, Message: "the connected client does not support sampling"}
+
+const defaultSamplingMaxTokens = 100
+
+// SamplingService asks the connected client to run its LLM on behalf of a
+// tool, so tools can build agent-style workflows without holding model
+// credentials themselves.
+type SamplingService interface {
+	CreateMessage(ctx context.Context, req *v1.SamplingRequest) (*v1.SamplingResponse, error)
+	// Ask is CreateMessage with a single user message.
+	Ask(ctx context.Context, prompt string, maxTokens int) (string, error)
+}
+
+func NewSamplingService(
+	service *Service,
+	conf *viper.Viper,
+) SamplingService {
+	conf.SetDefault("mcp.sampling.timeout", 60*time.Second)
+	return &samplingService{
+		timeout: conf.GetDuration("mcp.sampling.timeout"),
+		Service: service,
+	}
+}
+
+type samplingService struct {
+	timeout time.Duration
+	*Service
+}
+
+func (s *samplingService) CreateMessage(ctx context.Context, req *v1.SamplingRequest) (_ *v1.SamplingResponse, err error) {
+	ctx, span := telemetry.Start(ctx, "SamplingService.CreateMessage")
+	defer func() { telemetry.End(span, err) }()
+
+	srv := server.ServerFromContext(ctx)
+	if srv == nil || !supportsSampling(ctx) {
+		return nil, ErrSamplingUnsupported
+	}
+	if len(req.Messages) == 0 {
+		return nil, v1.Errorf(v1.KindValidation, "sampling request has no messages")
+	}
+
+	timeout := s.timeout
+	if req.Timeout > 0 {
+		timeout = min(req.Timeout, s.timeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := srv.RequestSampling(ctx, mcp.CreateMessageRequest{
+		CreateMessageParams: createMessageParams(req),
+	})
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, v1.Errorf(v1.KindUpstream, "sampling timed out after %s: %w", timeout, ctx.Err())
+		}
+		return nil, v1.Errorf(v1.KindUpstream, "sampling failed: %w", err)
+	}
+	s.logger.WithContext(ctx).Debug("sampling completed",
+		zap.String("model", result.Model),
+		zap.String("stopReason", result.StopReason),
+	)
+
+	text, err := samplingText(result.Content)
+	if err != nil {
+		return nil, v1.Errorf(v1.KindUpstream, "sampling failed: %w", err)
+	}
+	return &v1.SamplingResponse{
+		Model:      result.Model,
+		StopReason: result.StopReason,
+		Role:       string(result.Role),
+		Text:       text,
+	}, nil
+}
+
+func (s *samplingService) Ask(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	resp, err := s.CreateMessage(ctx, &v1.SamplingRequest{
+		Messages:  []v1.SamplingMessage{{Role: string(mcp.RoleUser), Text: prompt}},
+		MaxTokens: maxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// supportsSampling reports whether the client declared the sampling
+// capability and its transport can carry server-to-client requests.
+func supportsSampling(ctx context.Context) bool {
+	if server.InProcessSamplingHandlerFromContext(ctx) != nil {
+		return true
+	}
+	session := server.ClientSessionFromContext(ctx)
+	if _, ok := session.(server.SessionWithSampling); !ok {
+		return false
+	}
+	if info, ok := session.(server.SessionWithClientInfo); ok {
+		return info.GetClientCapabilities().Sampling != nil
+	}
+	return true
+}
+
+func createMessageParams(req *v1.SamplingRequest) mcp.CreateMessageParams {
+	params := mcp.CreateMessageParams{
+		Messages:      make([]mcp.SamplingMessage, 0, len(req.Messages)),
+		SystemPrompt:  req.SystemPrompt,
+		MaxTokens:     req.MaxTokens,
+		StopSequences: req.StopSequences,
+	}
+	// mcp-go sends temperature with omitempty, so an explicit 0 still
+	// reaches the client as "not set".
+	if req.Temperature != nil {
+		params.Temperature = *req.Temperature
+	}
+	if params.MaxTokens <= 0 {
+		params.MaxTokens = defaultSamplingMaxTokens
+	}
+	for _, m := range req.Messages {
+		role := mcp.RoleUser
+		if m.Role == string(mcp.RoleAssistant) {
+			role = mcp.RoleAssistant
+		}
+		params.Messages = append(params.Messages, mcp.SamplingMessage{
+			Role:    role,
+			Content: mcp.NewTextContent(m.Text),
+		})
+	}
+	if len(req.ModelHints) > 0 || req.CostPriority > 0 || req.SpeedPriority > 0 || req.IntelligencePriority > 0 {
+		prefs := &mcp.ModelPreferences{
+			CostPriority:         req.CostPriority,
+			SpeedPriority:        req.SpeedPriority,
+			IntelligencePriority: req.IntelligencePriority,
+		}
+		for _, hint := range req.ModelHints {
+			prefs.Hints = append(prefs.Hints, mcp.ModelHint{Name: hint})
+		}
+		params.ModelPreferences = prefs
+	}
+	return params
+}
+
+// samplingText extracts the text of a sampling result. Results decoded from
+// the wire hold a plain map, in-process results hold typed content.
+func samplingText(content any) (string, error) {
+	if m, ok := content.(map[string]any); ok {
+		parsed, err := mcp.ParseContent(m)
+		if err != nil {
+			return "", fmt.Errorf("parse sampling content: %w", err)
+		}
+		content = parsed
+	}
+	switch c := content.(type) {
+	case mcp.TextContent:
+		return c.Text, nil
+	case *mcp.TextContent:
+		return c.Text, nil
+	default:
+		return "", fmt.Errorf("unsupported sampling content type %T", content)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	v1 "github.com/go-nunu/nunu-layout-mcp/api/v1"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"reflect"
+	"testing"
+)
+
+func TestCreateMessageParams(t *testing.T) {
+	warm := 0.7
+	tests := []struct {
+		name string
+		req  *v1.SamplingRequest
+		want map[string]any
+	}{
+		{
+			name: "defaults",
+			req:  &v1.SamplingRequest{Messages: []v1.SamplingMessage{{Role: "user", Text: "hi"}}},
+			want: map[string]any{
+				"messages":  []any{map[string]any{"role": "user", "content": map[string]any{"type": "text", "text": "hi"}}},
+				"maxTokens": 100.0,
+			},
+		},
+		{
+			name: "temperature and preferences",
+			req: &v1.SamplingRequest{
+				Messages:      []v1.SamplingMessage{{Role: "assistant", Text: "a"}, {Role: "system", Text: "b"}},
+				SystemPrompt:  "be brief",
+				MaxTokens:     20,
+				Temperature:   &warm,
+				StopSequences: []string{"\n"},
+				ModelHints:    []string{"claude"},
+				CostPriority:  0.5,
+			},
+			want: map[string]any{
+				"messages": []any{
+					map[string]any{"role": "assistant", "content": map[string]any{"type": "text", "text": "a"}},
+					// Unknown roles are sent as user messages.
+					map[string]any{"role": "user", "content": map[string]any{"type": "text", "text": "b"}},
+				},
+				"systemPrompt":     "be brief",
+				"maxTokens":        20.0,
+				"temperature":      0.7,
+				"stopSequences":    []any{"\n"},
+				"modelPreferences": map[string]any{"hints": []any{map[string]any{"name": "claude"}}, "costPriority": 0.5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(createMessageParams(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("params = %s\nwant %v", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateMessageParamsTemperature(t *testing.T) {
+	zero := 0.0
+	if params := createMessageParams(&v1.SamplingRequest{}); params.Temperature != 0 {
+		t.Errorf("unset temperature = %v, want 0 (omitted)", params.Temperature)
+	}
+	if params := createMessageParams(&v1.SamplingRequest{Temperature: &zero}); params.Temperature != 0 {
+		t.Errorf("zero temperature = %v, want 0", params.Temperature)
+	}
+}
+
+func TestCreateMessageUnsupported(t *testing.T) {
+	svc := NewSamplingService(NewService(nil, &log.Logger{Logger: zap.NewNop()}, nil), viper.New())
+	_, err := svc.CreateMessage(context.Background(), &v1.SamplingRequest{
+		Messages: []v1.SamplingMessage{{Role: string(mcp.RoleUser), Text: "hi"}},
+	})
+	if err != ErrSamplingUnsupported || v1.KindOf(err) != v1.KindUpstream {
+		t.Fatalf("err = %v, want ErrSamplingUnsupported", err)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"github.com/go-nunu/nunu-layout-mcp/internal/repository"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/sid"
 )
@@ -9,11 +10,11 @@ type Service struct {
 	logger *log.Logger
 	sid    *sid.Sid
 	//jwt    *jwt.JWT
-	//tm     repository.Transaction
+	tm repository.Transaction
 }
 
 func NewService(
-	//tm repository.Transaction,
+	tm repository.Transaction,
 	logger *log.Logger,
 	sid *sid.Sid,
 	// jwt *jwt.JWT,
@@ -22,6 +23,6 @@ func NewService(
 		logger: logger,
 		sid:    sid,
 		//jwt:    jwt,
-		//tm:     tm,
+		tm: tm,
 	}
 }
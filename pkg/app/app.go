@@ -44,15 +44,18 @@ func (a *App) Run(ctx context.Context) error {
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 
+	// A server whose Start fails stops the app, so Run returns its error
+	// instead of running without it.
+	errs := make(chan error, len(a.servers))
 	for _, srv := range a.servers {
 		go func(srv server.Server) {
-			err := srv.Start(ctx)
-			if err != nil {
-				log.Printf("Server start err: %v", err)
+			if err := srv.Start(ctx); err != nil {
+				errs <- err
 			}
 		}(srv)
 	}
 
+	var err error
 	select {
 	case <-signals:
 		// Received termination signal
@@ -60,15 +63,16 @@ func (a *App) Run(ctx context.Context) error {
 	case <-ctx.Done():
 		// Context canceled
 		log.Println("Context canceled")
+	case err = <-errs:
+		log.Printf("Server start err: %v", err)
 	}
 
 	// Gracefully stop the servers
 	for _, srv := range a.servers {
-		err := srv.Stop(ctx)
-		if err != nil {
+		if err := srv.Stop(ctx); err != nil {
 			log.Printf("Server stop err: %v", err)
 		}
 	}
 
-	return nil
+	return err
 }
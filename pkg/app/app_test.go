@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/server"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer blocks in Start until ctx is done, unless it fails right away.
+type fakeServer struct {
+	startErr error
+	stopped  atomic.Bool
+}
+
+func (s *fakeServer) Start(ctx context.Context) error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (s *fakeServer) Stop(ctx context.Context) error {
+	s.stopped.Store(true)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	errBind := errors.New("listen tcp :8000: bind: address already in use")
+	tests := []struct {
+		name    string
+		servers []*fakeServer
+		cancel  bool
+		wantErr error
+	}{
+		{name: "start fails", servers: []*fakeServer{{}, {startErr: errBind}}, wantErr: errBind},
+		{name: "context canceled", servers: []*fakeServer{{}, {}}, cancel: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+			var servers []server.Server
+			for _, srv := range tt.servers {
+				servers = append(servers, srv)
+			}
+			app := NewApp(WithServer(servers...))
+
+			done := make(chan error, 1)
+			go func() { done <- app.Run(ctx) }()
+			select {
+			case err := <-done:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Run = %v, want %v", err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Run did not return")
+			}
+			for i, srv := range tt.servers {
+				if !srv.stopped.Load() {
+					t.Errorf("server %d not stopped", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrDenied = errors.New("egress denied")
+
+// blockedPrefixes are never reachable unless listed in allow_cidrs or
+// allow_private is set: loopback, private, link-local (incl. cloud metadata),
+// CGNAT, multicast and reserved ranges. IPv6 ranges that embed an IPv4
+// address (IPv4-compatible, 6to4, NAT64 and Teredo) are blocked as a whole,
+// as they can reach the IPv4 ranges above through a relay or translator.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/96"), // unspecified, loopback and IPv4-compatible
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001::/32"), // Teredo
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+type Policy struct {
+	allowHosts   []string
+	denyHosts    []string
+	allowCIDRs   []netip.Prefix
+	denyCIDRs    []netip.Prefix
+	allowPrivate bool
+	maxRedirects int
+}
+
+func NewPolicy(conf *viper.Viper) *Policy {
+	conf.SetDefault("egress.max_redirects", 5)
+
+	p := &Policy{
+		allowPrivate: conf.GetBool("egress.allow_private"),
+		maxRedirects: conf.GetInt("egress.max_redirects"),
+	}
+	var err error
+	if p.allowHosts, err = normalizeHosts(conf.GetStringSlice("egress.allow_hosts")); err != nil {
+		panic(fmt.Sprintf("egress.allow_hosts error: %s", err.Error()))
+	}
+	if p.denyHosts, err = normalizeHosts(conf.GetStringSlice("egress.deny_hosts")); err != nil {
+		panic(fmt.Sprintf("egress.deny_hosts error: %s", err.Error()))
+	}
+	if p.allowCIDRs, err = parsePrefixes(conf.GetStringSlice("egress.allow_cidrs")); err != nil {
+		panic(fmt.Sprintf("egress.allow_cidrs error: %s", err.Error()))
+	}
+	if p.denyCIDRs, err = parsePrefixes(conf.GetStringSlice("egress.deny_cidrs")); err != nil {
+		panic(fmt.Sprintf("egress.deny_cidrs error: %s", err.Error()))
+	}
+	return p
+}
+
+// CheckURL validates the scheme and host name of u. The resolved address is
+// checked separately when the connection is dialed.
+func (p *Policy) CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrDenied, u.Scheme)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrDenied)
+	}
+	if matchHost(p.denyHosts, host) {
+		return fmt.Errorf("%w: host %q is denied", ErrDenied, host)
+	}
+	if len(p.allowHosts) > 0 && !matchHost(p.allowHosts, host) {
+		return fmt.Errorf("%w: host %q is not in the allow list", ErrDenied, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.CheckAddr(addr)
+	}
+	return nil
+}
+
+// CheckAddr validates an IP address against the CIDR lists and the
+// built-in blocklist.
+func (p *Policy) CheckAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if containsAddr(p.denyCIDRs, addr) {
+		return fmt.Errorf("%w: address %s is denied", ErrDenied, addr)
+	}
+	if containsAddr(p.allowCIDRs, addr) {
+		return nil
+	}
+	if len(p.allowCIDRs) > 0 {
+		return fmt.Errorf("%w: address %s is not in the allow list", ErrDenied, addr)
+	}
+	if !p.allowPrivate && containsAddr(blockedPrefixes, addr) {
+		return fmt.Errorf("%w: address %s is in a blocked range", ErrDenied, addr)
+	}
+	return nil
+}
+
+// Client returns an http.Client whose every request, including the ones
+// made for redirects, goes through the policy. It has no overall timeout;
+// callers bound each request with its context.
+func (p *Policy) Client() *http.Client {
+	return &http.Client{
+		Transport: p.Transport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= p.maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", p.maxRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+// Transport checks the URL of every request and the address actually being
+// connected to, after DNS resolution, so rebinding a name to an internal
+// address between the two checks does not help.
+func (p *Policy) Transport() http.RoundTripper {
+	return &transport{policy: p, base: p.baseTransport()}
+}
+
+type transport struct {
+	policy *Policy
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.policy.CheckURL(req.URL); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (p *Policy) baseTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrDenied, err)
+			}
+			return p.CheckAddr(addrPort.Addr())
+		},
+	}
+	return &http.Transport{
+		// Proxies are ignored on purpose: they would dial on our behalf and bypass the address check.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// normalizeHosts lowercases the patterns and rejects wildcards other than a
+// leading "*.", such as "*example.com", which would also match
+// evilexample.com.
+func normalizeHosts(hosts []string) ([]string, error) {
+	out := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		h = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(h)), ".")
+		if h == "" {
+			continue
+		}
+		if strings.Contains(strings.TrimPrefix(h, "*."), "*") || h == "*." {
+			return nil, fmt.Errorf("invalid host pattern %q, wildcards must be a leading \"*.\"", h)
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// matchHost supports exact names and "*.example.com" wildcards, which match
+// any subdomain but not example.com itself.
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
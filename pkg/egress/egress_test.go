@@ -0,0 +1,126 @@
+package egress
+
+import (
+	"errors"
+	"github.com/spf13/viper"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestCheckAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{addr: "93.184.216.34"},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946"},
+		{addr: "127.0.0.1", blocked: true},
+		{addr: "169.254.169.254", blocked: true},
+		{addr: "10.1.2.3", blocked: true},
+		{addr: "::ffff:127.0.0.1", blocked: true},
+		{addr: "::", blocked: true},
+		{addr: "::1", blocked: true},
+		{addr: "::a9fe:a9fe", blocked: true},                          // IPv4-compatible 169.254.169.254
+		{addr: "::5db8:d822", blocked: true},                          // IPv4-compatible, even for a public address
+		{addr: "2002:7f00:1::1", blocked: true},                       // 6to4 127.0.0.1
+		{addr: "2002:a9fe:a9fe::", blocked: true},                     // 6to4 169.254.169.254
+		{addr: "64:ff9b::a9fe:a9fe", blocked: true},                   // NAT64 169.254.169.254
+		{addr: "64:ff9b:1::a00:1", blocked: true},                     // local-use NAT64 10.0.0.1
+		{addr: "2001:0:4136:e378:8000:63bf:3fff:fdd2", blocked: true}, // Teredo
+		{addr: "fd00:ec2::254", blocked: true},                        // unique local
+		{addr: "fe80::1", blocked: true},                              // link-local
+		{addr: "ff02::1", blocked: true},                              // multicast
+		{addr: "2001:db8::1"},                                         // documentation, not an IPv4 wrapper
+		{addr: "2003:7f00:1::1"},                                      // next to 6to4
+		{addr: "64:ff9b:2::a9fe:a9fe"},                                // next to NAT64
+		{addr: "0:0:0:1::7f00:1"},                                     // next to IPv4-compatible
+		{addr: "2001:1::1"},                                           // next to Teredo
+	}
+	p := NewPolicy(viper.New())
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := p.CheckAddr(netip.MustParseAddr(tt.addr))
+			if tt.blocked != errors.Is(err, ErrDenied) {
+				t.Fatalf("CheckAddr(%s) = %v, want blocked %v", tt.addr, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestCheckAddrAllowPrivate(t *testing.T) {
+	conf := viper.New()
+	conf.Set("egress.allow_private", true)
+	conf.Set("egress.deny_cidrs", []string{"169.254.0.0/16"})
+	p := NewPolicy(conf)
+	for _, addr := range []string{"127.0.0.1", "2002:7f00:1::1", "64:ff9b::a00:1"} {
+		if err := p.CheckAddr(netip.MustParseAddr(addr)); err != nil {
+			t.Errorf("CheckAddr(%s) = %v, want allowed with allow_private", addr, err)
+		}
+	}
+	if err := p.CheckAddr(netip.MustParseAddr("169.254.169.254")); !errors.Is(err, ErrDenied) {
+		t.Errorf("CheckAddr(169.254.169.254) = %v, want denied by deny_cidrs", err)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	p := NewPolicy(viper.New())
+	for _, raw := range []string{
+		"http://[2002:a9fe:a9fe::]/latest/meta-data",
+		"http://[64:ff9b::7f00:1]:8080/",
+		"http://[::7f00:1]/",
+		"ftp://example.com/",
+	} {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.CheckURL(u); !errors.Is(err, ErrDenied) {
+			t.Errorf("CheckURL(%s) = %v, want denied", raw, err)
+		}
+	}
+}
+
+func TestCheckURLHosts(t *testing.T) {
+	conf := viper.New()
+	conf.Set("egress.allow_hosts", []string{"*.example.com", "api.test."})
+	conf.Set("egress.deny_hosts", []string{"admin.example.com"})
+	p := NewPolicy(conf)
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{host: "www.example.com", allowed: true},
+		{host: "a.b.example.com", allowed: true},
+		{host: "WWW.Example.com.", allowed: true},
+		{host: "api.test", allowed: true},
+		{host: "example.com"},          // the wildcard only covers subdomains
+		{host: "evilexample.com"},      // no dot boundary
+		{host: "example.com.evil.net"}, // suffix of a different name
+		{host: "admin.example.com"},    // denied
+		{host: "sub.api.test"},         // exact names have no subdomains
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := p.CheckURL(&url.URL{Scheme: "https", Host: tt.host})
+			if tt.allowed != (err == nil) {
+				t.Fatalf("CheckURL(%s) = %v, want allowed %v", tt.host, err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestInvalidHostPatterns(t *testing.T) {
+	for _, pattern := range []string{"*example.com", "api.*.example.com", "*", "*."} {
+		t.Run(pattern, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("NewPolicy accepted %q", pattern)
+				}
+			}()
+			conf := viper.New()
+			conf.Set("egress.deny_hosts", []string{pattern})
+			NewPolicy(conf)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"go.uber.org/zap"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval limits how often an unknown kid may trigger a refresh,
+// counted from the last attempt so a failing provider is not hammered.
+const minRefreshInterval = 30 * time.Second
+
+// Fetcher loads a raw JWKS document.
+type Fetcher interface {
+	Fetch(ctx context.Context) ([]byte, error)
+}
+
+// FetcherFunc adapts a function to a Fetcher.
+type FetcherFunc func(ctx context.Context) ([]byte, error)
+
+func (f FetcherFunc) Fetch(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+// NewHTTPFetcher fetches the JWKS document from url. A nil client uses a
+// client with a 10 second timeout.
+func NewHTTPFetcher(url string, client *http.Client) Fetcher {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return FetcherFunc(func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %d fetching %s", resp.StatusCode, url)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	})
+}
+
+// NewFileFetcher reads the JWKS document from a local file.
+func NewFileFetcher(path string) Fetcher {
+	return FetcherFunc(func(ctx context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	alg string
+	key any
+}
+
+// JWKS caches the verification keys of a JSON Web Key Set and refreshes
+// them in the background so rotated keys are picked up.
+type JWKS struct {
+	fetcher Fetcher
+	logger  *log.Logger
+
+	mu          sync.RWMutex
+	keys        map[string]publicKey
+	lastAttempt time.Time
+
+	refreshMu sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewJWKS loads the key set once and then refreshes it every interval.
+// An interval <= 0 disables background refresh.
+func NewJWKS(fetcher Fetcher, interval time.Duration, logger *log.Logger) (*JWKS, error) {
+	k := &JWKS{
+		fetcher: fetcher,
+		logger:  logger,
+		keys:    map[string]publicKey{},
+		done:    make(chan struct{}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := k.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go k.refreshLoop(interval)
+	}
+	return k, nil
+}
+
+func (k *JWKS) refreshLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-k.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			// Keep serving the cached keys if the refresh fails.
+			if err := k.Refresh(ctx); err != nil {
+				k.logger.Warn("jwks refresh failed, keeping the cached keys", zap.Error(err))
+			}
+			cancel()
+		}
+	}
+}
+
+// Close stops the background refresh.
+func (k *JWKS) Close() {
+	k.closeOnce.Do(func() {
+		close(k.done)
+	})
+}
+
+// Refresh fetches and replaces the cached key set. Keys that cannot be
+// parsed are logged and skipped; the set is only rejected when no usable
+// key is left.
+func (k *JWKS) Refresh(ctx context.Context) error {
+	k.refreshMu.Lock()
+	defer k.refreshMu.Unlock()
+
+	k.mu.Lock()
+	k.lastAttempt = time.Now()
+	k.mu.Unlock()
+
+	data, err := k.fetcher.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	keys, skipped, err := parseJWKS(data)
+	for _, err := range skipped {
+		k.logger.Warn("jwk skipped", zap.Error(err))
+	}
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// refreshDue reports whether an unknown kid may trigger a refresh, and if
+// so counts it as an attempt, so concurrent lookups refresh only once.
+func (k *JWKS) refreshDue() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if time.Since(k.lastAttempt) <= minRefreshInterval {
+		return false
+	}
+	k.lastAttempt = time.Now()
+	return true
+}
+
+// Key returns the public key for kid. Unknown kids trigger a rate-limited
+// refresh so freshly rotated keys verify without waiting for the ticker.
+func (k *JWKS) Key(ctx context.Context, kid, alg string) (any, error) {
+	key, ok := k.lookup(kid)
+	if !ok {
+		if k.refreshDue() {
+			if err := k.Refresh(ctx); err != nil {
+				return nil, err
+			}
+			key, ok = k.lookup(kid)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("no key found for kid %q", kid)
+	}
+	if key.alg != "" && key.alg != alg {
+		return nil, fmt.Errorf("key %q is for %s, token uses %s", kid, key.alg, alg)
+	}
+	return key.key, nil
+}
+
+func (k *JWKS) lookup(kid string) (publicKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// parseJWKS returns the signing keys of a key set, and the errors of the
+// keys it skipped because it cannot use them, e.g. symmetric keys or
+// unsupported curves.
+func parseJWKS(data []byte) (map[string]publicKey, []error, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, nil, fmt.Errorf("parse jwks: %w", err)
+	}
+	keys := make(map[string]publicKey, len(set.Keys))
+	var skipped []error
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("parse jwk %q: %w", jwk.Kid, err))
+			continue
+		}
+		keys[jwk.Kid] = publicKey{alg: jwk.Alg, key: key}
+	}
+	if len(keys) == 0 {
+		return nil, skipped, errors.New("jwks contains no usable signing keys")
+	}
+	return keys, skipped, nil
+}
+
+func (jwk jsonWebKey) publicKey() (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,221 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var nopLogger = &log.Logger{Logger: zap.NewNop()}
+
+// jwksServer serves a key set that tests can replace or break.
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    []map[string]string
+	fail    bool
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...map[string]string) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.fail {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(fail bool, keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail, s.keys = fail, keys
+}
+
+func rsaJWK(t *testing.T, kid string) (*rsa.PrivateKey, map[string]string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, MyCustomClaims{
+		UserId: "alice",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWKSVerifiesToken(t *testing.T) {
+	key, jwk := rsaJWK(t, "k1")
+	srv := newJWKSServer(t, jwk)
+	jwks, err := NewJWKS(NewHTTPFetcher(srv.URL, nil), 0, nopLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := New(nil, WithJWKS(jwks))
+
+	claims, err := j.ParseToken("Bearer " + signRS256(t, key, "k1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "alice" {
+		t.Errorf("subject = %q, want alice", claims.Subject)
+	}
+
+	other, _ := rsaJWK(t, "k1")
+	if _, err := j.ParseToken(signRS256(t, other, "k1")); err == nil {
+		t.Error("token signed with another key verified")
+	}
+}
+
+func TestJWKSSkipsUnusableKeys(t *testing.T) {
+	_, jwk := rsaJWK(t, "rsa")
+	tests := []struct {
+		name    string
+		keys    []map[string]string
+		wantErr bool
+	}{
+		{
+			name: "symmetric, unknown curve and encryption keys are skipped",
+			keys: []map[string]string{
+				{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+				{"kty": "EC", "kid": "secp256k1", "crv": "secp256k1", "x": "AA", "y": "AA"},
+				{"kty": "OKP", "kid": "x25519", "crv": "X25519", "x": "AA"},
+				{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AA", "e": "AQAB"},
+				jwk,
+			},
+		},
+		{
+			name:    "no usable key",
+			keys:    []map[string]string{{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}},
+			wantErr: true,
+		},
+		{
+			name:    "empty set",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newJWKSServer(t, tt.keys...)
+			jwks, err := NewJWKS(NewHTTPFetcher(srv.URL, nil), 0, nopLogger)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := jwks.Key(context.Background(), "rsa", "RS256"); err != nil {
+				t.Errorf("rsa key: %v", err)
+			}
+			if _, err := jwks.Key(context.Background(), "hmac", "HS256"); err == nil {
+				t.Error("got the skipped oct key")
+			}
+		})
+	}
+}
+
+func TestJWKSRefreshesOnUnknownKid(t *testing.T) {
+	_, old := rsaJWK(t, "old")
+	srv := newJWKSServer(t, old)
+	jwks, err := NewJWKS(NewHTTPFetcher(srv.URL, nil), 0, nopLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, rotated := rsaJWK(t, "new")
+	srv.set(false, rotated)
+	if _, err := jwks.Key(context.Background(), "new", "RS256"); err == nil {
+		t.Fatal("refreshed right after the initial load")
+	}
+
+	jwks.lastAttempt = time.Time{}
+	if _, err := New(nil, WithJWKS(jwks)).ParseToken(signRS256(t, key, "new")); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, want 2", n)
+	}
+}
+
+func TestJWKSFailingProviderIsRateLimited(t *testing.T) {
+	_, jwk := rsaJWK(t, "k1")
+	srv := newJWKSServer(t, jwk)
+	jwks, err := NewJWKS(NewHTTPFetcher(srv.URL, nil), 0, nopLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.set(true)
+	jwks.lastAttempt = time.Time{}
+
+	if _, err := jwks.Key(context.Background(), "unknown1", "RS256"); err == nil {
+		t.Fatal("got a key from a failing provider")
+	}
+	for i := 0; i < 5; i++ {
+		jwks.Key(context.Background(), "unknown2", "RS256")
+	}
+	if n := srv.fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, want 2: one at startup and one failed refresh", n)
+	}
+	if _, err := jwks.Key(context.Background(), "k1", "RS256"); err != nil {
+		t.Errorf("cached key after failed refresh: %v", err)
+	}
+}
+
+func TestJWKSBadRefreshKeepsKeys(t *testing.T) {
+	_, jwk := rsaJWK(t, "k1")
+	srv := newJWKSServer(t, jwk)
+	jwks, err := NewJWKS(NewHTTPFetcher(srv.URL, nil), 0, nopLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.set(false, map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"})
+	if err := jwks.Refresh(context.Background()); err == nil {
+		t.Fatal("refresh to a set without usable keys succeeded")
+	}
+	if _, err := jwks.Key(context.Background(), "k1", "RS256"); err != nil {
+		t.Errorf("cached key: %v", err)
+	}
+}
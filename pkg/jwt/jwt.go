@@ -1,25 +1,115 @@
 package jwt
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/spf13/viper"
 )
 
+const ctxClaimsKey = "claims"
+
 type JWT struct {
-	key []byte
+	key        []byte
+	jwks       *JWKS
+	allowHS256 bool
+	issuer     string
+	audience   string
+	leeway     time.Duration
+}
+
+type Option func(*JWT)
+
+// WithJWKS verifies RS*, PS*, ES* and EdDSA tokens with keys from jwks.
+func WithJWKS(jwks *JWKS) Option {
+	return func(j *JWT) {
+		j.jwks = jwks
+	}
+}
+
+// WithHS256 keeps accepting HS256 tokens signed with the shared key when
+// WithJWKS is given, e.g. while moving to an identity provider.
+func WithHS256() Option {
+	return func(j *JWT) {
+		j.allowHS256 = true
+	}
+}
+
+func WithIssuer(issuer string) Option {
+	return func(j *JWT) {
+		j.issuer = issuer
+	}
+}
+
+func WithAudience(audience string) Option {
+	return func(j *JWT) {
+		j.audience = audience
+	}
+}
+
+// WithLeeway allows for clock skew when validating exp, nbf and iat.
+func WithLeeway(leeway time.Duration) Option {
+	return func(j *JWT) {
+		j.leeway = leeway
+	}
 }
 
 type MyCustomClaims struct {
 	UserId string
+	Roles  []string `json:"roles,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewJwt(conf *viper.Viper) *JWT {
-	return &JWT{key: []byte(conf.GetString("security.jwt.key"))}
+func NewJwt(conf *viper.Viper, logger *log.Logger) (*JWT, func()) {
+	opts := []Option{
+		WithIssuer(conf.GetString("security.jwt.issuer")),
+		WithAudience(conf.GetString("security.jwt.audience")),
+		WithLeeway(conf.GetDuration("security.jwt.leeway")),
+	}
+
+	var fetcher Fetcher
+	if url := conf.GetString("security.jwt.jwks.url"); url != "" {
+		fetcher = NewHTTPFetcher(url, nil)
+	} else if file := conf.GetString("security.jwt.jwks.file"); file != "" {
+		fetcher = NewFileFetcher(file)
+	}
+	if fetcher != nil {
+		jwks, err := NewJWKS(fetcher, conf.GetDuration("security.jwt.jwks.refresh_interval"), logger)
+		if err != nil {
+			panic(fmt.Sprintf("jwks error: %s", err.Error()))
+		}
+		opts = append(opts, WithJWKS(jwks))
+	}
+	if conf.GetBool("security.jwt.allow_hs256") {
+		opts = append(opts, WithHS256())
+	}
+
+	j := New([]byte(conf.GetString("security.jwt.key")), opts...)
+	return j, j.Close
+}
+
+// New creates a JWT that verifies HS256 tokens with key and, when WithJWKS
+// is given, asymmetric tokens with the JWKS keys. With WithJWKS, HS256
+// tokens are only accepted with WithHS256; key still signs GenToken tokens.
+func New(key []byte, opts ...Option) *JWT {
+	j := &JWT{key: key}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Close stops the JWKS background refresh, if any.
+func (j *JWT) Close() {
+	if j.jwks != nil {
+		j.jwks.Close()
+	}
 }
 
 func (j *JWT) GenToken(userId string, expiresAt time.Time) (string, error) {
@@ -29,10 +119,10 @@ func (j *JWT) GenToken(userId string, expiresAt time.Time) (string, error) {
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "",
-			Subject:   "",
+			Issuer:    j.issuer,
+			Subject:   userId,
 			ID:        "",
-			Audience:  []string{},
+			Audience:  audience(j.audience),
 		},
 	})
 
@@ -49,15 +139,74 @@ func (j *JWT) ParseToken(tokenString string) (*MyCustomClaims, error) {
 	if strings.TrimSpace(tokenString) == "" {
 		return nil, errors.New("token is empty")
 	}
-	token, err := jwt.ParseWithClaims(tokenString, &MyCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return j.key, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &MyCustomClaims{}, j.keyFunc, j.parserOptions()...)
 	if err != nil {
 		return nil, err
 	}
 	if claims, ok := token.Claims.(*MyCustomClaims); ok && token.Valid {
 		return claims, nil
-	} else {
-		return nil, err
 	}
+	return nil, errors.New("token is invalid")
+}
+
+// keyFunc only hands out the shared secret for HMAC tokens and JWKS keys
+// for asymmetric ones, so an attacker cannot sign with the wrong key type.
+func (j *JWT) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if !j.acceptsHS256() {
+			return nil, errors.New("hmac tokens are not accepted")
+		}
+		return j.key, nil
+	default:
+		if j.jwks == nil {
+			return nil, fmt.Errorf("%s tokens are not accepted", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		return j.jwks.Key(context.Background(), kid, token.Method.Alg())
+	}
+}
+
+func (j *JWT) acceptsHS256() bool {
+	return len(j.key) > 0 && (j.jwks == nil || j.allowHS256)
+}
+
+func (j *JWT) parserOptions() []jwt.ParserOption {
+	var methods []string
+	if j.acceptsHS256() {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if j.jwks != nil {
+		methods = append(methods, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA")
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(j.leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if j.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(j.issuer))
+	}
+	if j.audience != "" {
+		opts = append(opts, jwt.WithAudience(j.audience))
+	}
+	return opts
+}
+
+func audience(aud string) jwt.ClaimStrings {
+	if aud == "" {
+		return jwt.ClaimStrings{}
+	}
+	return jwt.ClaimStrings{aud}
+}
+
+// WithClaims returns a copy of ctx that carries the parsed claims
+func WithClaims(ctx context.Context, claims *MyCustomClaims) context.Context {
+	return context.WithValue(ctx, ctxClaimsKey, claims)
+}
+
+// ClaimsFromContext returns the claims stored by WithClaims, if any
+func ClaimsFromContext(ctx context.Context) (*MyCustomClaims, bool) {
+	claims, ok := ctx.Value(ctxClaimsKey).(*MyCustomClaims)
+	return claims, ok && claims != nil
 }
@@ -0,0 +1,45 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSigningMethods(t *testing.T) {
+	rsaKey, jwk := rsaJWK(t, "k1")
+	srv := newJWKSServer(t, jwk)
+	newJWKS := func() *JWKS {
+		jwks, err := NewJWKS(NewHTTPFetcher(srv.URL, nil), 0, nopLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(jwks.Close)
+		return jwks
+	}
+	hs256, err := New([]byte("secret")).GenToken("alice", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs256 := signRS256(t, rsaKey, "k1")
+
+	tests := []struct {
+		name      string
+		jwt       *JWT
+		hs256, rs bool
+	}{
+		{name: "key only", jwt: New([]byte("secret")), hs256: true},
+		{name: "jwks only", jwt: New(nil, WithJWKS(newJWKS())), rs: true},
+		{name: "key and jwks", jwt: New([]byte("secret"), WithJWKS(newJWKS())), rs: true},
+		{name: "key and jwks with allow_hs256", jwt: New([]byte("secret"), WithJWKS(newJWKS()), WithHS256()), hs256: true, rs: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.jwt.ParseToken(hs256); (err == nil) != tt.hs256 {
+				t.Errorf("HS256 token: err = %v, want accepted %v", err, tt.hs256)
+			}
+			if _, err := tt.jwt.ParseToken(rs256); (err == nil) != tt.rs {
+				t.Errorf("RS256 token: err = %v, want accepted %v", err, tt.rs)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -15,6 +16,7 @@ const ctxLoggerKey = "zapLogger"
 
 type Logger struct {
 	*zap.Logger
+	stdout bool
 }
 
 func NewLog(conf *viper.Viper) *Logger {
@@ -74,21 +76,29 @@ func NewLog(conf *viper.Viper) *Logger {
 			EncodeCaller:   zapcore.ShortCallerEncoder,
 		})
 	}
+	// The STDIO transport owns stdout for JSON-RPC frames, so console output moves to stderr.
+	console := os.Stdout
+	if conf.GetBool("mcp.transports.stdio.enable") {
+		console = os.Stderr
+	}
 	// default(both) log to console and file
 	core := zapcore.NewCore(
 		encoder,
-		zapcore.NewMultiWriteSyncer(zapcore.AddSync(os.Stdout), zapcore.AddSync(&hook)), // Print to console and file
+		zapcore.NewMultiWriteSyncer(zapcore.AddSync(console), zapcore.AddSync(&hook)), // Print to console and file
 		level,
 	)
 	mode := conf.GetString("log.mode")
+	stdout := console == os.Stdout || isStdout(lp)
 	switch mode {
 	case "console":
+		stdout = console == os.Stdout
 		core = zapcore.NewCore(
 			encoder,
-			zapcore.AddSync(os.Stdout),
+			zapcore.AddSync(console),
 			level,
 		)
 	case "file":
+		stdout = isStdout(lp)
 		core = zapcore.NewCore(
 			encoder,
 			zapcore.AddSync(&hook),
@@ -96,9 +106,23 @@ func NewLog(conf *viper.Viper) *Logger {
 		)
 	}
 	if conf.GetString("env") != "prod" {
-		return &Logger{zap.New(core, zap.Development(), zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel))}
+		return &Logger{Logger: zap.New(core, zap.Development(), zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel)), stdout: stdout}
+	}
+	return &Logger{Logger: zap.New(core, zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel)), stdout: stdout}
+}
+
+// WritesToStdout reports whether log lines are written to os.Stdout,
+// either as console output or because log.log_file_name points at it.
+func (l *Logger) WritesToStdout() bool {
+	return l.stdout
+}
+
+func isStdout(name string) bool {
+	switch filepath.Clean(name) {
+	case "/dev/stdout", "/dev/fd/1", "/proc/self/fd/1":
+		return true
 	}
-	return &Logger{zap.New(core, zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel))}
+	return false
 }
 
 func timeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
@@ -124,7 +148,7 @@ func (l *Logger) WithContext(ctx context.Context) *Logger {
 	zl := ctx.Value(ctxLoggerKey)
 	ctxLogger, ok := zl.(*zap.Logger)
 	if ok {
-		return &Logger{ctxLogger}
+		return &Logger{Logger: ctxLogger, stdout: l.stdout}
 	}
 	return l
 }
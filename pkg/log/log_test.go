@@ -0,0 +1,38 @@
+package log
+
+import (
+	"github.com/spf13/viper"
+	"path/filepath"
+	"testing"
+)
+
+func TestWritesToStdout(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "server.log")
+	tests := []struct {
+		name  string
+		stdio bool
+		mode  string
+		file  string
+		want  bool
+	}{
+		{name: "console and file", file: file, want: true},
+		{name: "console", mode: "console", file: file, want: true},
+		{name: "file", mode: "file", file: file, want: false},
+		{name: "stdio moves the console to stderr", stdio: true, file: file, want: false},
+		{name: "stdio console", stdio: true, mode: "console", file: file, want: false},
+		{name: "stdio with the file on stdout", stdio: true, mode: "file", file: "/dev/stdout", want: true},
+		{name: "stdio with the file on fd 1", stdio: true, file: "/proc/self/fd/1", want: true},
+		{name: "console ignores the file", stdio: true, mode: "console", file: "/dev/stdout", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := viper.New()
+			conf.Set("mcp.transports.stdio.enable", tt.stdio)
+			conf.Set("log.mode", tt.mode)
+			conf.Set("log.log_file_name", tt.file)
+			if got := NewLog(conf).WritesToStdout(); got != tt.want {
+				t.Fatalf("WritesToStdout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/viper"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	ctxMetricsKey   = "metrics"
+	ctxTransportKey = "transport"
+)
+
+// Metrics records Prometheus metrics for an MCP server: requests per
+// method, tool calls per tool, sessions per transport and progress
+// notifications. Hooks count requests and errors; the latency of a request
+// is measured from its BeforeAny hook to its OnSuccess or OnError hook, and
+// tool calls are timed by Middleware around the handler itself.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestErrors   *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	toolCalls       *prometheus.CounterVec
+	toolErrors      *prometheus.CounterVec
+	toolDuration    *prometheus.HistogramVec
+	toolsInFlight   *prometheus.GaugeVec
+	sessions        *prometheus.GaugeVec
+	progress        prometheus.Counter
+
+	started sync.Map // request key -> time.Time
+}
+
+// NewMetrics returns nil when metrics.enable is off.
+func NewMetrics(conf *viper.Viper) *Metrics {
+	if !conf.GetBool("metrics.enable") {
+		return nil
+	}
+	conf.SetDefault("metrics.namespace", "mcp")
+	ns := conf.GetString("metrics.namespace")
+	var buckets []float64
+	if err := conf.UnmarshalKey("metrics.buckets", &buckets); err != nil {
+		panic(fmt.Sprintf("metrics.buckets error: %s", err.Error()))
+	}
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "requests_total",
+			Help: "MCP requests received, by method.",
+		}, []string{"method"}),
+		requestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "request_errors_total",
+			Help: "MCP requests answered with a JSON-RPC error, by method.",
+		}, []string{"method"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "request_duration_seconds",
+			Help:    "Time to answer an MCP request, by method.",
+			Buckets: buckets,
+		}, []string{"method"}),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "tool_calls_total",
+			Help: "Tool calls, by tool.",
+		}, []string{"tool"}),
+		toolErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns, Name: "tool_errors_total",
+			Help: "Tool calls that failed or returned a tool error, by tool.",
+		}, []string{"tool"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns, Name: "tool_duration_seconds",
+			Help:    "Time spent in the tool handler, by tool.",
+			Buckets: buckets,
+		}, []string{"tool"}),
+		toolsInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns, Name: "tool_calls_in_flight",
+			Help: "Tool calls currently running, by tool.",
+		}, []string{"tool"}),
+		sessions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: ns, Name: "sessions_active",
+			Help: "Connected sessions, by transport.",
+		}, []string{"transport"}),
+		progress: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: ns, Name: "progress_notifications_total",
+			Help: "notifications/progress sent to clients.",
+		}),
+	}
+	m.registry.MustRegister(
+		m.requests, m.requestErrors, m.requestDuration,
+		m.toolCalls, m.toolErrors, m.toolDuration, m.toolsInFlight,
+		m.sessions, m.progress,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// AddHooks registers the request and session hooks.
+func (m *Metrics) AddHooks(hooks *server.Hooks) {
+	hooks.AddBeforeAny(m.beforeAny)
+	hooks.AddOnSuccess(func(ctx context.Context, id any, method mcp.MCPMethod, message any, result any) {
+		m.done(ctx, id, method)
+	})
+	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+		m.requestErrors.WithLabelValues(string(method)).Inc()
+		m.done(ctx, id, method)
+	})
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		m.sessions.WithLabelValues(TransportFromContext(ctx)).Inc()
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		m.sessions.WithLabelValues(TransportFromContext(ctx)).Dec()
+	})
+}
+
+func (m *Metrics) beforeAny(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+	m.requests.WithLabelValues(string(method)).Inc()
+	m.started.Store(requestKey(ctx, id), time.Now())
+}
+
+func (m *Metrics) done(ctx context.Context, id any, method mcp.MCPMethod) {
+	// Requests rejected before BeforeAny, e.g. unparsable ones, have no start.
+	if start, ok := m.started.LoadAndDelete(requestKey(ctx, id)); ok {
+		m.requestDuration.WithLabelValues(string(method)).Observe(time.Since(start.(time.Time)).Seconds())
+	}
+}
+
+// requestKey tells apart requests of different sessions that use the same ID.
+func requestKey(ctx context.Context, id any) string {
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return fmt.Sprintf("%s/%v", sessionID, id)
+}
+
+// Middleware times tool handlers and makes the Metrics available to them
+// through the context, for ProgressSent.
+func (m *Metrics) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := request.Params.Name
+		m.toolCalls.WithLabelValues(tool).Inc()
+		inFlight := m.toolsInFlight.WithLabelValues(tool)
+		inFlight.Inc()
+		start := time.Now()
+
+		result, err := next(context.WithValue(ctx, ctxMetricsKey, m), request)
+
+		m.toolDuration.WithLabelValues(tool).Observe(time.Since(start).Seconds())
+		inFlight.Dec()
+		if err != nil || (result != nil && result.IsError) {
+			m.toolErrors.WithLabelValues(tool).Inc()
+		}
+		return result, err
+	}
+}
+
+// ProgressSent counts a progress notification sent from a tool call. It is
+// a no-op when metrics are disabled.
+func ProgressSent(ctx context.Context) {
+	if m, ok := ctx.Value(ctxMetricsKey).(*Metrics); ok {
+		m.progress.Inc()
+	}
+}
+
+// WithTransport labels the sessions opened through the wrapped handler.
+func WithTransport(transport string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxTransportKey, transport)))
+		})
+	}
+}
+
+// TransportFromContext returns the transport set by WithTransport. STDIO
+// sessions do not pass through an HTTP handler, so it is the default.
+func TransportFromContext(ctx context.Context) string {
+	if transport, ok := ctx.Value(ctxTransportKey).(string); ok {
+		return transport
+	}
+	return "stdio"
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// value returns the metric name with exactly labels, or 0 if it has no sample.
+func value(t *testing.T, m *Metrics, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			if len(metric.GetLabel()) != len(labels) {
+				continue
+			}
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] != label.GetValue() {
+					continue metrics
+				}
+			}
+			switch {
+			case metric.Counter != nil:
+				return metric.GetCounter().GetValue()
+			case metric.Gauge != nil:
+				return metric.GetGauge().GetValue()
+			case metric.Histogram != nil:
+				return float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return 0
+}
+
+func newTestMetrics() *Metrics {
+	conf := viper.New()
+	conf.Set("metrics.enable", true)
+	conf.Set("metrics.namespace", "test")
+	return NewMetrics(conf)
+}
+
+func TestNewMetricsDisabled(t *testing.T) {
+	if m := NewMetrics(viper.New()); m != nil {
+		t.Fatal("NewMetrics returned metrics with metrics.enable off")
+	}
+	// Tools report progress whether or not metrics are enabled.
+	ProgressSent(context.Background())
+}
+
+func TestMetrics(t *testing.T) {
+	m := newTestMetrics()
+	hooks := &server.Hooks{}
+	m.AddHooks(hooks)
+	srv := server.NewMCPServer("test", "1.0.0",
+		server.WithToolCapabilities(false),
+		server.WithHooks(hooks),
+		server.WithToolHandlerMiddleware(m.Middleware),
+	)
+	srv.AddTool(mcp.NewTool("ok"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ProgressSent(ctx)
+		return mcp.NewToolResultText("ok"), nil
+	})
+	srv.AddTool(mcp.NewTool("tool_error"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultError("bad input"), nil
+	})
+	srv.AddTool(mcp.NewTool("failing"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return nil, errors.New("boom")
+	})
+
+	ts := httptest.NewServer(WithTransport("http")(server.NewStreamableHTTPServer(srv)))
+	defer ts.Close()
+	// Sessions are registered for the GET stream the client listens on.
+	c, err := client.NewStreamableHttpClient(ts.URL+"/mcp", transport.WithContinuousListening(), transport.WithLogger(zap.NewNop().Sugar()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{Name: "test", Version: "1.0.0"}
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range []string{"ok", "ok", "tool_error", "failing"} {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = tool
+		c.CallTool(ctx, request)
+	}
+	if _, err := c.ListTools(ctx, mcp.ListToolsRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	sessions := map[string]string{"transport": "http"}
+	waitFor(t, func() bool { return value(t, m, "test_sessions_active", sessions) == 1 })
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   float64
+	}{
+		{name: "test_requests_total", labels: map[string]string{"method": "initialize"}, want: 1},
+		{name: "test_requests_total", labels: map[string]string{"method": "tools/call"}, want: 4},
+		{name: "test_requests_total", labels: map[string]string{"method": "tools/list"}, want: 1},
+		{name: "test_request_errors_total", labels: map[string]string{"method": "tools/call"}, want: 1},
+		{name: "test_request_duration_seconds", labels: map[string]string{"method": "tools/call"}, want: 4},
+		{name: "test_tool_calls_total", labels: map[string]string{"tool": "ok"}, want: 2},
+		{name: "test_tool_calls_total", labels: map[string]string{"tool": "failing"}, want: 1},
+		{name: "test_tool_errors_total", labels: map[string]string{"tool": "ok"}, want: 0},
+		{name: "test_tool_errors_total", labels: map[string]string{"tool": "tool_error"}, want: 1},
+		{name: "test_tool_errors_total", labels: map[string]string{"tool": "failing"}, want: 1},
+		{name: "test_tool_duration_seconds", labels: map[string]string{"tool": "ok"}, want: 2},
+		{name: "test_tool_calls_in_flight", labels: map[string]string{"tool": "ok"}, want: 0},
+		{name: "test_progress_notifications_total", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := value(t, m, tt.name, tt.labels); got != tt.want {
+				t.Fatalf("%s%v = %v, want %v", tt.name, tt.labels, got, tt.want)
+			}
+		})
+	}
+
+	c.Close()
+	waitFor(t, func() bool { return value(t, m, "test_sessions_active", sessions) == 0 })
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTransportFromContext(t *testing.T) {
+	if got := TransportFromContext(context.Background()); got != "stdio" {
+		t.Fatalf("default transport = %q, want stdio", got)
+	}
+	var got string
+	handler := WithTransport("sse")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = TransportFromContext(r.Context())
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/sse", nil))
+	if got != "sse" {
+		t.Fatalf("transport = %q, want sse", got)
+	}
+}
@@ -0,0 +1,200 @@
+// Package migrate applies versioned schema migrations with GORM and records
+// them in the schema_migrations table. It works with every driver of
+// repository.NewDB: MySQL, Postgres and SQLite.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"slices"
+	"time"
+)
+
+// Migration changes the schema to Version. Up and Down run in a transaction
+// together with the update of schema_migrations. MySQL commits DDL
+// statements implicitly, so there a failed migration may be half applied.
+type Migration struct {
+	Version int64 // e.g. 20261017093000, the time it was created
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error // nil if it cannot be rolled back
+}
+
+type schemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status is a migration and whether it is applied. Applied migrations that
+// are not known to the Migrator have only Version and Name.
+type Status struct {
+	*Migration
+	AppliedAt time.Time // zero when pending
+}
+
+func (s Status) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []*Migration
+}
+
+// New returns a Migrator of migrations, sorted by version. Versions must be
+// positive and unique.
+func New(db *gorm.DB, migrations ...*Migration) (*Migrator, error) {
+	migrations = slices.Clone(migrations)
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i, m := range migrations {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q: version must be positive", m.Name)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s: no up", m.Version, m.Name)
+		}
+		if i > 0 && migrations[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migrations %s and %s have the same version %d", migrations[i-1].Name, m.Name, m.Version)
+		}
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Status lists every known and every applied migration by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []Status
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = row.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		statuses = append(statuses, Status{
+			Migration: &Migration{Version: row.Version, Name: row.Name},
+			AppliedAt: row.AppliedAt,
+		})
+	}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return statuses, nil
+}
+
+// Pending returns the migrations that are not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]*Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Migration
+	for _, status := range statuses {
+		if !status.Applied() {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies up to n pending migrations, oldest first, or all of them when
+// n <= 0. It stops at the first failure and returns the ones it applied.
+func (m *Migrator) Up(ctx context.Context, n int) ([]*Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && n < len(pending) {
+		pending = pending[:n]
+	}
+	var done []*Migration
+	for _, migration := range pending {
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the n latest applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, n int) ([]*Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []*Migration
+	for i := len(statuses) - 1; i >= 0 && len(done) < n; i-- {
+		migration := statuses[i].Migration
+		if !statuses[i].Applied() {
+			continue
+		}
+		if migration.Down == nil {
+			return done, fmt.Errorf("migration %d_%s cannot be rolled back", migration.Version, migration.Name)
+		}
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{Version: migration.Version}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// applied creates schema_migrations if needed and returns its rows.
+func (m *Migrator) applied(ctx context.Context) (map[int64]schemaMigration, error) {
+	db := m.db.WithContext(ctx)
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// ErrPending is returned by Check when migrations are not applied.
+var ErrPending = errors.New("migrations pending")
+
+// Check fails with ErrPending when some migrations are not applied.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d, the first is %d_%s", ErrPending, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createTable(name string) *Migration {
+	return &Migration{
+		Name: "create_" + name,
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE " + name + " (id INTEGER)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE " + name).Error
+		},
+	}
+}
+
+func versions(migrations []*Migration) []int64 {
+	var vs []int64
+	for _, m := range migrations {
+		vs = append(vs, m.Version)
+	}
+	return vs
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNew(t *testing.T) {
+	up := func(tx *gorm.DB) error { return nil }
+	tests := []struct {
+		name       string
+		migrations []*Migration
+		wantErr    string
+	}{
+		{name: "sorted", migrations: []*Migration{{Version: 2, Name: "b", Up: up}, {Version: 1, Name: "a", Up: up}}},
+		{name: "no version", migrations: []*Migration{{Name: "a", Up: up}}, wantErr: "version must be positive"},
+		{name: "no up", migrations: []*Migration{{Version: 1, Name: "a"}}, wantErr: "no up"},
+		{name: "same version", migrations: []*Migration{{Version: 1, Name: "a", Up: up}, {Version: 1, Name: "b", Up: up}}, wantErr: "same version 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(nil, tt.migrations...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if got := versions(m.migrations); !equal(got, []int64{1, 2}) {
+				t.Fatalf("versions = %v, want [1 2]", got)
+			}
+		})
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	a, b, c := createTable("a"), createTable("b"), createTable("c")
+	a.Version, b.Version, c.Version = 1, 2, 3
+	c.Down = nil
+
+	// Each step runs on the state left by the previous one.
+	steps := []struct {
+		name      string
+		up        bool
+		n         int
+		wantDone  []int64
+		wantErr   string
+		wantTable map[string]bool
+	}{
+		{name: "up one", up: true, n: 1, wantDone: []int64{1}, wantTable: map[string]bool{"a": true, "b": false}},
+		{name: "up the rest", up: true, wantDone: []int64{2, 3}, wantTable: map[string]bool{"a": true, "b": true, "c": true}},
+		{name: "up nothing pending", up: true},
+		{name: "down without a down", n: 1, wantErr: "cannot be rolled back", wantTable: map[string]bool{"c": true}},
+	}
+	ctx := context.Background()
+	db := newTestDB(t)
+	m, err := New(db, c, b, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		var done []*Migration
+		if step.up {
+			done, err = m.Up(ctx, step.n)
+		} else {
+			done, err = m.Down(ctx, step.n)
+		}
+		if step.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), step.wantErr) {
+				t.Fatalf("%s: err = %v, want %q", step.name, err, step.wantErr)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := versions(done); !equal(got, step.wantDone) {
+			t.Fatalf("%s: done = %v, want %v", step.name, got, step.wantDone)
+		}
+		for table, want := range step.wantTable {
+			if got := db.Migrator().HasTable(table); got != want {
+				t.Fatalf("%s: table %s exists = %v, want %v", step.name, table, got, want)
+			}
+		}
+	}
+
+	// With a down for c, c and b roll back, newest first.
+	c = createTable("c")
+	c.Version = 3
+	m, err = New(db, a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); !equal(got, []int64{3, 2}) {
+		t.Fatalf("down = %v, want [3 2]", got)
+	}
+	if db.Migrator().HasTable("b") || db.Migrator().HasTable("c") {
+		t.Fatal("tables b and c still exist after down")
+	}
+
+	// An applied migration the Migrator does not know is listed by Status
+	// but cannot be rolled back.
+	m, err = New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || !statuses[0].Applied() || statuses[0].Version != 1 || statuses[0].Name != "create_a" {
+		t.Fatalf("statuses = %+v, want the applied 1_create_a", statuses)
+	}
+	if _, err := m.Down(ctx, 1); err == nil || !strings.Contains(err.Error(), "1_create_a cannot be rolled back") {
+		t.Fatalf("Down = %v, want 1_create_a cannot be rolled back", err)
+	}
+}
+
+func TestUpFailure(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	a := createTable("a")
+	a.Version = 1
+	broken := &Migration{Version: 2, Name: "broken", Up: func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE TABLE half (id INTEGER)").Error; err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}}
+	m, err := New(db, a, broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := m.Up(ctx, 0)
+	if err == nil || !strings.Contains(err.Error(), "migration 2_broken up: boom") {
+		t.Fatalf("Up = %v, want the failure of 2_broken", err)
+	}
+	if got := versions(done); !equal(got, []int64{1}) {
+		t.Fatalf("done = %v, want [1]", got)
+	}
+	// SQLite rolls back DDL with the transaction.
+	if db.Migrator().HasTable("half") {
+		t.Fatal("the failed migration was not rolled back")
+	}
+	if err := m.Check(ctx); !errors.Is(err, ErrPending) || !strings.Contains(err.Error(), "2_broken") {
+		t.Fatalf("Check = %v, want ErrPending for 2_broken", err)
+	}
+}
+
+func TestLoadSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		wantDown bool
+		wantErr  string
+	}{
+		{
+			name: "plain",
+			files: fstest.MapFS{
+				"1_things.up.sql":   {Data: []byte("-- things\nCREATE TABLE things (id INTEGER);\nCREATE TABLE more (id INTEGER);\n")},
+				"1_things.down.sql": {Data: []byte("DROP TABLE more;\nDROP TABLE things;\n")},
+				"README.md":         {Data: []byte("ignored")},
+			},
+			wantDown: true,
+		},
+		{
+			name: "driver file wins",
+			files: fstest.MapFS{
+				"1_things.up.sql":        {Data: []byte("not sql for sqlite;")},
+				"1_things.up.sqlite.sql": {Data: []byte("CREATE TABLE things (id INTEGER);")},
+			},
+		},
+		{
+			name:    "no script for the driver",
+			files:   fstest.MapFS{"1_things.up.mysql.sql": {Data: []byte("CREATE TABLE things (id INT);")}},
+			wantErr: "no script for driver sqlite",
+		},
+		{
+			name:    "no up",
+			files:   fstest.MapFS{"1_things.down.sql": {Data: []byte("DROP TABLE things;")}},
+			wantErr: "has no up file",
+		},
+		{
+			name: "version used twice",
+			files: fstest.MapFS{
+				"1_things.up.sql": {Data: []byte("CREATE TABLE things (id INTEGER);")},
+				"1_other.up.sql":  {Data: []byte("CREATE TABLE other (id INTEGER);")},
+			},
+			wantErr: "version 1 is also used by",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newTestDB(t)
+			migrations, err := LoadSQL(tt.files)
+			if err == nil {
+				var m *Migrator
+				if m, err = New(db, migrations...); err == nil {
+					_, err = m.Up(ctx, 0)
+				}
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(migrations) != 1 || migrations[0].Version != 1 || migrations[0].Name != "things" {
+				t.Fatalf("migrations = %+v, want 1_things", migrations)
+			}
+			if !db.Migrator().HasTable("things") {
+				t.Fatal("table things does not exist after up")
+			}
+			if (migrations[0].Down != nil) != tt.wantDown {
+				t.Fatalf("has down = %v, want %v", migrations[0].Down != nil, tt.wantDown)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{name: "empty", script: "-- nothing\n\n"},
+		{name: "one", script: "CREATE TABLE a (id INTEGER);", want: []string{"CREATE TABLE a (id INTEGER);"}},
+		{
+			name:   "multi-line",
+			script: "-- a\nCREATE TABLE a (\n    id INTEGER\n);\nDROP TABLE b;\n",
+			want:   []string{"CREATE TABLE a (\n    id INTEGER\n);", "DROP TABLE b;"},
+		},
+		{name: "no final semicolon", script: "DROP TABLE a;\nDROP TABLE b", want: []string{"DROP TABLE a;", "DROP TABLE b"}},
+		{name: "semicolon inside a line", script: "INSERT INTO a VALUES ('x;y');", want: []string{"INSERT INTO a VALUES ('x;y');"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitStatements(tt.script)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Fatalf("splitStatements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"bufio"
+	"fmt"
+	"gorm.io/gorm"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sqlFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)(?:\.(mysql|postgres|sqlite))?\.sql$`)
+
+// LoadSQL reads the SQL migrations in the top directory of fsys. A migration
+// is <version>_<name>.up.sql with an optional <version>_<name>.down.sql.
+// <version>_<name>.up.<driver>.sql, for mysql, postgres or sqlite, replaces
+// the plain file for that driver, so schemas can use driver-specific types.
+// Statements end with a semicolon at the end of a line. Other files are
+// ignored.
+func LoadSQL(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	type scripts struct {
+		name     string
+		up, down map[string]string // by driver, "" for any
+	}
+	byVersion := map[int64]*scripts{}
+	for _, entry := range entries {
+		match := sqlFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		s, ok := byVersion[version]
+		if !ok {
+			s = &scripts{name: match[2], up: map[string]string{}, down: map[string]string{}}
+			byVersion[version] = s
+		}
+		if s.name != match[2] {
+			return nil, fmt.Errorf("%s: version %d is also used by %s", entry.Name(), version, s.name)
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			s.up[match[4]] = string(data)
+		} else {
+			s.down[match[4]] = string(data)
+		}
+	}
+
+	var migrations []*Migration
+	for version, s := range byVersion {
+		if len(s.up) == 0 {
+			return nil, fmt.Errorf("migration %d_%s has no up file", version, s.name)
+		}
+		migration := &Migration{Version: version, Name: s.name, Up: execScript(s.up)}
+		if len(s.down) > 0 {
+			migration.Down = execScript(s.down)
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// execScript runs the script for the driver of tx.
+func execScript(scripts map[string]string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		driver := tx.Dialector.Name()
+		script, ok := scripts[driver]
+		if !ok {
+			if script, ok = scripts[""]; !ok {
+				return fmt.Errorf("no script for driver %s", driver)
+			}
+		}
+		for _, statement := range splitStatements(script) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// splitStatements splits a script at lines that end with a semicolon and
+// drops comment lines. Drivers differ in whether Exec accepts several
+// statements at once.
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+	)
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		current.WriteString(scanner.Text())
+		current.WriteByte('\n')
+		if strings.HasSuffix(line, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
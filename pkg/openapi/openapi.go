@@ -0,0 +1,328 @@
+package openapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Document is the subset of an OpenAPI 3 document needed to call its
+// operations. Schemas are kept as generic JSON Schema maps.
+type Document struct {
+	OpenAPI    string              `yaml:"openapi"`
+	Info       Info                `yaml:"info"`
+	Servers    []Server            `yaml:"servers"`
+	Paths      map[string]PathItem `yaml:"paths"`
+	Components Components          `yaml:"components"`
+}
+
+type Info struct {
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+type Server struct {
+	URL       string                    `yaml:"url"`
+	Variables map[string]ServerVariable `yaml:"variables"`
+}
+
+type ServerVariable struct {
+	Default string `yaml:"default"`
+}
+
+type PathItem struct {
+	Parameters []Parameter `yaml:"parameters"`
+	Get        *Operation  `yaml:"get"`
+	Put        *Operation  `yaml:"put"`
+	Post       *Operation  `yaml:"post"`
+	Delete     *Operation  `yaml:"delete"`
+	Patch      *Operation  `yaml:"patch"`
+	Head       *Operation  `yaml:"head"`
+}
+
+type Operation struct {
+	OperationID string       `yaml:"operationId"`
+	Summary     string       `yaml:"summary"`
+	Description string       `yaml:"description"`
+	Deprecated  bool         `yaml:"deprecated"`
+	Parameters  []Parameter  `yaml:"parameters"`
+	RequestBody *RequestBody `yaml:"requestBody"`
+}
+
+type Parameter struct {
+	Ref         string         `yaml:"$ref"`
+	Name        string         `yaml:"name"`
+	In          string         `yaml:"in"` // path, query, header or cookie
+	Description string         `yaml:"description"`
+	Required    bool           `yaml:"required"`
+	Schema      map[string]any `yaml:"schema"`
+}
+
+type RequestBody struct {
+	Ref         string               `yaml:"$ref"`
+	Description string               `yaml:"description"`
+	Required    bool                 `yaml:"required"`
+	Content     map[string]MediaType `yaml:"content"`
+}
+
+type MediaType struct {
+	Schema map[string]any `yaml:"schema"`
+}
+
+type Components struct {
+	Schemas       map[string]any         `yaml:"schemas"`
+	Parameters    map[string]Parameter   `yaml:"parameters"`
+	RequestBodies map[string]RequestBody `yaml:"requestBodies"`
+}
+
+// Endpoint is an operation with its path-level parameters merged in and
+// every local $ref resolved.
+type Endpoint struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Description string
+	Parameters  []Parameter
+	// Body is nil when the operation takes no request body.
+	Body *Body
+}
+
+type Body struct {
+	Description string
+	Required    bool
+	ContentType string
+	Schema      map[string]any
+}
+
+// Load reads an OpenAPI 3 document written in YAML or JSON.
+func Load(file string) (*Document, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Document, error) {
+	doc := &Document{}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("openapi version %q is not supported, only 3.x", doc.OpenAPI)
+	}
+	return doc, nil
+}
+
+// BaseURL returns the first server URL with its variables set to their
+// defaults, or "" when the document lists no servers.
+func (d *Document) BaseURL() string {
+	if len(d.Servers) == 0 {
+		return ""
+	}
+	server := d.Servers[0]
+	url := server.URL
+	for name, variable := range server.Variables {
+		url = strings.ReplaceAll(url, "{"+name+"}", variable.Default)
+	}
+	return url
+}
+
+// Endpoints lists every operation, sorted by path and method.
+func (d *Document) Endpoints() ([]Endpoint, error) {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	var endpoints []Endpoint
+	for _, path := range paths {
+		item := d.Paths[path]
+		for _, op := range []struct {
+			method string
+			op     *Operation
+		}{
+			{http.MethodGet, item.Get},
+			{http.MethodPut, item.Put},
+			{http.MethodPost, item.Post},
+			{http.MethodDelete, item.Delete},
+			{http.MethodPatch, item.Patch},
+			{http.MethodHead, item.Head},
+		} {
+			if op.op == nil {
+				continue
+			}
+			endpoint, err := d.endpoint(op.method, path, item.Parameters, op.op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", op.method, path, err)
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints, nil
+}
+
+func (d *Document) endpoint(method, path string, shared []Parameter, op *Operation) (Endpoint, error) {
+	endpoint := Endpoint{
+		Method:      method,
+		Path:        path,
+		OperationID: op.OperationID,
+		Summary:     op.Summary,
+		Description: op.Description,
+	}
+
+	// Operation parameters override path-level ones with the same name and location.
+	var params []Parameter
+	for _, p := range append(slices.Clone(shared), op.Parameters...) {
+		p, err := d.parameter(p)
+		if err != nil {
+			return Endpoint{}, err
+		}
+		i := slices.IndexFunc(params, func(q Parameter) bool { return q.Name == p.Name && q.In == p.In })
+		if i >= 0 {
+			params[i] = p
+		} else {
+			params = append(params, p)
+		}
+	}
+	endpoint.Parameters = params
+
+	if op.RequestBody != nil {
+		body, err := d.body(*op.RequestBody)
+		if err != nil {
+			return Endpoint{}, err
+		}
+		endpoint.Body = body
+	}
+	return endpoint, nil
+}
+
+func (d *Document) parameter(p Parameter) (Parameter, error) {
+	if p.Ref != "" {
+		name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+		if !ok {
+			return Parameter{}, fmt.Errorf("$ref %q is not supported", p.Ref)
+		}
+		ref, ok := d.Components.Parameters[name]
+		if !ok {
+			return Parameter{}, fmt.Errorf("$ref %q not found", p.Ref)
+		}
+		p = ref
+	}
+	if p.Name == "" || p.In == "" {
+		return Parameter{}, errors.New("parameter name and in are required")
+	}
+	schema, err := d.schema(p.Schema, nil)
+	if err != nil {
+		return Parameter{}, fmt.Errorf("parameter %s: %w", p.Name, err)
+	}
+	p.Schema = schema
+	if p.In == "path" {
+		p.Required = true
+	}
+	return p, nil
+}
+
+// body picks the JSON media type when there is one, else the first listed.
+func (d *Document) body(b RequestBody) (*Body, error) {
+	if b.Ref != "" {
+		name, ok := strings.CutPrefix(b.Ref, "#/components/requestBodies/")
+		if !ok {
+			return nil, fmt.Errorf("$ref %q is not supported", b.Ref)
+		}
+		ref, ok := d.Components.RequestBodies[name]
+		if !ok {
+			return nil, fmt.Errorf("$ref %q not found", b.Ref)
+		}
+		b = ref
+	}
+	types := make([]string, 0, len(b.Content))
+	for contentType := range b.Content {
+		types = append(types, contentType)
+	}
+	if len(types) == 0 {
+		return nil, errors.New("requestBody has no content")
+	}
+	slices.Sort(types)
+	contentType := types[0]
+	for _, t := range types {
+		if t == "application/json" || strings.HasSuffix(t, "+json") {
+			contentType = t
+			break
+		}
+	}
+	schema, err := d.schema(b.Content[contentType].Schema, nil)
+	if err != nil {
+		return nil, fmt.Errorf("requestBody: %w", err)
+	}
+	return &Body{
+		Description: b.Description,
+		Required:    b.Required,
+		ContentType: contentType,
+		Schema:      schema,
+	}, nil
+}
+
+// schema returns a copy of s with every #/components/schemas $ref inlined.
+// A schema that refers back to itself is cut off as an untyped schema.
+func (d *Document) schema(s map[string]any, seen []string) (map[string]any, error) {
+	if s == nil {
+		return nil, nil
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		if !ok {
+			return nil, fmt.Errorf("$ref %q is not supported", ref)
+		}
+		if slices.Contains(seen, name) {
+			return map[string]any{"description": "recursive " + name}, nil
+		}
+		target, ok := d.Components.Schemas[name].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+		return d.schema(target, append(seen, name))
+	}
+	out := make(map[string]any, len(s))
+	for key, value := range s {
+		resolved, err := d.value(value, seen)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = resolved
+	}
+	return out, nil
+}
+
+func (d *Document) value(v any, seen []string) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		return d.schema(v, seen)
+	case map[any]any:
+		// YAML allows non-string keys, e.g. enum maps keyed by numbers.
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = value
+		}
+		return d.schema(m, seen)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			resolved, err := d.value(item, seen)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
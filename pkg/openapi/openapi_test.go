@@ -0,0 +1,176 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const petstore = `
+openapi: 3.0.3
+info: {title: Petstore, version: "1.0"}
+servers:
+  - url: https://{region}.example.com/v1
+    variables:
+      region: {default: eu}
+paths:
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, schema: {type: integer}}
+      - {name: verbose, in: query, description: shared}
+    get:
+      operationId: getPet
+      summary: Get a pet
+      parameters:
+        - {name: verbose, in: query, description: overridden, schema: {type: boolean}}
+        - $ref: '#/components/parameters/Trace'
+    delete:
+      operationId: deletePet
+  /pets:
+    post:
+      operationId: createPet
+      requestBody:
+        $ref: '#/components/requestBodies/Pet'
+components:
+  parameters:
+    Trace: {name: X-Trace, in: header}
+  requestBodies:
+    Pet:
+      required: true
+      content:
+        text/plain: {schema: {type: string}}
+        application/json: {schema: {$ref: '#/components/schemas/Pet'}}
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+        parent: {$ref: '#/components/schemas/Pet'}
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{name: "yaml", spec: petstore},
+		{name: "json", spec: `{"openapi": "3.1.0", "paths": {}}`},
+		{name: "swagger 2", spec: `swagger: "2.0"`, wantErr: "only 3.x"},
+		{name: "not a document", spec: `[1, 2]`, wantErr: "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.spec))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Parse error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.BaseURL(); got != "https://eu.example.com/v1" {
+		t.Fatalf("BaseURL = %q", got)
+	}
+	if got := (&Document{}).BaseURL(); got != "" {
+		t.Fatalf("BaseURL without servers = %q", got)
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	doc, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints, err := doc.Endpoints()
+	if err != nil {
+		t.Fatalf("Endpoints: %v", err)
+	}
+
+	var got []string
+	for _, e := range endpoints {
+		got = append(got, e.Method+" "+e.Path)
+	}
+	want := []string{"POST /pets", "GET /pets/{id}", "DELETE /pets/{id}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("endpoints = %v, want %v", got, want)
+	}
+
+	create := endpoints[0]
+	if create.Body == nil || create.Body.ContentType != "application/json" || !create.Body.Required {
+		t.Fatalf("createPet body = %+v, want the required JSON body", create.Body)
+	}
+	if create.Body.Schema["type"] != "object" {
+		t.Fatalf("createPet schema = %v, want the inlined Pet schema", create.Body.Schema)
+	}
+	parent := create.Body.Schema["properties"].(map[string]any)["parent"]
+	if !reflect.DeepEqual(parent, map[string]any{"description": "recursive Pet"}) {
+		t.Fatalf("recursive parent = %v", parent)
+	}
+
+	get := endpoints[1]
+	wantParams := []Parameter{
+		{Name: "id", In: "path", Required: true, Schema: map[string]any{"type": "integer"}},
+		{Name: "verbose", In: "query", Description: "overridden", Schema: map[string]any{"type": "boolean"}},
+		{Name: "X-Trace", In: "header"},
+	}
+	if !reflect.DeepEqual(get.Parameters, wantParams) {
+		t.Fatalf("getPet parameters = %+v, want %+v", get.Parameters, wantParams)
+	}
+	if get.Body != nil {
+		t.Fatalf("getPet body = %+v, want none", get.Body)
+	}
+	if endpoints[2].Method != http.MethodDelete || len(endpoints[2].Parameters) != 2 {
+		t.Fatalf("deletePet = %+v, want the path-level parameters", endpoints[2])
+	}
+}
+
+func TestEndpointsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{
+			name:    "missing parameter ref",
+			spec:    `{openapi: 3.0.0, paths: {/a: {get: {parameters: [{$ref: "#/components/parameters/Nope"}]}}}}`,
+			wantErr: `GET /a: $ref "#/components/parameters/Nope" not found`,
+		},
+		{
+			name:    "remote schema ref",
+			spec:    `{openapi: 3.0.0, paths: {/a: {get: {parameters: [{name: q, in: query, schema: {$ref: "other.yaml#/X"}}]}}}}`,
+			wantErr: `parameter q: $ref "other.yaml#/X" is not supported`,
+		},
+		{
+			name:    "parameter without location",
+			spec:    `{openapi: 3.0.0, paths: {/a: {get: {parameters: [{name: q}]}}}}`,
+			wantErr: "parameter name and in are required",
+		},
+		{
+			name:    "body without content",
+			spec:    `{openapi: 3.0.0, paths: {/a: {post: {requestBody: {required: true}}}}}`,
+			wantErr: "requestBody has no content",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = doc.Endpoints()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Endpoints error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
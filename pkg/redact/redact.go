@@ -0,0 +1,156 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const Placeholder = "[REDACTED]"
+
+// Redactor masks secrets and personal data in values before they are
+// logged. Object fields named in log.redact.fields are replaced as a whole,
+// matches of log.redact.patterns are replaced inside strings, and strings
+// longer than log.redact.max_size bytes, such as response bodies or base64
+// images, are cut short.
+type Redactor struct {
+	enable   bool
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+	maxSize  int
+}
+
+func NewRedactor(conf *viper.Viper) *Redactor {
+	conf.SetDefault("log.redact.enable", true)
+	conf.SetDefault("log.redact.max_size", 1024)
+	r := &Redactor{
+		enable:  conf.GetBool("log.redact.enable"),
+		fields:  map[string]bool{},
+		maxSize: conf.GetInt("log.redact.max_size"),
+	}
+	for _, field := range conf.GetStringSlice("log.redact.fields") {
+		r.fields[normalize(field)] = true
+	}
+	for _, pattern := range conf.GetStringSlice("log.redact.patterns") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			panic(fmt.Sprintf("log.redact.patterns error: %s", err.Error()))
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r
+}
+
+// normalize makes "X-Api-Key", "x_api_key" and "xApiKey" the same field.
+func normalize(name string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// Any is zap.Any with v redacted. Fields named in sensitive are masked in
+// addition to the configured ones, see SensitiveArguments.
+func (r *Redactor) Any(key string, v any, sensitive ...string) zap.Field {
+	return zap.Any(key, r.Redact(v, sensitive...))
+}
+
+// Error logs err under "error" with the patterns applied to its message.
+func (r *Redactor) Error(err error) zap.Field {
+	if err == nil || !r.enable {
+		return zap.Error(err)
+	}
+	return zap.String("error", r.string(err.Error()))
+}
+
+// Redact returns a JSON-like copy of v with secrets masked. Values that do
+// not marshal to JSON are returned as they are.
+func (r *Redactor) Redact(v any, sensitive ...string) any {
+	if !r.enable || v == nil {
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var tree any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return v
+	}
+	fields := r.fields
+	if len(sensitive) > 0 {
+		fields = make(map[string]bool, len(r.fields)+len(sensitive))
+		for field := range r.fields {
+			fields[field] = true
+		}
+		for _, field := range sensitive {
+			fields[normalize(field)] = true
+		}
+	}
+	return r.walk(tree, fields)
+}
+
+func (r *Redactor) walk(v any, fields map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if fields[normalize(key)] {
+				v[key] = Placeholder
+			} else {
+				v[key] = r.walk(value, fields)
+			}
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = r.walk(value, fields)
+		}
+		return v
+	case string:
+		return r.string(v)
+	default:
+		return v
+	}
+}
+
+func (r *Redactor) string(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Placeholder)
+	}
+	if r.maxSize <= 0 || len(s) <= r.maxSize {
+		return s
+	}
+	cut := r.maxSize
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(%d bytes truncated)", s[:cut], len(s)-cut)
+}
+
+// SensitiveArguments returns the arguments of tool whose schema is marked
+// "writeOnly": true, e.g. with the `jsonschema:"writeOnly=true"` tag or in
+// a catalog inputSchema.
+func SensitiveArguments(tool mcp.Tool) []string {
+	properties := tool.InputSchema.Properties
+	if tool.RawInputSchema != nil {
+		var schema struct {
+			Properties map[string]any `json:"properties"`
+		}
+		if err := json.Unmarshal(tool.RawInputSchema, &schema); err != nil {
+			return nil
+		}
+		properties = schema.Properties
+	}
+	var names []string
+	for name, property := range properties {
+		if property, ok := property.(map[string]any); ok && property["writeOnly"] == true {
+			names = append(names, name)
+		}
+	}
+	return names
+}
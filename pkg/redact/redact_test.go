@@ -0,0 +1,123 @@
+package redact
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newTestRedactor(enable bool) *Redactor {
+	conf := viper.New()
+	conf.Set("log.redact.enable", enable)
+	conf.Set("log.redact.fields", []string{"password", "X-Api-Key", "authorization"})
+	conf.Set("log.redact.patterns", []string{`sk-[A-Za-z0-9]+`, `\b\d{3}-\d{2}-\d{4}\b`})
+	conf.Set("log.redact.max_size", 32)
+	return NewRedactor(conf)
+}
+
+func TestRedact(t *testing.T) {
+	r := newTestRedactor(true)
+	tests := []struct {
+		name      string
+		value     any
+		sensitive []string
+		want      string
+	}{
+		{name: "nil", value: nil, want: "null"},
+		{name: "field", value: map[string]any{"user": "bob", "password": "hunter2"}, want: `{"password":"[REDACTED]","user":"bob"}`},
+		{name: "field spellings", value: map[string]any{"x_api_key": "1", "xApiKey": "2", "X-API-KEY": "3", "api_key": "4"}, want: `{"X-API-KEY":"[REDACTED]","api_key":"4","xApiKey":"[REDACTED]","x_api_key":"[REDACTED]"}`},
+		{name: "nested", value: map[string]any{"headers": map[string]any{"Authorization": "Bearer x"}, "list": []any{map[string]any{"password": "p"}}}, want: `{"headers":{"Authorization":"[REDACTED]"},"list":[{"password":"[REDACTED]"}]}`},
+		{name: "whole object", value: map[string]any{"password": map[string]any{"old": "a", "new": "b"}}, want: `{"password":"[REDACTED]"}`},
+		{name: "pattern", value: map[string]any{"note": "key sk-abc123 ok"}, want: `{"note":"key [REDACTED] ok"}`},
+		{name: "pattern in a list", value: []string{"ssn 123-45-6789"}, want: `["ssn [REDACTED]"]`},
+		{name: "truncated", value: "0123456789abcdef0123456789abcdefghij", want: `"0123456789abcdef0123456789abcdef...(4 bytes truncated)"`},
+		{name: "truncated on a rune boundary", value: "0123456789abcdef012345678901234é", want: `"0123456789abcdef012345678901234...(2 bytes truncated)"`},
+		{name: "numbers kept", value: map[string]any{"count": uint64(12345678901234567890)}, want: `{"count":12345678901234567890}`},
+		{name: "struct", value: struct {
+			Password string `json:"password"`
+			Name     string `json:"name"`
+		}{"p", "n"}, want: `{"name":"n","password":"[REDACTED]"}`},
+		{name: "extra sensitive field", value: map[string]any{"token": "t", "user": "u"}, sensitive: []string{"token"}, want: `{"token":"[REDACTED]","user":"u"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(r.Redact(tt.value, tt.sensitive...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Fatalf("Redact = %s, want %s", data, tt.want)
+			}
+		})
+	}
+
+	// The extra fields only apply to the call that names them.
+	if data, _ := json.Marshal(r.Redact(map[string]any{"token": "t"})); string(data) != `{"token":"t"}` {
+		t.Fatalf("Redact = %s, want the token kept", data)
+	}
+}
+
+func TestRedactDisabled(t *testing.T) {
+	r := newTestRedactor(false)
+	value := map[string]any{"password": "hunter2"}
+	if got := r.Redact(value); got.(map[string]any)["password"] != "hunter2" {
+		t.Fatalf("Redact = %v, want it unchanged", got)
+	}
+	if field := r.Error(errors.New("sk-abc")); field.Interface.(error).Error() != "sk-abc" {
+		t.Fatalf("Error = %v, want it unchanged", field)
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	r := newTestRedactor(true)
+	field := r.Error(errors.New("bad key sk-abc123"))
+	if field.Key != "error" || field.String != "bad key [REDACTED]" {
+		t.Fatalf("Error = %+v, want the key masked", field)
+	}
+}
+
+func TestNewRedactorInvalidPattern(t *testing.T) {
+	defer func() {
+		if err := recover(); err == nil || !strings.Contains(err.(string), "log.redact.patterns error") {
+			t.Fatalf("recover() = %v, want a log.redact.patterns error", err)
+		}
+	}()
+	conf := viper.New()
+	conf.Set("log.redact.patterns", []string{"("})
+	NewRedactor(conf)
+}
+
+func TestSensitiveArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		tool mcp.Tool
+		want []string
+	}{
+		{
+			name: "input schema",
+			tool: mcp.NewTool("login", mcp.WithString("user"), mcp.WithString("password", mcp.PropertyOption(func(schema map[string]any) {
+				schema["writeOnly"] = true
+			}))),
+			want: []string{"password"},
+		},
+		{
+			name: "raw input schema",
+			tool: mcp.NewToolWithRawSchema("call", "", json.RawMessage(`{"type":"object","properties":{"headers":{"type":"object","writeOnly":true},"token":{"type":"string","writeOnly":true},"url":{"type":"string"}}}`)),
+			want: []string{"headers", "token"},
+		},
+		{name: "none", tool: mcp.NewTool("echo", mcp.WithString("message"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SensitiveArguments(tt.tool)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("SensitiveArguments = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -71,7 +71,12 @@ func (s *Server) Start(ctx context.Context) error {
 }
 
 func (s *Server) Stop(ctx context.Context) error {
-	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	if s.httpSrv == nil {
+		return nil
+	}
+	// Stop is usually called with an already canceled ctx, which would
+	// skip the graceful part of Shutdown.
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
 	defer cancel()
 
 	s.logger.Sugar().Info("Shutting down server gracefully...")
@@ -3,29 +3,48 @@ package mcp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 )
 
 type Server struct {
 	*server.MCPServer
-	stdio      bool
-	stdioOpts  []server.StdioOption
-	httpAddr   string
-	sseAddr    string
-	httpSrv    *server.StreamableHTTPServer
-	sseSrv     *server.SSEServer
-	logger     *log.Logger
-	middleware http.Handler
+	stdio     bool
+	stdioOpts []server.StdioOption
+	httpAddr  string
+	sseAddr   string
+	httpSrv   *server.StreamableHTTPServer
+	sseSrv    *server.SSEServer
+	logger    *log.Logger
+	onStop    []func()
+
+	promptMiddlewares   []PromptHandlerMiddleware
+	resourceMiddlewares []server.ResourceHandlerMiddleware
+
+	mu          sync.Mutex
+	sseStarted  bool
+	httpStarted bool
+
+	// mcp-go has no getters for prompts and resources, so their names are
+	// tracked here for Registry.
+	namesMu   sync.RWMutex
+	prompts   map[string]struct{}
+	resources map[string]struct{}
 }
 
 type Option func(*Server)
 
 func NewServer(logger *log.Logger, opts ...Option) *Server {
 	s := &Server{
-		logger: logger,
+		logger:    logger,
+		prompts:   map[string]struct{}{},
+		resources: map[string]struct{}{},
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -57,10 +76,134 @@ func WithStreamableHTTPSrv(addr string, srv *server.StreamableHTTPServer) Option
 	}
 }
 
+// WithOnStop registers functions run at the start of Stop, before the
+// transports are shut down, e.g. to cancel in-flight tool calls.
+func WithOnStop(fns ...func()) Option {
+	return func(s *Server) {
+		s.onStop = append(s.onStop, fns...)
+	}
+}
+
+// PromptHandlerMiddleware wraps prompt handlers, like
+// server.ToolHandlerMiddleware does for tools.
+type PromptHandlerMiddleware func(server.PromptHandlerFunc) server.PromptHandlerFunc
+
+// WithPromptMiddleware wraps every prompt handler added through Server. The
+// first middleware is the outermost.
+func WithPromptMiddleware(mw ...PromptHandlerMiddleware) Option {
+	return func(s *Server) {
+		s.promptMiddlewares = append(s.promptMiddlewares, mw...)
+	}
+}
+
+// WithResourceMiddleware wraps every resource and resource template handler
+// added through Server. Unlike server.WithResourceHandlerMiddleware it also
+// covers templates. The first middleware is the outermost.
+func WithResourceMiddleware(mw ...server.ResourceHandlerMiddleware) Option {
+	return func(s *Server) {
+		s.resourceMiddlewares = append(s.resourceMiddlewares, mw...)
+	}
+}
+
+func (s *Server) promptHandler(handler server.PromptHandlerFunc) server.PromptHandlerFunc {
+	for i := len(s.promptMiddlewares) - 1; i >= 0; i-- {
+		handler = s.promptMiddlewares[i](handler)
+	}
+	return handler
+}
+
+func (s *Server) resourceHandler(handler server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+	for i := len(s.resourceMiddlewares) - 1; i >= 0; i-- {
+		handler = s.resourceMiddlewares[i](handler)
+	}
+	return handler
+}
+
+func (s *Server) AddPrompt(prompt mcp.Prompt, handler server.PromptHandlerFunc) {
+	s.AddPrompts(server.ServerPrompt{Prompt: prompt, Handler: handler})
+}
+
+func (s *Server) AddPrompts(prompts ...server.ServerPrompt) {
+	prompts = slices.Clone(prompts)
+	s.namesMu.Lock()
+	for i, prompt := range prompts {
+		s.prompts[prompt.Prompt.Name] = struct{}{}
+		prompts[i].Handler = s.promptHandler(prompt.Handler)
+	}
+	s.namesMu.Unlock()
+	s.MCPServer.AddPrompts(prompts...)
+}
+
+func (s *Server) DeletePrompts(names ...string) {
+	s.namesMu.Lock()
+	for _, name := range names {
+		delete(s.prompts, name)
+	}
+	s.namesMu.Unlock()
+	s.MCPServer.DeletePrompts(names...)
+}
+
+// HasPrompt reports whether a prompt was added through this Server.
+func (s *Server) HasPrompt(name string) bool {
+	s.namesMu.RLock()
+	defer s.namesMu.RUnlock()
+	_, ok := s.prompts[name]
+	return ok
+}
+
+func (s *Server) AddResource(resource mcp.Resource, handler server.ResourceHandlerFunc) {
+	s.AddResources(server.ServerResource{Resource: resource, Handler: handler})
+}
+
+func (s *Server) AddResources(resources ...server.ServerResource) {
+	resources = slices.Clone(resources)
+	s.namesMu.Lock()
+	for i, resource := range resources {
+		s.resources[resource.Resource.URI] = struct{}{}
+		resources[i].Handler = s.resourceHandler(resource.Handler)
+	}
+	s.namesMu.Unlock()
+	s.MCPServer.AddResources(resources...)
+}
+
+func (s *Server) AddResourceTemplate(template mcp.ResourceTemplate, handler server.ResourceTemplateHandlerFunc) {
+	s.MCPServer.AddResourceTemplate(template, server.ResourceTemplateHandlerFunc(s.resourceHandler(server.ResourceHandlerFunc(handler))))
+}
+
+func (s *Server) DeleteResources(uris ...string) {
+	s.namesMu.Lock()
+	for _, uri := range uris {
+		delete(s.resources, uri)
+	}
+	s.namesMu.Unlock()
+	s.MCPServer.DeleteResources(uris...)
+}
+
+// HasResource reports whether a resource was added through this Server.
+func (s *Server) HasResource(uri string) bool {
+	s.namesMu.RLock()
+	defer s.namesMu.RUnlock()
+	_, ok := s.resources[uri]
+	return ok
+}
+
+// NotifyResourceListChanged sends notifications/resources/list_changed for
+// resources that are listed without being added to the server, which
+// mcp-go cannot announce itself.
+func (s *Server) NotifyResourceListChanged() {
+	s.SendNotificationToAllClients(mcp.MethodNotificationResourcesListChanged, nil)
+}
+
 func (s *Server) Start(ctx context.Context) error {
 	if s.MCPServer == nil {
 		return errors.New("mcp server not initialized")
 	}
+	if !s.stdio && s.sseSrv == nil && s.httpSrv == nil {
+		return errors.New("no mcp transport enabled")
+	}
+	if s.stdio && s.logger.WritesToStdout() {
+		return errors.New("stdio transport enabled but logs are written to stdout, which would corrupt JSON-RPC frames")
+	}
 	if s.stdio {
 		go func() {
 			s.logger.Sugar().Info("Starting STDIO server...")
@@ -69,46 +212,79 @@ func (s *Server) Start(ctx context.Context) error {
 			}
 		}()
 	}
+	// A listener that fails, e.g. on a port in use, stops the server, so the
+	// process does not keep running without the transport.
+	errs := make(chan error, 2)
 	if s.sseSrv != nil {
+		s.mu.Lock()
+		s.sseStarted = true
+		s.mu.Unlock()
 		go func() {
 			s.logger.Sugar().Infof("Starting SSE server on %s...", s.sseAddr)
-			if err := s.sseSrv.Start(s.sseAddr); err != nil {
-				s.logger.Sugar().Errorf("SSE server error: %v", err)
+			if err := s.sseSrv.Start(s.sseAddr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("sse server: %w", err)
 			}
 		}()
 	}
 	if s.httpSrv != nil {
-		s.logger.Sugar().Infof("Starting StreamableHTTP server on %s...", s.httpAddr)
-		if err := s.httpSrv.Start(s.httpAddr); err != nil {
-			s.logger.Sugar().Errorf("StreamableHTTP server error: %v", err)
-		}
+		s.mu.Lock()
+		s.httpStarted = true
+		s.mu.Unlock()
+		go func() {
+			s.logger.Sugar().Infof("Starting StreamableHTTP server on %s...", s.httpAddr)
+			if err := s.httpSrv.Start(s.httpAddr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("streamable http server: %w", err)
+			}
+		}()
 	}
 	// 等待 context 取消
-	<-ctx.Done()
-	s.logger.Sugar().Info("Context canceled, shutting down server...")
-	return s.Stop(ctx)
+	select {
+	case <-ctx.Done():
+		s.logger.Sugar().Info("Context canceled, shutting down server...")
+		return s.Stop(ctx)
+	case err := <-errs:
+		_ = s.Stop(ctx)
+		return err
+	}
 }
 
 func (s *Server) Stop(ctx context.Context) error {
 	// 设置优雅关闭的超时时间
-	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
 	defer cancel()
 
+	for _, fn := range s.onStop {
+		fn()
+	}
+
+	s.mu.Lock()
+	sseStarted, httpStarted := s.sseStarted, s.httpStarted
+	s.sseStarted, s.httpStarted = false, false
+	s.mu.Unlock()
+
+	if !sseStarted && !httpStarted {
+		return nil
+	}
+
 	s.logger.Info("Shutting down server gracefully...")
 
 	var shutdownErr error
 
 	// 尝试关闭 SSE 服务
-	if err := s.sseSrv.Shutdown(shutdownCtx); err != nil {
-		s.logger.Sugar().Errorf("Failed to shutdown SSE server: %v", err)
-		shutdownErr = err
+	if sseStarted {
+		if err := s.sseSrv.Shutdown(shutdownCtx); err != nil {
+			s.logger.Sugar().Errorf("Failed to shutdown SSE server: %v", err)
+			shutdownErr = err
+		}
 	}
 
 	// 尝试关闭 HTTP 服务
-	if err := s.httpSrv.Shutdown(shutdownCtx); err != nil {
-		s.logger.Sugar().Errorf("Failed to shutdown HTTP server: %v", err)
-		if shutdownErr == nil {
-			shutdownErr = err
+	if httpStarted {
+		if err := s.httpSrv.Shutdown(shutdownCtx); err != nil {
+			s.logger.Sugar().Errorf("Failed to shutdown HTTP server: %v", err)
+			if shutdownErr == nil {
+				shutdownErr = err
+			}
 		}
 	}
 
@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"context"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStart(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	stdoutConf := viper.New()
+	stdoutConf.Set("mcp.transports.stdio.enable", true)
+	stdoutConf.Set("log.mode", "file")
+	stdoutConf.Set("log.log_file_name", "/dev/stdout")
+
+	nop := &log.Logger{Logger: zap.NewNop()}
+	mcpSrv := server.NewMCPServer("test", "1.0.0")
+	tests := []struct {
+		name    string
+		logger  *log.Logger
+		opts    []Option
+		wantErr string
+	}{
+		{name: "no transport", logger: nop, opts: []Option{WithMCPSrv(mcpSrv)}, wantErr: "no mcp transport enabled"},
+		{name: "no mcp server", logger: nop, opts: []Option{WithStdioSrv(true)}, wantErr: "mcp server not initialized"},
+		{
+			name:    "stdio with the log file on stdout",
+			logger:  log.NewLog(stdoutConf),
+			opts:    []Option{WithMCPSrv(mcpSrv), WithStdioSrv(true)},
+			wantErr: "logs are written to stdout",
+		},
+		{
+			name:    "http address in use",
+			logger:  nop,
+			opts:    []Option{WithMCPSrv(mcpSrv), WithStreamableHTTPSrv(busy.Addr().String(), server.NewStreamableHTTPServer(mcpSrv))},
+			wantErr: "address already in use",
+		},
+		{
+			name:    "sse address in use",
+			logger:  nop,
+			opts:    []Option{WithMCPSrv(mcpSrv), WithSSESrv(busy.Addr().String(), server.NewSSEServer(mcpSrv))},
+			wantErr: "address already in use",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() { done <- NewServer(tt.logger, tt.opts...).Start(context.Background()) }()
+			select {
+			case err := <-done:
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Start = %v, want %q", err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Start did not fail")
+			}
+		})
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	mcpSrv := server.NewMCPServer("test", "1.0.0")
+	srv := NewServer(&log.Logger{Logger: zap.NewNop()},
+		WithMCPSrv(mcpSrv),
+		WithStreamableHTTPSrv("127.0.0.1:0", server.NewStreamableHTTPServer(mcpSrv)),
+		WithSSESrv("127.0.0.1:0", server.NewSSEServer(mcpSrv)),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Start(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start = %v, want a clean stop", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Start did not return after cancel")
+	}
+}
+
+func TestStop(t *testing.T) {
+	mcpSrv := server.NewMCPServer("test", "1.0.0")
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "no transports", opts: []Option{WithMCPSrv(mcpSrv)}},
+		{name: "stdio only", opts: []Option{WithMCPSrv(mcpSrv), WithStdioSrv(true)}},
+		// Configured but never started, so there is nothing to shut down.
+		{name: "not started", opts: []Option{
+			WithMCPSrv(mcpSrv),
+			WithSSESrv("127.0.0.1:0", server.NewSSEServer(mcpSrv)),
+			WithStreamableHTTPSrv("127.0.0.1:0", server.NewStreamableHTTPServer(mcpSrv)),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stopped := 0
+			srv := NewServer(&log.Logger{Logger: zap.NewNop()}, append(tt.opts, WithOnStop(func() { stopped++ }))...)
+			for i := 0; i < 2; i++ {
+				if err := srv.Stop(context.Background()); err != nil {
+					t.Fatalf("Stop = %v", err)
+				}
+			}
+			if stopped != 2 {
+				t.Fatalf("OnStop ran %d times, want 2", stopped)
+			}
+		})
+	}
+}
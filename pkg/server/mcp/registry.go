@@ -0,0 +1,296 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mark3labs/mcp-go/server"
+	"sync"
+)
+
+// ErrNotManaged is returned when deleting a name no source owns.
+var ErrNotManaged = errors.New("not managed at runtime")
+
+// Registry changes tools, prompts and resources at runtime. Every entry is
+// owned by a source, e.g. "catalog" or "admin"; a source can only replace or
+// remove its own entries, never the ones registered in code at startup.
+// The MCP server sends the matching list_changed notification to every
+// connected session after each change.
+type Registry struct {
+	srv *Server
+
+	mu        sync.Mutex
+	tools     map[string]string // name -> source
+	prompts   map[string]string // name -> source
+	resources map[string]string // uri -> source
+}
+
+func NewRegistry(srv *Server) *Registry {
+	return &Registry{
+		srv:       srv,
+		tools:     map[string]string{},
+		prompts:   map[string]string{},
+		resources: map[string]string{},
+	}
+}
+
+// Set replaces every tool, prompt and resource owned by source. All names
+// are checked before anything changes, so a clash in any of them leaves
+// the registry untouched.
+func (r *Registry) Set(source string, tools []server.ServerTool, prompts []server.ServerPrompt, resources []server.ServerResource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names, promptNames, uris := toolNames(tools), promptNames(prompts), resourceURIs(resources)
+	if err := check("tool", r.tools, source, names, r.hasTool); err != nil {
+		return err
+	}
+	if err := check("prompt", r.prompts, source, promptNames, r.srv.HasPrompt); err != nil {
+		return err
+	}
+	if err := check("resource", r.resources, source, uris, r.srv.HasResource); err != nil {
+		return err
+	}
+	r.setTools(source, tools, names)
+	r.setPrompts(source, prompts, promptNames)
+	r.setResources(source, resources, uris)
+	return nil
+}
+
+// SetTools replaces every tool owned by source with tools.
+func (r *Registry) SetTools(source string, tools ...server.ServerTool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := toolNames(tools)
+	if err := check("tool", r.tools, source, names, r.hasTool); err != nil {
+		return err
+	}
+	r.setTools(source, tools, names)
+	return nil
+}
+
+func (r *Registry) setTools(source string, tools []server.ServerTool, names []string) {
+	claim(r.tools, source, names)
+	if removed := release(r.tools, source, names); len(removed) > 0 {
+		r.srv.DeleteTools(removed...)
+	}
+	if len(tools) > 0 {
+		r.srv.AddTools(tools...)
+	}
+}
+
+// UpsertTool adds or replaces a single tool owned by source.
+func (r *Registry) UpsertTool(source string, tool server.ServerTool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := []string{tool.Tool.Name}
+	if err := check("tool", r.tools, source, names, r.hasTool); err != nil {
+		return err
+	}
+	claim(r.tools, source, names)
+	r.srv.AddTools(tool)
+	return nil
+}
+
+// DeleteTool removes a tool owned by source.
+func (r *Registry) DeleteTool(source, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := owned("tool", r.tools, source, name); err != nil {
+		return err
+	}
+	delete(r.tools, name)
+	r.srv.DeleteTools(name)
+	return nil
+}
+
+// SetPrompts replaces every prompt owned by source with prompts.
+func (r *Registry) SetPrompts(source string, prompts ...server.ServerPrompt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := promptNames(prompts)
+	if err := check("prompt", r.prompts, source, names, r.srv.HasPrompt); err != nil {
+		return err
+	}
+	r.setPrompts(source, prompts, names)
+	return nil
+}
+
+func (r *Registry) setPrompts(source string, prompts []server.ServerPrompt, names []string) {
+	claim(r.prompts, source, names)
+	if removed := release(r.prompts, source, names); len(removed) > 0 {
+		r.srv.DeletePrompts(removed...)
+	}
+	if len(prompts) > 0 {
+		r.srv.AddPrompts(prompts...)
+	}
+}
+
+// UpsertPrompt adds or replaces a single prompt owned by source.
+func (r *Registry) UpsertPrompt(source string, prompt server.ServerPrompt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := []string{prompt.Prompt.Name}
+	if err := check("prompt", r.prompts, source, names, r.srv.HasPrompt); err != nil {
+		return err
+	}
+	claim(r.prompts, source, names)
+	r.srv.AddPrompts(prompt)
+	return nil
+}
+
+// DeletePrompt removes a prompt owned by source.
+func (r *Registry) DeletePrompt(source, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := owned("prompt", r.prompts, source, name); err != nil {
+		return err
+	}
+	delete(r.prompts, name)
+	r.srv.DeletePrompts(name)
+	return nil
+}
+
+// SetResources replaces every resource owned by source with resources.
+func (r *Registry) SetResources(source string, resources ...server.ServerResource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	uris := resourceURIs(resources)
+	if err := check("resource", r.resources, source, uris, r.srv.HasResource); err != nil {
+		return err
+	}
+	r.setResources(source, resources, uris)
+	return nil
+}
+
+func (r *Registry) setResources(source string, resources []server.ServerResource, uris []string) {
+	claim(r.resources, source, uris)
+	if removed := release(r.resources, source, uris); len(removed) > 0 {
+		r.srv.DeleteResources(removed...)
+	}
+	if len(resources) > 0 {
+		r.srv.AddResources(resources...)
+	}
+}
+
+// UpsertResource adds or replaces a single resource owned by source.
+func (r *Registry) UpsertResource(source string, resource server.ServerResource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	uris := []string{resource.Resource.URI}
+	if err := check("resource", r.resources, source, uris, r.srv.HasResource); err != nil {
+		return err
+	}
+	claim(r.resources, source, uris)
+	r.srv.AddResources(resource)
+	return nil
+}
+
+// DeleteResource removes a resource owned by source.
+func (r *Registry) DeleteResource(source, uri string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := owned("resource", r.resources, source, uri); err != nil {
+		return err
+	}
+	delete(r.resources, uri)
+	r.srv.DeleteResources(uri)
+	return nil
+}
+
+// Owned lists the tool, prompt and resource names owned by source.
+func (r *Registry) Owned(source string) (tools, prompts, resources []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return ownedBy(r.tools, source), ownedBy(r.prompts, source), ownedBy(r.resources, source)
+}
+
+func (r *Registry) hasTool(name string) bool {
+	return r.srv.GetTool(name) != nil
+}
+
+// check fails if a name is given twice, belongs to another source or was
+// registered in code.
+func check(kind string, owners map[string]string, source string, names []string, exists func(string) bool) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return fmt.Errorf("%s %q is defined twice", kind, name)
+		}
+		seen[name] = true
+		owner, ok := owners[name]
+		if ok && owner != source {
+			return fmt.Errorf("%s %q is managed by %s", kind, name, owner)
+		}
+		if !ok && exists(name) {
+			return fmt.Errorf("%s %q is already registered", kind, name)
+		}
+	}
+	return nil
+}
+
+// claim records source as the owner of names that passed check.
+func claim(owners map[string]string, source string, names []string) {
+	for _, name := range names {
+		owners[name] = source
+	}
+}
+
+// release drops the entries of source that are not in keep and returns them.
+func release(owners map[string]string, source string, keep []string) []string {
+	kept := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		kept[name] = true
+	}
+	var removed []string
+	for name, owner := range owners {
+		if owner == source && !kept[name] {
+			delete(owners, name)
+			removed = append(removed, name)
+		}
+	}
+	return removed
+}
+
+func owned(kind string, owners map[string]string, source, name string) error {
+	owner, ok := owners[name]
+	if !ok {
+		return fmt.Errorf("%s %q is %w", kind, name, ErrNotManaged)
+	}
+	if owner != source {
+		return fmt.Errorf("%s %q is managed by %s", kind, name, owner)
+	}
+	return nil
+}
+
+func ownedBy(owners map[string]string, source string) []string {
+	names := []string{}
+	for name, owner := range owners {
+		if owner == source {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func toolNames(tools []server.ServerTool) []string {
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Tool.Name)
+	}
+	return names
+}
+
+func promptNames(prompts []server.ServerPrompt) []string {
+	names := make([]string, 0, len(prompts))
+	for _, prompt := range prompts {
+		names = append(names, prompt.Prompt.Name)
+	}
+	return names
+}
+
+func resourceURIs(resources []server.ServerResource) []string {
+	uris := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		uris = append(uris, resource.Resource.URI)
+	}
+	return uris
+}
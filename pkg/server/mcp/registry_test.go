@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"context"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"slices"
+	"testing"
+)
+
+func newTestRegistry() (*Server, *Registry) {
+	srv := NewServer(&log.Logger{Logger: zap.NewNop()}, WithMCPSrv(server.NewMCPServer("test", "1.0.0",
+		server.WithToolCapabilities(true),
+		server.WithPromptCapabilities(true),
+		server.WithResourceCapabilities(false, true),
+	)))
+	return srv, NewRegistry(srv)
+}
+
+func testTool(name string) server.ServerTool {
+	return server.ServerTool{
+		Tool: mcp.NewTool(name),
+		Handler: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(name), nil
+		},
+	}
+}
+
+func testPrompt(name string) server.ServerPrompt {
+	return server.ServerPrompt{
+		Prompt: mcp.NewPrompt(name),
+		Handler: func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return mcp.NewGetPromptResult(name, nil), nil
+		},
+	}
+}
+
+func testResource(uri string) server.ServerResource {
+	return server.ServerResource{
+		Resource: mcp.NewResource(uri, uri),
+		Handler: func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return nil, nil
+		},
+	}
+}
+
+func TestRegistrySet(t *testing.T) {
+	srv, reg := newTestRegistry()
+	srv.AddPrompts(testPrompt("code_prompt"))
+
+	err := reg.Set("catalog",
+		[]server.ServerTool{testTool("a"), testTool("b")},
+		[]server.ServerPrompt{testPrompt("p")},
+		[]server.ServerResource{testResource("catalog://r")},
+	)
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		tools     []server.ServerTool
+		prompts   []server.ServerPrompt
+		resources []server.ServerResource
+	}{
+		{
+			name:    "prompt registered in code",
+			tools:   []server.ServerTool{testTool("c")},
+			prompts: []server.ServerPrompt{testPrompt("code_prompt")},
+		},
+		{
+			name:      "resource defined twice",
+			tools:     []server.ServerTool{testTool("c")},
+			resources: []server.ServerResource{testResource("catalog://x"), testResource("catalog://x")},
+		},
+		{
+			name:  "tool owned by another source",
+			tools: []server.ServerTool{testTool("admin_tool")},
+		},
+	}
+	if err := reg.UpsertTool("admin", testTool("admin_tool")); err != nil {
+		t.Fatalf("UpsertTool: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := reg.Set("catalog", tt.tools, tt.prompts, tt.resources); err == nil {
+				t.Fatal("Set succeeded, want an error")
+			}
+			tools, prompts, resources := reg.Owned("catalog")
+			slices.Sort(tools)
+			if !slices.Equal(tools, []string{"a", "b"}) || !slices.Equal(prompts, []string{"p"}) || !slices.Equal(resources, []string{"catalog://r"}) {
+				t.Fatalf("owned = %v %v %v, want the previous entries", tools, prompts, resources)
+			}
+			if srv.GetTool("a") == nil || srv.GetTool("c") != nil {
+				t.Fatal("server tools changed after a failed Set")
+			}
+			if !srv.HasPrompt("p") || !srv.HasResource("catalog://r") {
+				t.Fatal("server prompts or resources changed after a failed Set")
+			}
+		})
+	}
+
+	if err := reg.Set("catalog", []server.ServerTool{testTool("b")}, nil, nil); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if srv.GetTool("a") != nil || srv.GetTool("b") == nil || srv.HasPrompt("p") || srv.HasResource("catalog://r") {
+		t.Fatal("Set did not replace the previous entries")
+	}
+	if !srv.HasPrompt("code_prompt") || srv.GetTool("admin_tool") == nil {
+		t.Fatal("Set removed entries of other owners")
+	}
+}
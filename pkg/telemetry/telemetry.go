@@ -0,0 +1,281 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-nunu/nunu-layout-mcp/pkg/log"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const instrumentationName = "github.com/go-nunu/nunu-layout-mcp"
+
+// methods are the requests the MCP server answers. Others never reach the
+// OnSuccess or OnError hooks, so no span is started for them.
+var methods = []mcp.MCPMethod{
+	mcp.MethodInitialize,
+	mcp.MethodPing,
+	mcp.MethodSetLogLevel,
+	mcp.MethodResourcesList,
+	mcp.MethodResourcesTemplatesList,
+	mcp.MethodResourcesRead,
+	mcp.MethodPromptsList,
+	mcp.MethodPromptsGet,
+	mcp.MethodToolsList,
+	mcp.MethodToolsCall,
+}
+
+// Tracing traces MCP requests with OpenTelemetry. Each JSON-RPC request gets
+// a server span, started in the hooks and continued from the W3C trace
+// context ("traceparent", "tracestate") in the request's _meta. Middleware
+// puts the span into the tool handler's context, so services, outbound HTTP
+// and SQL become its children, and adds the trace ID to the logger.
+type Tracing struct {
+	enable     bool
+	logger     *log.Logger
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	requests sync.Map // request key -> trace.Span
+	calls    sync.Map // *mcp.Meta of a tools/call -> trace.Span
+}
+
+// NewTracing installs the global tracer provider and propagator. With
+// tracing.enable off the provider stays a no-op. The returned func flushes
+// and shuts down the exporter.
+func NewTracing(conf *viper.Viper, logger *log.Logger) (*Tracing, func()) {
+	t := &Tracing{
+		enable: conf.GetBool("tracing.enable"),
+		logger: logger,
+		propagator: propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		),
+	}
+	otel.SetTextMapPropagator(t.propagator)
+	if !t.enable {
+		t.tracer = otel.Tracer(instrumentationName)
+		return t, func() {}
+	}
+
+	var opts []otlptracehttp.Option
+	if endpoint := conf.GetString("tracing.endpoint"); endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+	}
+	if conf.GetBool("tracing.insecure") {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		panic(fmt.Sprintf("tracing error: %s", err.Error()))
+	}
+
+	conf.SetDefault("tracing.sample_ratio", 1.0)
+	serviceName := conf.GetString("tracing.service_name")
+	if serviceName == "" {
+		serviceName = conf.GetString("mcp.name")
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", conf.GetString("mcp.version")),
+		)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.GetFloat64("tracing.sample_ratio")))),
+	)
+	otel.SetTracerProvider(provider)
+	t.tracer = provider.Tracer(instrumentationName)
+
+	return t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			logger.Error("tracing shutdown error", zap.Error(err))
+		}
+	}
+}
+
+// Start starts a span as a child of the one in ctx. It uses the global
+// provider, so it costs next to nothing while tracing is disabled.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End marks span as failed when err is set and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartHTTP starts a client span for an outbound request and adds its trace
+// context to the request headers. The query string is left out of the span,
+// as it may carry secrets.
+func StartHTTP(req *http.Request) (*http.Request, trace.Span) {
+	u := *req.URL
+	u.RawQuery, u.User = "", nil
+	ctx, span := otel.Tracer(instrumentationName).Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", u.String()),
+			attribute.String("server.address", req.URL.Hostname()),
+		),
+	)
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return req, span
+}
+
+// Inject writes the trace context of ctx into carrier, e.g. the _meta of an
+// outgoing MCP request.
+func Inject(ctx context.Context, carrier map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(carrier))
+}
+
+// AddHooks registers the hooks that start and end request spans. Add them
+// after the other hooks, so those still find the span in RequestContext.
+func (t *Tracing) AddHooks(hooks *server.Hooks) {
+	if !t.enable {
+		return
+	}
+	hooks.AddOnRequestInitialization(t.onRequestInitialization)
+	hooks.AddBeforeCallTool(t.beforeCallTool)
+	hooks.AddOnSuccess(func(ctx context.Context, id any, method mcp.MCPMethod, message any, result any) {
+		t.end(ctx, id, nil)
+	})
+	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+		t.end(ctx, id, err)
+	})
+}
+
+func (t *Tracing) onRequestInitialization(ctx context.Context, id any, message any) error {
+	raw, ok := message.(json.RawMessage)
+	if !ok {
+		return nil
+	}
+	var request struct {
+		Method mcp.MCPMethod `json:"method"`
+		Params struct {
+			Meta map[string]any `json:"_meta"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(raw, &request); err != nil || !slices.Contains(methods, request.Method) {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	for k, v := range request.Params.Meta {
+		if s, ok := v.(string); ok {
+			carrier[k] = s
+		}
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("rpc.method", string(request.Method)),
+		attribute.String("rpc.jsonrpc.request_id", fmt.Sprint(id)),
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		attrs = append(attrs, attribute.String("mcp.session.id", session.SessionID()))
+	}
+	_, span := t.tracer.Start(t.propagator.Extract(ctx, carrier), string(request.Method),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+	t.requests.Store(requestKey(ctx, id), span)
+	return nil
+}
+
+// beforeCallTool names the span after the tool and hands it to Middleware
+// through the request's _meta, the only thing both of them see.
+func (t *Tracing) beforeCallTool(ctx context.Context, id any, message *mcp.CallToolRequest) {
+	v, ok := t.requests.Load(requestKey(ctx, id))
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	span.SetName(string(mcp.MethodToolsCall) + " " + message.Params.Name)
+	span.SetAttributes(attribute.String("mcp.tool.name", message.Params.Name))
+	if message.Params.Meta == nil {
+		message.Params.Meta = &mcp.Meta{}
+	}
+	t.calls.Store(message.Params.Meta, span)
+}
+
+func (t *Tracing) end(ctx context.Context, id any, err error) {
+	if v, ok := t.requests.LoadAndDelete(requestKey(ctx, id)); ok {
+		End(v.(trace.Span), err)
+	}
+}
+
+// Middleware runs the tool handler in the request span and logs with its
+// trace ID.
+func (t *Tracing) Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !t.enable || request.Params.Meta == nil {
+			return next(ctx, request)
+		}
+		v, ok := t.calls.LoadAndDelete(request.Params.Meta)
+		if !ok {
+			return next(ctx, request)
+		}
+		span := v.(trace.Span)
+		result, err := next(t.withSpan(ctx, span), request)
+		if err == nil && result != nil && result.IsError {
+			span.SetStatus(codes.Error, "tool returned an error result")
+		}
+		return result, err
+	}
+}
+
+// RequestContext returns ctx with the span of request id and a logger that
+// adds its trace ID, for logging from hooks. ctx is returned unchanged when
+// the request is not traced.
+func (t *Tracing) RequestContext(ctx context.Context, id any) context.Context {
+	if !t.enable {
+		return ctx
+	}
+	v, ok := t.requests.Load(requestKey(ctx, id))
+	if !ok {
+		return ctx
+	}
+	return t.withSpan(ctx, v.(trace.Span))
+}
+
+func (t *Tracing) withSpan(ctx context.Context, span trace.Span) context.Context {
+	ctx = trace.ContextWithSpan(ctx, span)
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return ctx
+	}
+	return t.logger.WithValue(ctx,
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	)
+}
+
+// requestKey scopes request ids to the session, since every client numbers
+// its own requests.
+func requestKey(ctx context.Context, id any) string {
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return fmt.Sprintf("%s/%v", sessionID, id)
+}
@@ -10,6 +10,10 @@ import (
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	gormlogger "gorm.io/gorm/logger"
 )
@@ -32,10 +36,20 @@ func New(zapLogger *zap.Logger) gormlogger.Interface {
 		SlowThreshold:             100 * time.Millisecond,
 		Colorful:                  false,
 		IgnoreRecordNotFoundError: false,
-		ParameterizedQueries:      false,
+		ParameterizedQueries:      true,
 	}
 }
 
+// ParamsFilter drops the bound values from logged and traced statements
+// when ParameterizedQueries is set, as they may hold secrets or personal
+// data.
+func (l Logger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.ParameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
+
 func (l *Logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
 	newlogger := *l
 	newlogger.LogLevel = level
@@ -64,6 +78,7 @@ func (l Logger) Error(ctx context.Context, msg string, data ...interface{}) {
 }
 
 func (l Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.span(ctx, begin, fc, err)
 	if l.LogLevel <= gormlogger.Silent {
 		return
 	}
@@ -97,6 +112,28 @@ func (l Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, i
 	}
 }
 
+// span records the statement as a child of the span in ctx, so SQL shows up
+// in request traces. Statements outside a trace are skipped.
+func (l Logger) span(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	sql, rows := fc()
+	_, span := otel.Tracer(gormPackage).Start(ctx, "gorm.query",
+		trace.WithTimestamp(begin),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.query.text", sql),
+			attribute.Int64("db.response.returned_rows", rows),
+		),
+	)
+	if err != nil && !errors.Is(err, gormlogger.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 var (
 	gormPackage = filepath.Join("gorm.io", "gorm")
 )
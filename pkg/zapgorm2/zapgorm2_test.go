@@ -0,0 +1,36 @@
+package zapgorm2
+
+import (
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"strings"
+	"testing"
+)
+
+func TestParameterizedQueries(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger: New(zap.New(core)).LogMode(gormlogger.Info),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE secrets (value TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO secrets (value) VALUES (?)", "hunter2").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	entries := logs.FilterMessage("trace").All()
+	if len(entries) != 2 {
+		t.Fatalf("got %d statements logged, want 2", len(entries))
+	}
+	sql := entries[1].ContextMap()["sql"].(string)
+	if strings.Contains(sql, "hunter2") || !strings.Contains(sql, "VALUES (?)") {
+		t.Fatalf("sql = %q, want the placeholder instead of the value", sql)
+	}
+}